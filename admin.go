@@ -0,0 +1,225 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"sync/atomic"
+	"time"
+)
+
+// listingKeys is the number of keys created before the Key Listing benchmark.
+const listingKeys = 10000
+
+// adminKeys is the number of keys each Key Rename and Key Delete run creates
+// and then renames or deletes.
+const adminKeys = 1000
+
+// flushesPerRun is the number of flushes per Flush run, each following a
+// write of cardinalityBatch points.
+const flushesPerRun = 10
+
+// adminLoadKey receives the background writes admin operations compete with.
+const adminLoadKey = "bench_admin_load"
+
+// adminPrefix returns a fresh key prefix for an admin benchmark run.
+func adminPrefix(op string) string {
+	return fmt.Sprintf("bench_%s_%d_", op, time.Now().UnixNano())
+}
+
+// adminBatch caps cardinalityBatch to the driver's MaxBatch.
+func adminBatch(maxBatch int) int {
+	if maxBatch > 0 {
+		return min(cardinalityBatch, maxBatch)
+	}
+	return cardinalityBatch
+}
+
+// underWriteLoad runs fn while a goroutine keeps writing single points to
+// adminLoadKey, so administrative operations compete with ingestion.
+func underWriteLoad(w Writer, fn func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; ctx.Err() == nil; i++ {
+			w.Write(ctx, adminLoadKey, float64(i))
+		}
+	}()
+	fn()
+	cancel()
+	<-done
+}
+
+// runKeyListing lists keys after creating listingKeys of them. Each run
+// issues one listing per worker; a listing missing created keys fails.
+func runKeyListing(w Writer, l KeyLister, maxBatch, runs, workers int) *BenchmarkResult {
+	workers = max(workers, 1)
+	result := newBenchResult("Key Listing", w.Name(), workers)
+	ctx := context.Background()
+
+	fmt.Printf("Creating %d keys for %s...\n", listingKeys, w.Name())
+	if created := runKeyCreation(w, adminPrefix("list"), 0, listingKeys, adminBatch(maxBatch)); created.SuccessRate() < 100 {
+		fmt.Fprintf(os.Stderr, "%s: only %.1f%% of listing keys created\n", w.Name(), created.SuccessRate())
+	}
+
+	for run := 0; run < runs; run++ {
+		var acc atomicAccumulator
+		start := time.Now()
+		splitWork(workers, workers, func(_, _ int) {
+			opStart := time.Now()
+			n, err := l.ListKeys(ctx)
+			result.recordOp(time.Since(opStart))
+			if err == nil && n >= listingKeys {
+				acc.addSuccess(1)
+			} else {
+				acc.addFailure(1)
+			}
+		})
+		result.addRun(time.Since(start), acc.successCount(), acc.failureCount())
+	}
+	result.compute()
+	return result
+}
+
+// runKeyAdmin creates adminKeys keys of one point per run and applies op to
+// each of them from workers goroutines while background writes continue.
+func runKeyAdmin(name string, w Writer, maxBatch, runs, workers int, op func(ctx context.Context, key string) error) *BenchmarkResult {
+	result := newBenchResult(name, w.Name(), adminKeys)
+	ctx := context.Background()
+	batch := adminBatch(maxBatch)
+
+	for run := 0; run < runs; run++ {
+		prefix := adminPrefix("admin")
+		keys := make([]string, adminKeys)
+		points := make([]KeyedPoint, adminKeys)
+		ts := time.Now().Unix()
+		for i := range keys {
+			keys[i] = cardinalityKey(prefix, i)
+			points[i] = KeyedPoint{Key: keys[i], Value: rand.Float64() * 100, Timestamp: ts}
+		}
+		for lo := 0; lo < len(points); lo += batch {
+			if err := w.WriteBatch(ctx, points[lo:min(lo+batch, len(points))]); err != nil {
+				fmt.Fprintf(os.Stderr, "%s %s setup: %v\n", w.Name(), name, err)
+			}
+		}
+
+		var acc atomicAccumulator
+		var elapsed time.Duration
+		underWriteLoad(w, func() {
+			start := time.Now()
+			splitWork(workers, len(keys), func(lo, hi int) {
+				for _, key := range keys[lo:hi] {
+					opStart := time.Now()
+					err := op(ctx, key)
+					result.recordOp(time.Since(opStart))
+					if err == nil {
+						acc.addSuccess(1)
+					} else {
+						acc.addFailure(1)
+					}
+				}
+			})
+			elapsed = time.Since(start)
+		})
+		result.addRun(elapsed, acc.successCount(), acc.failureCount())
+	}
+	result.compute()
+	return result
+}
+
+// runBulkLoad is runBatchWrite through the driver's bulk import path.
+func runBulkLoad(b BulkLoader, key string, count, runs, workers int) *BenchmarkResult {
+	result := newBenchResult("Bulk Load", b.Name(), count)
+	ctx := context.Background()
+
+	for run := 0; run < runs; run++ {
+		points := make([]KeyedPoint, count)
+		ts := time.Now().Unix()
+		for i := 0; i < count; i++ {
+			points[i] = KeyedPoint{Key: key, Value: rand.Float64() * 100, Timestamp: ts + int64(i)}
+		}
+
+		var failed atomic.Uint64
+		start := time.Now()
+		splitWork(workers, count, func(lo, hi int) {
+			opStart := time.Now()
+			err := b.BulkLoad(ctx, key, points[lo:hi])
+			result.recordOp(time.Since(opStart))
+			failed.Add(writeFailures(err, hi-lo))
+		})
+		result.addRun(time.Since(start), uint64(count)-failed.Load(), failed.Load())
+	}
+	result.compute()
+	return result
+}
+
+// runFlush times flushesPerRun flushes per run, each after an untimed write
+// of cardinalityBatch points so there is something to flush.
+func runFlush(w Writer, f Flusher, maxBatch, runs int) *BenchmarkResult {
+	result := newBenchResult("Flush", w.Name(), flushesPerRun)
+	ctx := context.Background()
+	batch := adminBatch(maxBatch)
+
+	for run := 0; run < runs; run++ {
+		var elapsed time.Duration
+		var success, failure uint64
+		for i := 0; i < flushesPerRun; i++ {
+			points := make([]KeyedPoint, batch)
+			ts := time.Now().Unix()
+			for j := range points {
+				points[j] = KeyedPoint{Key: benchSensorKey, Value: rand.Float64() * 100, Timestamp: ts + int64(j)}
+			}
+			// A flush after a failed write has nothing to flush, so it
+			// counts as a failure instead of being timed.
+			if err := w.WriteBatch(ctx, points); err != nil {
+				failure++
+				continue
+			}
+
+			start := time.Now()
+			err := f.Flush(ctx)
+			d := time.Since(start)
+			result.recordOp(d)
+			elapsed += d
+			if err == nil {
+				success++
+			} else {
+				failure++
+			}
+		}
+		result.addRun(elapsed, success, failure)
+	}
+	result.compute()
+	return result
+}
+
+// runExport exports the preloaded range key, through the driver's export
+// API when it has one and otherwise as a read of the whole span. Throughput
+// counts points; an export missing points fails.
+func runExport(d Driver, data rangeData, runs int) *BenchmarkResult {
+	result := newBenchResult("Export", d.Name(), rangeSpan)
+	ctx := context.Background()
+
+	export := func() (int, error) {
+		return d.(RangeReader).ReadRange(ctx, data.key, data.end-rangeSpan+1, data.end)
+	}
+	if e, ok := d.(Exporter); ok {
+		export = func() (int, error) { return e.Export(ctx, data.key) }
+	}
+
+	for run := 0; run < runs; run++ {
+		start := time.Now()
+		n, err := export()
+		elapsed := time.Since(start)
+		result.recordOp(elapsed)
+		if err == nil && n >= rangeSpan {
+			result.addRun(elapsed, rangeSpan, 0)
+		} else {
+			result.addRun(elapsed, uint64(min(n, rangeSpan)), uint64(rangeSpan-min(n, rangeSpan)))
+		}
+	}
+	result.compute()
+	return result
+}
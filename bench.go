@@ -1,126 +1,203 @@
 package main
 
 import (
-	"bufio"
 	"context"
 	"fmt"
 	"math/rand/v2"
-	"net"
+	"os"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
 const benchSensorKey = "benchmark_sensor"
 
-// runWriteBenchmark performs sequential single-point writes with warmup and multiple runs.
-func runWriteBenchmark(w Writer, key string, count, warmup, runs int) *BenchmarkResult {
-	result := newBenchResult("Write (seq)", w.Name(), count)
-	ctx := context.Background()
+// multiKeyPoints is the number of points per sensor preloaded for, and read
+// back by, the multi-key read benchmark.
+const multiKeyPoints = 5000
+
+// pipelineWorkers is the Pipeline Write concurrency when no -concurrency
+// sweep is given.
+const pipelineWorkers = 8
+
+// benchmarkDef is a benchmark with a generic runner usable by any driver
+// that has the capabilities it needs. Drivers may replace the runner via
+// driverSpec.Runners. Concurrent benchmarks honour Config.Workers and are
+// repeated at every -concurrency level.
+type benchmarkDef struct {
+	Name       string
+	Supports   func(c driverCaps) bool
+	Run        benchRunner
+	Concurrent bool
+}
 
-	for i := 0; i < warmup; i++ {
-		w.Write(ctx, key, float64(i))
+// benchmarks lists every benchmark in the order it runs.
+var benchmarks = []benchmarkDef{
+	{"Write (seq)", func(c driverCaps) bool { return c.Writer }, func(cfg *Config, d Driver, _ driverCaps) *BenchmarkResult {
+		return runWrite(cfg, d.(Writer))
+	}, true},
+	{"Pipeline Write", func(c driverCaps) bool { return c.Writer }, func(cfg *Config, d Driver, _ driverCaps) *BenchmarkResult {
+		workers := cfg.Workers
+		if workers == 0 {
+			workers = pipelineWorkers
+		}
+		return runPipelinedWrite(d.(Writer), benchSensorKey, cfg.Count, cfg.Runs, workers)
+	}, true},
+	{"Batch Write", func(c driverCaps) bool { return c.Writer }, func(cfg *Config, d Driver, _ driverCaps) *BenchmarkResult {
+		return runBatchWrite(d.(Writer), benchSensorKey, cfg.Count, cfg.Runs, cfg.Workers)
+	}, true},
+	{"Read (single)", func(c driverCaps) bool { return c.Reader }, func(cfg *Config, d Driver, _ driverCaps) *BenchmarkResult {
+		return runRead(cfg, d.(Reader))
+	}, true},
+	{"Range Read (short)", func(c driverCaps) bool { return c.Writer && c.RangeReader },
+		rangeBenchmark("Range Read (short)", shortRangeWindow, 0), true},
+	{"Range Read (long)", func(c driverCaps) bool { return c.Writer && c.RangeReader },
+		rangeBenchmark("Range Read (long)", longRangeWindow, 0), true},
+	{"Downsampled Read", func(c driverCaps) bool { return c.Writer && c.AggregateReader },
+		rangeBenchmark("Downsampled Read", rangeSpan, downsampleBucket), true},
+	{"Multi-Key Write", func(c driverCaps) bool { return c.Writer }, func(cfg *Config, d Driver, c driverCaps) *BenchmarkResult {
+		return runMultiWriteBatch(d.(Writer), cfg.Count/cfg.Sensors, cfg.Sensors, c.MaxBatch, cfg.Runs, cfg.Workers)
+	}, true},
+	{"Pub/Sub", func(c driverCaps) bool { return c.PubSuber }, func(cfg *Config, d Driver, _ driverCaps) *BenchmarkResult {
+		return runPubSubBenchmark(d.(PubSuber), benchSensorKey, cfg.Count, cfg.Runs)
+	}, false},
+	{"Multi-Key Read", func(c driverCaps) bool { return c.Writer && c.MultiReader }, func(cfg *Config, d Driver, _ driverCaps) *BenchmarkResult {
+		if err := preloadMultiKey(cfg, d.(Writer)); err != nil {
+			fmt.Fprintf(os.Stderr, "%s preload: %v\n", d.Name(), err)
+		}
+		return runMultiRead(d.(MultiReader), cfg.Sensors, multiKeyPoints, cfg.Runs, cfg.Workers)
+	}, true},
+	{"Key Listing", func(c driverCaps) bool { return c.Writer && c.KeyLister }, func(cfg *Config, d Driver, c driverCaps) *BenchmarkResult {
+		return runKeyListing(d.(Writer), d.(KeyLister), c.MaxBatch, cfg.Runs, cfg.Workers)
+	}, true},
+	{"Key Rename", func(c driverCaps) bool { return c.Writer && c.KeyRenamer }, func(cfg *Config, d Driver, c driverCaps) *BenchmarkResult {
+		kr := d.(KeyRenamer)
+		return runKeyAdmin("Key Rename", d.(Writer), c.MaxBatch, cfg.Runs, cfg.Workers, func(ctx context.Context, key string) error {
+			return kr.RenameKey(ctx, key, key+"_renamed")
+		})
+	}, true},
+	{"Key Delete", func(c driverCaps) bool { return c.Writer && c.KeyDeleter }, func(cfg *Config, d Driver, c driverCaps) *BenchmarkResult {
+		return runKeyAdmin("Key Delete", d.(Writer), c.MaxBatch, cfg.Runs, cfg.Workers, d.(KeyDeleter).DeleteKey)
+	}, true},
+	{"Bulk Load", func(c driverCaps) bool { return c.BulkLoader }, func(cfg *Config, d Driver, _ driverCaps) *BenchmarkResult {
+		return runBulkLoad(d.(BulkLoader), benchSensorKey, cfg.Count, cfg.Runs, cfg.Workers)
+	}, true},
+	{"Flush", func(c driverCaps) bool { return c.Writer && c.Flusher }, func(cfg *Config, d Driver, c driverCaps) *BenchmarkResult {
+		return runFlush(d.(Writer), d.(Flusher), c.MaxBatch, cfg.Runs)
+	}, false},
+	{"Export", func(c driverCaps) bool { return c.Writer && (c.Exporter || c.RangeReader) }, func(cfg *Config, d Driver, c driverCaps) *BenchmarkResult {
+		data, err := preloadRange(d.(Writer), c.MaxBatch)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s range preload: %v; skipping Export\n", d.Name(), err)
+			return nil
+		}
+		return runExport(d, data, cfg.Runs)
+	}, false},
+}
+
+// runWrite runs the single-point write benchmark in the mode selected by cfg.
+func runWrite(cfg *Config, w Writer) *BenchmarkResult {
+	if sched := cfg.openLoopSchedule(); sched != nil {
+		return runOpenLoopWrite(w, benchSensorKey, cfg.Warmup, cfg.Runs, sched, cfg.MaxInFlight)
 	}
+	if cfg.Duration > 0 {
+		return runTimedWrite(w, benchSensorKey, cfg.Warmup, cfg.Runs, cfg.Duration, cfg.Workers)
+	}
+	return runWriteBenchmark(w, benchSensorKey, cfg.Count, cfg.Warmup, cfg.Runs, cfg.Workers)
+}
 
-	for run := 0; run < runs; run++ {
-		start := time.Now()
-		var success, failure uint64
-		for i := 0; i < count; i++ {
-			if err := w.Write(ctx, key, float64(i)); err == nil {
-				success++
+// runRead runs the single-key read benchmark in the mode selected by cfg.
+func runRead(cfg *Config, r Reader) *BenchmarkResult {
+	if sched := cfg.openLoopSchedule(); sched != nil {
+		return runOpenLoopRead(r, benchSensorKey, cfg.Count, cfg.Runs, sched, cfg.MaxInFlight)
+	}
+	if cfg.Duration > 0 {
+		return runTimedRead(r, benchSensorKey, cfg.Count, cfg.Runs, cfg.Duration, cfg.Workers)
+	}
+	return runReadBenchmark(r, benchSensorKey, cfg.Count, readRuns(cfg.Runs), cfg.Workers)
+}
+
+// splitWork calls fn on workers goroutines, giving each a contiguous share
+// [lo, hi) of n items, and waits for them. Fewer than one worker counts as one.
+func splitWork(workers, n int, fn func(lo, hi int)) {
+	workers = max(1, min(workers, n))
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		lo, hi := w*n/workers, (w+1)*n/workers
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			fn(lo, hi)
+		}()
+	}
+	wg.Wait()
+}
+
+// writeConcurrently writes values 0..count-1 to key from workers goroutines,
+// recording each call's latency into hist.
+func writeConcurrently(w Writer, key string, count, workers int, hist *latencyHistogram) (success, failure uint64) {
+	ctx := context.Background()
+	var acc atomicAccumulator
+	splitWork(workers, count, func(lo, hi int) {
+		var s, f uint64
+		local := newLatencyHistogram()
+		for i := lo; i < hi; i++ {
+			opStart := time.Now()
+			err := w.Write(ctx, key, float64(i))
+			local.Record(time.Since(opStart))
+			if err == nil {
+				s++
 			} else {
-				failure++
+				f++
 			}
 		}
-		result.addRun(time.Since(start), success, failure)
-	}
-
-	result.compute()
-	return result
+		acc.addSuccess(s)
+		acc.addFailure(f)
+		hist.Merge(local)
+	})
+	return acc.successCount(), acc.failureCount()
 }
 
-// runPipelinedWrite performs concurrent writes to the same key using all available parallelism.
-// This tests each database's ability to handle write contention on a single timeseries,
-// which is a meaningful scenario for all databases (unlike the old GTSDB-only TCP pipeline).
-func runPipelinedWrite(w Writer, key string, count, runs int) *BenchmarkResult {
-	concurrency := 8 // number of concurrent workers
-	result := newBenchResult("Pipeline Write", w.Name(), count)
+// runWriteBenchmark performs single-point writes with warmup and multiple
+// runs, spread over workers goroutines (sequential when workers <= 1).
+func runWriteBenchmark(w Writer, key string, count, warmup, runs, workers int) *BenchmarkResult {
+	result := newBenchResult("Write (seq)", w.Name(), count)
 	ctx := context.Background()
 
+	for i := 0; i < warmup; i++ {
+		w.Write(ctx, key, float64(i))
+	}
+
 	for run := 0; run < runs; run++ {
-		var wg sync.WaitGroup
-		opsPerWorker := count / concurrency
-		var acc atomicAccumulator
 		start := time.Now()
-
-		for i := 0; i < concurrency; i++ {
-			wg.Add(1)
-			go func(workerIdx, startVal int) {
-				defer wg.Done()
-				var s, f uint64
-				for j := 0; j < opsPerWorker; j++ {
-					val := float64(startVal + j)
-					if err := w.Write(ctx, key, val); err == nil {
-						s++
-					} else {
-						f++
-					}
-				}
-				acc.addSuccess(s)
-				acc.addFailure(f)
-			}(i, i*opsPerWorker)
-		}
-		wg.Wait()
-
-		result.addRun(time.Since(start), acc.successCount(), acc.failureCount())
+		success, failure := writeConcurrently(w, key, count, workers, result.Latency)
+		result.addRun(time.Since(start), success, failure)
 	}
 
 	result.compute()
 	return result
 }
 
-// runPipelinedWriteGTSDB uses GTSDB's TCP pipelining (fire-and-forget sends, then collect ACKs).
-// This is strictly faster than the generic concurrent version for GTSDB because it
-// avoids per-call mutex contention and TCP round-trip delays.
-func runPipelinedWriteGTSDB(tcpAddr string, key string, count, runs int) *BenchmarkResult {
-	result := newBenchResult("Pipeline Write", "GTSDB", count)
+// runPipelinedWrite performs concurrent writes to the same key from concurrency workers.
+// This tests each database's ability to handle write contention on a single timeseries,
+// which is a meaningful scenario for all databases: every driver serves the workers from
+// its own connection pool.
+func runPipelinedWrite(w Writer, key string, count, runs, concurrency int) *BenchmarkResult {
+	result := newBenchResult("Pipeline Write", w.Name(), count)
 
 	for run := 0; run < runs; run++ {
-		conn, err := net.Dial("tcp", tcpAddr)
-		if err != nil {
-			result.addRun(0, 0, uint64(count))
-			continue
-		}
-		reader := bufio.NewReader(conn)
-
 		start := time.Now()
-
-		var success, failure uint64
-		for i := 0; i < count; i++ {
-			payload := fmt.Sprintf(`{"operation":"write","key":"%s","write":{"value":%f}}`, key, float64(i))
-			if _, err := conn.Write(append([]byte(payload), '\n')); err != nil {
-				failure++
-				continue
-			}
-		}
-
-		for i := 0; i < count; i++ {
-			if _, err := reader.ReadBytes('\n'); err != nil {
-				failure++
-			} else {
-				success++
-			}
-		}
-
+		success, failure := writeConcurrently(w, key, count, concurrency, result.Latency)
 		result.addRun(time.Since(start), success, failure)
-		conn.Close()
 	}
 
 	result.compute()
 	return result
 }
 
-// runBatchWrite performs bulk writes via batch API.
-func runBatchWrite(w Writer, key string, count, runs int) *BenchmarkResult {
+// runBatchWrite performs bulk writes via batch API, splitting each run's
+// points between workers concurrent WriteBatch calls.
+func runBatchWrite(w Writer, key string, count, runs, workers int) *BenchmarkResult {
 	result := newBenchResult("Batch Write", w.Name(), count)
 	ctx := context.Background()
 
@@ -131,34 +208,44 @@ func runBatchWrite(w Writer, key string, count, runs int) *BenchmarkResult {
 			points[i] = KeyedPoint{Key: key, Value: rand.Float64() * 100, Timestamp: ts + int64(i)}
 		}
 
+		var failed atomic.Uint64
 		start := time.Now()
-		err := w.WriteBatch(ctx, points)
-		if err == nil {
-			result.addRun(time.Since(start), uint64(count), 0)
-		} else {
-			result.addRun(time.Since(start), 0, uint64(count))
-		}
+		splitWork(workers, count, func(lo, hi int) {
+			opStart := time.Now()
+			err := w.WriteBatch(ctx, points[lo:hi])
+			result.recordOp(time.Since(opStart))
+			failed.Add(writeFailures(err, hi-lo))
+		})
+		result.addRun(time.Since(start), uint64(count)-failed.Load(), failed.Load())
 	}
 
 	result.compute()
 	return result
 }
 
-// runReadBenchmark performs individual read queries with warmup and multiple runs.
-func runReadBenchmark(r Reader, key string, lastX, runs int) *BenchmarkResult {
-	result := newBenchResult("Read (single)", r.Name(), 1)
+// runReadBenchmark performs read queries with warmup and multiple runs. Each
+// run issues one read per worker, all at once.
+func runReadBenchmark(r Reader, key string, lastX, runs, workers int) *BenchmarkResult {
+	workers = max(workers, 1)
+	result := newBenchResult("Read (single)", r.Name(), workers)
 	ctx := context.Background()
 
 	r.Read(ctx, key, lastX)
 
 	for run := 0; run < runs; run++ {
+		var acc atomicAccumulator
 		start := time.Now()
-		_, err := r.Read(ctx, key, lastX)
-		if err == nil {
-			result.addRun(time.Since(start), 1, 0)
-		} else {
-			result.addRun(time.Since(start), 0, 1)
-		}
+		splitWork(workers, workers, func(_, _ int) {
+			opStart := time.Now()
+			_, err := r.Read(ctx, key, lastX)
+			result.recordOp(time.Since(opStart))
+			if err == nil {
+				acc.addSuccess(1)
+			} else {
+				acc.addFailure(1)
+			}
+		})
+		result.addRun(time.Since(start), acc.successCount(), acc.failureCount())
 	}
 
 	result.compute()
@@ -166,12 +253,12 @@ func runReadBenchmark(r Reader, key string, lastX, runs int) *BenchmarkResult {
 }
 
 // runMultiWriteInflux performs concurrent multi-sensor writes via InfluxDB async WriteAPI.
-func runMultiWriteInflux(d *influxDriver, numPointsPerSensor, numSensors, runs int) *BenchmarkResult {
+func runMultiWriteInflux(d *influxDriver, numPointsPerSensor, numSensors, runs, workers int) *BenchmarkResult {
 	totalOps := numPointsPerSensor * numSensors
 	result := newBenchResult("Multi-Key Write", d.Name(), totalOps)
 
 	for run := 0; run < runs; run++ {
-		s, f, d := d.multiWrite(numPointsPerSensor, numSensors)
+		s, f, d := d.multiWrite(numPointsPerSensor, numSensors, workers)
 		result.addRun(d, s, f)
 	}
 	result.compute()
@@ -179,83 +266,94 @@ func runMultiWriteInflux(d *influxDriver, numPointsPerSensor, numSensors, runs i
 }
 
 // runMultiWriteVM performs concurrent multi-sensor writes via VictoriaMetrics.
-func runMultiWriteVM(d *vmDriver, numPointsPerSensor, numSensors, runs int) *BenchmarkResult {
+func runMultiWriteVM(d *vmDriver, numPointsPerSensor, numSensors, runs, workers int) *BenchmarkResult {
 	totalOps := numPointsPerSensor * numSensors
 	result := newBenchResult("Multi-Key Write", d.Name(), totalOps)
 
 	for run := 0; run < runs; run++ {
-		s, f, d := d.multiWrite(numPointsPerSensor, numSensors)
+		s, f, d := d.multiWrite(numPointsPerSensor, numSensors, workers)
 		result.addRun(d, s, f)
 	}
 	result.compute()
 	return result
 }
 
-// runReadManyVM uses VM's range query to read N points per sensor.
-func runReadManyVM(v *vmDriver, numSensors, pointsPerSensor, runs int) *BenchmarkResult {
+// runReadManyVM uses VM's range query to read N points per sensor, with
+// workers identical queries in flight per run.
+func runReadManyVM(v *vmDriver, numSensors, pointsPerSensor, runs, workers int) *BenchmarkResult {
+	workers = max(workers, 1)
 	totalOps := numSensors * pointsPerSensor
-	result := newBenchResult("Multi-Key Read", "VM", totalOps)
+	result := newBenchResult("Multi-Key Read", "VM", totalOps*workers)
 	ctx := context.Background()
 
-	for run := 0; run < runs; run++ {
-		keys := make([]string, numSensors)
-		for i := 0; i < numSensors; i++ {
-			keys[i] = fmt.Sprintf("bench_sensor_%d", i)
-		}
+	keys := sensorKeys(numSensors)
 
+	for run := 0; run < runs; run++ {
+		var acc atomicAccumulator
 		start := time.Now()
-		count, err := v.readMany(ctx, keys, pointsPerSensor)
-		if err == nil {
-			// count = total data points returned across all sensors
-			result.addRun(time.Since(start), uint64(count), 0)
-		} else {
-			result.addRun(time.Since(start), 0, uint64(count))
-		}
+		splitWork(workers, workers, func(_, _ int) {
+			opStart := time.Now()
+			count, err := v.readMany(ctx, keys, pointsPerSensor)
+			result.recordOp(time.Since(opStart))
+			if err == nil {
+				// count = total data points returned across all sensors
+				acc.addSuccess(uint64(count))
+			} else {
+				acc.addFailure(uint64(count))
+			}
+		})
+		result.addRun(time.Since(start), acc.successCount(), acc.failureCount())
 	}
 	result.compute()
 	return result
 }
 
 // runReadManyInflux performs many individual Flux queries over HTTP keep-alive.
-func runReadManyInflux(d *influxDriver, numSensors, pointsPerSensor, runs int) *BenchmarkResult {
+func runReadManyInflux(d *influxDriver, numSensors, pointsPerSensor, runs, workers int) *BenchmarkResult {
 	totalOps := numSensors * pointsPerSensor
 	result := newBenchResult("Multi-Key Read", d.Name(), totalOps)
 	ctx := context.Background()
 
 	for run := 0; run < runs; run++ {
 		start := time.Now()
-		s, f := d.readMany(ctx, numSensors, pointsPerSensor)
+		s, f := d.readMany(ctx, numSensors, pointsPerSensor, workers)
 		result.addRun(time.Since(start), s, f)
 	}
 	result.compute()
 	return result
 }
 
-// runMultiReadGTSDB uses GTSDB's multi-read API via the shared connection.
-func runMultiReadGTSDB(g *gtsdbDriver, numSensors, pointsPerSensor, runs int) *BenchmarkResult {
+// runMultiRead reads many keys in one MultiRead round-trip, with workers
+// round-trips in flight per run.
+func runMultiRead(m MultiReader, numSensors, pointsPerSensor, runs, workers int) *BenchmarkResult {
+	workers = max(workers, 1)
 	totalOps := numSensors * pointsPerSensor
-	result := newBenchResult("Multi-Key Read", "GTSDB", totalOps)
+	result := newBenchResult("Multi-Key Read", m.Name(), totalOps*workers)
 	ctx := context.Background()
 
-	keys := make([]string, numSensors)
-	for i := 0; i < numSensors; i++ {
-		keys[i] = fmt.Sprintf("bench_sensor_%d", i)
-	}
+	keys := sensorKeys(numSensors)
 
 	for run := 0; run < runs; run++ {
+		var acc atomicAccumulator
 		start := time.Now()
-		counts, err := g.MultiRead(ctx, keys, pointsPerSensor)
-		if err != nil {
-			result.addRun(time.Since(start), 0, uint64(totalOps))
-			continue
-		}
-		var success uint64
-		for _, c := range counts {
-			if c > 0 {
-				success++
+		splitWork(workers, workers, func(_, _ int) {
+			opStart := time.Now()
+			counts, err := m.MultiRead(ctx, keys, pointsPerSensor)
+			result.recordOp(time.Since(opStart))
+			if err != nil {
+				acc.addFailure(uint64(totalOps))
+				return
 			}
-		}
-		result.addRun(time.Since(start), success, uint64(totalOps)-success)
+			var success uint64
+			for _, c := range counts {
+				if c > 0 {
+					success++
+				}
+			}
+			acc.addSuccess(success)
+			acc.addFailure(uint64(totalOps) - success)
+		})
+		result.addRun(time.Since(start), acc.successCount(), acc.failureCount())
 	}
 	result.compute()
 	return result
@@ -268,11 +366,12 @@ type modelsDataPoint struct {
 	Value     float64 `json:"value"`
 }
 
-// runMultiWriteGTSDBBatch uses GTSDB's TCP batch-write API to write multiple sensors' data
-// in a single TCP request (no HTTP overhead).
-func runMultiWriteGTSDBBatch(g *gtsdbDriver, numPointsPerSensor, numSensors, runs int) *BenchmarkResult {
+// runMultiWriteBatch writes multiple sensors' data through WriteBatch, split
+// into calls of at most maxBatch points (0 = one call per run) that workers
+// goroutines send concurrently.
+func runMultiWriteBatch(w Writer, numPointsPerSensor, numSensors, maxBatch, runs, workers int) *BenchmarkResult {
 	totalOps := numPointsPerSensor * numSensors
-	result := newBenchResult("Multi-Key Write", "GTSDB", totalOps)
+	result := newBenchResult("Multi-Key Write", w.Name(), totalOps)
 	ctx := context.Background()
 
 	for run := 0; run < runs; run++ {
@@ -290,23 +389,26 @@ func runMultiWriteGTSDBBatch(g *gtsdbDriver, numPointsPerSensor, numSensors, run
 			}
 		}
 
-		start := time.Now()
-		var success, failure uint64
-		// Split into batches of up to 10000 points (GTSDB limit)
-		batchSize := 10000
+		batchSize := maxBatch
+		if batchSize <= 0 {
+			batchSize = len(allPoints)
+		}
+		var batches [][]KeyedPoint
 		for b := 0; b < len(allPoints); b += batchSize {
-			end := b + batchSize
-			if end > len(allPoints) {
-				end = len(allPoints)
-			}
-			err := g.writeBatchTCP(ctx, allPoints[b:end])
-			if err == nil {
-				success += uint64(end - b)
-			} else {
-				failure += uint64(end - b)
-			}
+			batches = append(batches, allPoints[b:min(b+batchSize, len(allPoints))])
 		}
-		result.addRun(time.Since(start), success, failure)
+
+		var failed atomic.Uint64
+		start := time.Now()
+		splitWork(workers, len(batches), func(lo, hi int) {
+			for _, batch := range batches[lo:hi] {
+				opStart := time.Now()
+				err := w.WriteBatch(ctx, batch)
+				result.recordOp(time.Since(opStart))
+				failed.Add(writeFailures(err, len(batch)))
+			}
+		})
+		result.addRun(time.Since(start), uint64(len(allPoints))-failed.Load(), failed.Load())
 	}
 	result.compute()
 	return result
@@ -328,30 +430,30 @@ func runPubSubBenchmark(p PubSuber, key string, count, runs int) *BenchmarkResul
 	return result
 }
 
-// preloadAndInit prepares data for read benchmarks.
-func preloadAndInit(cfg *Config, g *gtsdbDriver, i *influxDriver, v *vmDriver) {
-	if cfg.HasDB("gtsdb") && g != nil {
-		fmt.Println("Pre-loading GTSDB...")
-		g.initKeys(cfg.Sensors)
-		time.Sleep(100 * time.Millisecond)
-		g.preloadTCP(cfg.Sensors, 5000)
-	}
-	if cfg.HasDB("influx") && i != nil {
-		fmt.Println("Pre-loading InfluxDB...")
-		i.preload(cfg.Sensors, 5000)
-		time.Sleep(500 * time.Millisecond) // wait for async flush to complete
+// sensorKeys returns the multi-key read benchmark's keys.
+func sensorKeys(n int) []string {
+	keys := make([]string, n)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("bench_sensor_%d", i)
 	}
-	if cfg.HasDB("vm") && v != nil {
-		fmt.Println("Pre-loading VictoriaMetrics...")
-		for s := 0; s < cfg.Sensors; s++ {
-			points := make([]KeyedPoint, 5000)
-			key := fmt.Sprintf("bench_sensor_%d", s)
-			for j := 0; j < 5000; j++ {
-				points[j] = KeyedPoint{Key: key, Value: rand.Float64() * 100, Timestamp: 1700000000 + int64(j)}
-			}
-			v.WriteBatch(context.Background(), points)
+	return keys
+}
+
+// preloadMultiKey writes multiKeyPoints points for each of cfg.Sensors keys
+// through WriteBatch, for drivers without a specialised preload.
+func preloadMultiKey(cfg *Config, w Writer) error {
+	fmt.Printf("Pre-loading %s...\n", w.Name())
+	for s := 0; s < cfg.Sensors; s++ {
+		points := make([]KeyedPoint, multiKeyPoints)
+		key := fmt.Sprintf("bench_sensor_%d", s)
+		for j := 0; j < multiKeyPoints; j++ {
+			points[j] = KeyedPoint{Key: key, Value: rand.Float64() * 100, Timestamp: 1700000000 + int64(j)}
+		}
+		if err := w.WriteBatch(context.Background(), points); err != nil {
+			return err
 		}
-		time.Sleep(200 * time.Millisecond) // wait for VM ingestion
 	}
+	time.Sleep(200 * time.Millisecond) // wait for ingestion
 	fmt.Println("Pre-load done.")
+	return nil
 }
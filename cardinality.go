@@ -0,0 +1,411 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"text/tabwriter"
+	"time"
+)
+
+// cardinalityBatch is the number of points or keys per call in the
+// high-cardinality benchmarks, further capped by the driver's MaxBatch.
+const cardinalityBatch = 1000
+
+// highCardReads is the number of random-key reads per High-Card Read run,
+// each asking for the last highCardReadPoints points.
+const (
+	highCardReads      = 1000
+	highCardReadPoints = 10
+)
+
+// keyDist picks which key of a keyspace each write or read goes to.
+type keyDist struct {
+	// Kind is uniform, zipf or hot.
+	Kind string
+	// S is the Zipf exponent; it must be greater than 1.
+	S float64
+	// HotKeys is the fraction of keys in the hot set and HotShare the
+	// fraction of operations sent to it.
+	HotKeys  float64
+	HotShare float64
+}
+
+func (d keyDist) String() string {
+	switch d.Kind {
+	case "zipf":
+		return fmt.Sprintf("zipf:%g", d.S)
+	case "hot":
+		return fmt.Sprintf("hot:%g:%g", d.HotKeys, d.HotShare)
+	}
+	return "uniform"
+}
+
+// parseKeyDist parses a -key-dist value: uniform, zipf[:s] or
+// hot[:keys:share], e.g. hot:0.01:0.9 sends 90% of operations to 1% of keys.
+func parseKeyDist(s string) (keyDist, error) {
+	parts := strings.Split(s, ":")
+	args := make([]float64, len(parts)-1)
+	for i, p := range parts[1:] {
+		v, err := strconv.ParseFloat(p, 64)
+		if err != nil {
+			return keyDist{}, fmt.Errorf("invalid key distribution %q: %q is not a number", s, p)
+		}
+		args[i] = v
+	}
+	switch parts[0] {
+	case "", "uniform":
+		if len(args) > 0 {
+			return keyDist{}, fmt.Errorf("invalid key distribution %q: uniform takes no parameters", s)
+		}
+		return keyDist{Kind: "uniform"}, nil
+	case "zipf":
+		d := keyDist{Kind: "zipf", S: 1.1}
+		switch len(args) {
+		case 0:
+		case 1:
+			d.S = args[0]
+		default:
+			return keyDist{}, fmt.Errorf("invalid key distribution %q: expected zipf[:s]", s)
+		}
+		if d.S <= 1 {
+			return keyDist{}, fmt.Errorf("invalid key distribution %q: zipf exponent must be greater than 1", s)
+		}
+		return d, nil
+	case "hot":
+		d := keyDist{Kind: "hot", HotKeys: 0.01, HotShare: 0.9}
+		switch len(args) {
+		case 0:
+		case 2:
+			d.HotKeys, d.HotShare = args[0], args[1]
+		default:
+			return keyDist{}, fmt.Errorf("invalid key distribution %q: expected hot[:keys:share]", s)
+		}
+		if d.HotKeys <= 0 || d.HotKeys > 1 || d.HotShare < 0 || d.HotShare > 1 {
+			return keyDist{}, fmt.Errorf("invalid key distribution %q: hot fractions must be within (0, 1]", s)
+		}
+		return d, nil
+	}
+	return keyDist{}, fmt.Errorf("unknown key distribution %q: expected uniform, zipf[:s] or hot[:keys:share]", s)
+}
+
+// sampler returns a function drawing key indices in [0, n) from d. Lower
+// indices are the hotter ones.
+func (d keyDist) sampler(n int, r *rand.Rand) func() int {
+	switch d.Kind {
+	case "zipf":
+		if n > 1 {
+			z := rand.NewZipf(r, d.S, 1, uint64(n-1))
+			return func() int { return int(z.Uint64()) }
+		}
+	case "hot":
+		hot := max(1, int(d.HotKeys*float64(n)))
+		if hot < n {
+			return func() int {
+				if r.Float64() < d.HotShare {
+					return r.IntN(hot)
+				}
+				return hot + r.IntN(n-hot)
+			}
+		}
+	}
+	return func() int { return r.IntN(n) }
+}
+
+// parseCardinality parses a -cardinality list such as "1k,100k,1M" into
+// ascending, de-duplicated key counts.
+func parseCardinality(s string) ([]int, error) {
+	var levels []int
+	for _, p := range parseCSV(s) {
+		mult := 1
+		switch {
+		case strings.HasSuffix(p, "k"), strings.HasSuffix(p, "K"):
+			mult = 1000
+		case strings.HasSuffix(p, "M"):
+			mult = 1000000
+		}
+		digits := p
+		if mult > 1 {
+			digits = p[:len(p)-1]
+		}
+		n, err := strconv.Atoi(digits)
+		if err != nil || n <= 0 {
+			return nil, fmt.Errorf("invalid cardinality %q: expected a positive key count such as 10000, 100k or 1M", p)
+		}
+		if n *= mult; !slices.Contains(levels, n) {
+			levels = append(levels, n)
+		}
+	}
+	slices.Sort(levels)
+	return levels, nil
+}
+
+// cardinalityKey names key i of a high-cardinality keyspace.
+func cardinalityKey(prefix string, i int) string {
+	return prefix + strconv.Itoa(i)
+}
+
+// runCardinality runs the high-cardinality suite against w at every
+// -cardinality level. Levels share one keyspace: each creates only the keys
+// the previous level lacked, so series creation is paid once per key and
+// server memory is measured at exactly that many series.
+func runCardinality(cfg *Config, w Writer, maxBatch int, prefix string) []*BenchmarkResult {
+	batch := cardinalityBatch
+	if maxBatch > 0 {
+		batch = min(batch, maxBatch)
+	}
+	rng := rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64()))
+
+	var results []*BenchmarkResult
+	created := 0
+	for _, n := range cfg.Cardinality {
+		fmt.Printf("%s: creating keys %d..%d...\n", w.Name(), created, n-1)
+		level := []*BenchmarkResult{runKeyCreation(w, prefix, created, n, batch)}
+		created = n
+
+		sample := cfg.KeyDist.sampler(n, rng)
+		level = append(level, runHighCardWrite(w, prefix, sample, cfg.Count, batch, cfg.Runs))
+		if r, ok := w.(Reader); ok {
+			level = append(level, runHighCardRead(r, prefix, sample, readRuns(cfg.Runs)))
+		}
+
+		var mem uint64
+		if m, ok := w.(MemoryReporter); ok {
+			var err error
+			if mem, err = m.ServerMemory(context.Background()); err != nil {
+				fmt.Fprintf(os.Stderr, "%s server memory: %v\n", w.Name(), err)
+			}
+		}
+		for i, r := range level {
+			r.Cardinality = n
+			r.ServerMemory = mem
+			if i > 0 {
+				r.KeyDist = cfg.KeyDist.String()
+			}
+		}
+		results = append(results, level...)
+	}
+	return results
+}
+
+// runKeyCreation creates keys [from, to) in calls of batch keys, through
+// KeyCreator when the driver has it and otherwise by writing each key's
+// first point.
+func runKeyCreation(w Writer, prefix string, from, to, batch int) *BenchmarkResult {
+	result := newBenchResult("Key Creation", w.Name(), to-from)
+	ctx := context.Background()
+	ts := time.Now().Unix()
+
+	create := func(lo, hi int) error {
+		points := make([]KeyedPoint, hi-lo)
+		for i := range points {
+			points[i] = KeyedPoint{Key: cardinalityKey(prefix, lo+i), Value: rand.Float64() * 100, Timestamp: ts}
+		}
+		return w.WriteBatch(ctx, points)
+	}
+	if kc, ok := w.(KeyCreator); ok {
+		create = func(lo, hi int) error {
+			keys := make([]string, hi-lo)
+			for i := range keys {
+				keys[i] = cardinalityKey(prefix, lo+i)
+			}
+			return kc.CreateKeys(ctx, keys)
+		}
+	}
+
+	chunks := (to - from + batch - 1) / batch
+	var failed atomic.Uint64
+	start := time.Now()
+	splitWork(pipelineWorkers, chunks, func(lo, hi int) {
+		for c := lo; c < hi; c++ {
+			first := from + c*batch
+			last := min(first+batch, to)
+			opStart := time.Now()
+			err := create(first, last)
+			result.recordOp(time.Since(opStart))
+			failed.Add(writeFailures(err, last-first))
+		}
+	})
+	result.addRun(time.Since(start), uint64(to-from)-failed.Load(), failed.Load())
+	result.compute()
+	return result
+}
+
+// runHighCardWrite writes count points per run to keys drawn from sample,
+// in WriteBatch calls of batch points.
+func runHighCardWrite(w Writer, prefix string, sample func() int, count, batch, runs int) *BenchmarkResult {
+	result := newBenchResult("High-Card Write", w.Name(), count)
+	ctx := context.Background()
+
+	for run := 0; run < runs; run++ {
+		points := make([]KeyedPoint, count)
+		ts := time.Now().Unix()
+		for i := range points {
+			points[i] = KeyedPoint{Key: cardinalityKey(prefix, sample()), Value: rand.Float64() * 100, Timestamp: ts + int64(i)}
+		}
+
+		var failed atomic.Uint64
+		start := time.Now()
+		splitWork(pipelineWorkers, (count+batch-1)/batch, func(lo, hi int) {
+			for b := lo; b < hi; b++ {
+				chunk := points[b*batch : min((b+1)*batch, count)]
+				opStart := time.Now()
+				err := w.WriteBatch(ctx, chunk)
+				result.recordOp(time.Since(opStart))
+				failed.Add(writeFailures(err, len(chunk)))
+			}
+		})
+		result.addRun(time.Since(start), uint64(count)-failed.Load(), failed.Load())
+	}
+	result.compute()
+	return result
+}
+
+// runHighCardRead reads the latest points of highCardReads keys drawn from
+// sample per run.
+func runHighCardRead(r Reader, prefix string, sample func() int, runs int) *BenchmarkResult {
+	result := newBenchResult("High-Card Read", r.Name(), highCardReads)
+	ctx := context.Background()
+
+	for run := 0; run < runs; run++ {
+		keys := make([]string, highCardReads)
+		for i := range keys {
+			keys[i] = cardinalityKey(prefix, sample())
+		}
+
+		var acc atomicAccumulator
+		start := time.Now()
+		splitWork(pipelineWorkers, len(keys), func(lo, hi int) {
+			for _, key := range keys[lo:hi] {
+				opStart := time.Now()
+				_, err := r.Read(ctx, key, highCardReadPoints)
+				result.recordOp(time.Since(opStart))
+				if err == nil {
+					acc.addSuccess(1)
+				} else {
+					acc.addFailure(1)
+				}
+			}
+		})
+		result.addRun(time.Since(start), acc.successCount(), acc.failureCount())
+	}
+	result.compute()
+	return result
+}
+
+// scrapeResidentMemory reads process_resident_memory_bytes from a
+// Prometheus metrics endpoint.
+func scrapeResidentMemory(ctx context.Context, client *http.Client, metricsURL string) (uint64, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, metricsURL, nil)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("metrics: HTTP %d", resp.StatusCode)
+	}
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		value, ok := strings.CutPrefix(scanner.Text(), "process_resident_memory_bytes ")
+		if !ok {
+			continue
+		}
+		v, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil {
+			return 0, fmt.Errorf("metrics: bad resident memory %q", value)
+		}
+		return uint64(v), nil
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, err
+	}
+	return 0, fmt.Errorf("metrics: no process_resident_memory_bytes at %s", metricsURL)
+}
+
+// cardinalityTag describes the keyspace a result was measured on.
+func cardinalityTag(r *BenchmarkResult) string {
+	if r.KeyDist == "" {
+		return fmt.Sprintf("%d keys", r.Cardinality)
+	}
+	return fmt.Sprintf("%d keys, %s", r.Cardinality, r.KeyDist)
+}
+
+// cardinalityRow is one driver's results at one -cardinality level.
+type cardinalityRow struct {
+	Driver string
+	Keys   int
+	Memory uint64
+	// Results maps benchmark name to its result at this level.
+	Results map[string]*BenchmarkResult
+}
+
+// cardinalityRows groups high-cardinality results by driver and level, in
+// the order they were run.
+func cardinalityRows(results []*BenchmarkResult) []*cardinalityRow {
+	var rows []*cardinalityRow
+	index := make(map[string]*cardinalityRow)
+	for _, r := range results {
+		if r.Cardinality == 0 {
+			continue
+		}
+		id := fmt.Sprintf("%s/%d", r.DriverName, r.Cardinality)
+		row := index[id]
+		if row == nil {
+			row = &cardinalityRow{Driver: r.DriverName, Keys: r.Cardinality, Memory: r.ServerMemory, Results: make(map[string]*BenchmarkResult)}
+			index[id] = row
+			rows = append(rows, row)
+		}
+		row.Results[r.Name] = r
+	}
+	return rows
+}
+
+// printCardinality prints throughput and server memory against key count.
+// Memory per key is the growth since the driver's previous level divided by
+// the keys added.
+func printCardinality(results []*BenchmarkResult) {
+	rows := cardinalityRows(results)
+	if len(rows) == 0 {
+		return
+	}
+
+	fmt.Println("\n=== CARDINALITY ===")
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "Driver\tKeys\tCreate keys/s\tWrite pts/s\tRead ops/s\tRead P99\tServer RSS\tRSS/new key\t\n")
+	var prev *cardinalityRow
+	for _, row := range rows {
+		rate := func(name string) string {
+			if r := row.Results[name]; r != nil {
+				return fmt.Sprintf("%.0f", r.OpsPerSec)
+			}
+			return "-"
+		}
+		readP99 := "-"
+		if r := row.Results["High-Card Read"]; r != nil {
+			readP99 = r.OpLatency.P99.String()
+		}
+		rss, perKey := "-", "-"
+		if row.Memory > 0 {
+			rss = fmt.Sprintf("%.1f MB", float64(row.Memory)/(1<<20))
+			if prev != nil && prev.Driver == row.Driver && prev.Memory > 0 {
+				perKey = fmt.Sprintf("%.0f B", (float64(row.Memory)-float64(prev.Memory))/float64(row.Keys-prev.Keys))
+			}
+		}
+		fmt.Fprintf(w, "%s\t%d\t%s\t%s\t%s\t%s\t%s\t%s\t\n",
+			row.Driver, row.Keys, rate("Key Creation"), rate("High-Card Write"), rate("High-Card Read"), readP99, rss, perKey)
+		prev = row
+	}
+	w.Flush()
+}
@@ -0,0 +1,277 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"slices"
+	"text/tabwriter"
+)
+
+// Verdicts of a benchmark/driver pair between two runs.
+const (
+	verdictRegression  = "REGRESSION"
+	verdictImprovement = "improvement"
+	verdictNoChange    = "no significant change"
+	verdictUntested    = "too few runs to test"
+	verdictAdded       = "new"
+	verdictRemoved     = "removed"
+)
+
+// benchDiff compares one benchmark/driver pair across two runs.
+type benchDiff struct {
+	Label    string
+	Driver   string
+	Old, New *reportEntry
+	// Change is the relative change in mean throughput, New/Old - 1.
+	Change  float64
+	P       float64
+	Verdict string
+}
+
+// entryLabel names an entry's benchmark with the variant tags printTable
+// shows, so entries of different variants are never compared.
+func entryLabel(e reportEntry) string {
+	label := e.Name
+	if e.Mode != "" {
+		label += " [" + e.Mode + "]"
+	}
+	if e.Workers > 0 {
+		label += fmt.Sprintf(" [%d workers]", e.Workers)
+	}
+	if e.Cardinality > 0 {
+		label += fmt.Sprintf(" [%d keys", e.Cardinality)
+		if e.KeyDist != "" {
+			label += ", " + e.KeyDist
+		}
+		label += "]"
+	}
+	if e.FaultProfile != "" {
+		label += " {fault: " + e.FaultProfile + "}"
+	}
+	return label
+}
+
+// compareRuns pairs the entries of two runs and tests each pair's per-run
+// throughput with Welch's t-test. A pair regressed when its throughput fell
+// by more than threshold (a fraction) at significance level alpha.
+func compareRuns(old, cur runRecord, alpha, threshold float64) []benchDiff {
+	type key struct{ label, driver string }
+	var order []key
+	diffs := make(map[key]*benchDiff)
+	pair := func(entries []reportEntry, isNew bool) {
+		for i := range entries {
+			e := &entries[i]
+			k := key{entryLabel(*e), e.Driver}
+			d, ok := diffs[k]
+			if !ok {
+				d = &benchDiff{Label: k.label, Driver: k.driver, P: math.NaN()}
+				diffs[k] = d
+				order = append(order, k)
+			}
+			if isNew {
+				d.New = e
+			} else {
+				d.Old = e
+			}
+		}
+	}
+	pair(old.Results, false)
+	pair(cur.Results, true)
+
+	out := make([]benchDiff, len(order))
+	for i, k := range order {
+		d := diffs[k]
+		switch {
+		case d.Old == nil:
+			d.Verdict = verdictAdded
+		case d.New == nil:
+			d.Verdict = verdictRemoved
+		default:
+			// Change is of the per-run means the t-test compares.
+			if base := meanThroughput(d.Old.OpsPerSec, d.Old.RunOpsPerSec); base > 0 {
+				d.Change = meanThroughput(d.New.OpsPerSec, d.New.RunOpsPerSec)/base - 1
+			}
+			_, _, d.P = welchTTest(d.Old.RunOpsPerSec, d.New.RunOpsPerSec)
+			switch {
+			case math.IsNaN(d.P):
+				d.Verdict = verdictUntested
+			case d.P < alpha && d.Change < -threshold:
+				d.Verdict = verdictRegression
+			case d.P < alpha && d.Change > threshold:
+				d.Verdict = verdictImprovement
+			default:
+				d.Verdict = verdictNoChange
+			}
+		}
+		out[i] = *d
+	}
+	return out
+}
+
+// printDiffs prints the comparison of run old against run cur.
+func printDiffs(w io.Writer, old, cur runRecord, diffs []benchDiff) {
+	fmt.Fprintf(w, "Comparing %s -> %s\n", old.ID, cur.ID)
+	if old.Meta.GTSDBCommit != cur.Meta.GTSDBCommit {
+		fmt.Fprintf(w, "  GTSDB commit: %s -> %s\n", orDash(old.Meta.GTSDBCommit), orDash(cur.Meta.GTSDBCommit))
+	}
+	if old.Meta.Host != cur.Meta.Host {
+		fmt.Fprintf(w, "  (!) hosts differ: %s/%s %d CPUs -> %s/%s %d CPUs\n",
+			old.Meta.Host.Hostname, old.Meta.Host.Arch, old.Meta.Host.CPUs,
+			cur.Meta.Host.Hostname, cur.Meta.Host.Arch, cur.Meta.Host.CPUs)
+	}
+	for _, name := range flagChanges(old.Meta.Flags, cur.Meta.Flags) {
+		fmt.Fprintf(w, "  flag -%s: %s -> %s\n", name, orDash(old.Meta.Flags[name]), orDash(cur.Meta.Flags[name]))
+	}
+	fmt.Fprintln(w)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "Benchmark\tDriver\tOld ops/s\tNew ops/s\tChange\tp\tVerdict\n")
+	fmt.Fprintf(tw, "---------\t------\t---------\t---------\t------\t-\t-------\n")
+	for _, d := range diffs {
+		oldOps, newOps, change, p := "-", "-", "-", "-"
+		if d.Old != nil {
+			oldOps = fmt.Sprintf("%.0f", meanThroughput(d.Old.OpsPerSec, d.Old.RunOpsPerSec))
+		}
+		if d.New != nil {
+			newOps = fmt.Sprintf("%.0f", meanThroughput(d.New.OpsPerSec, d.New.RunOpsPerSec))
+		}
+		if d.Old != nil && d.New != nil {
+			change = fmt.Sprintf("%+.1f%%", d.Change*100)
+		}
+		switch {
+		case d.P < 0.001:
+			p = "<0.001"
+		case !math.IsNaN(d.P):
+			p = fmt.Sprintf("%.3f", d.P)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", d.Label, d.Driver, oldOps, newOps, change, p, d.Verdict)
+	}
+	tw.Flush()
+}
+
+// flagChanges returns the names of flags set differently in two runs.
+func flagChanges(old, cur map[string]string) []string {
+	var names []string
+	for name, v := range old {
+		if cur[name] != v {
+			names = append(names, name)
+		}
+	}
+	for name := range cur {
+		if _, ok := old[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	return names
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// runCompare implements "benchmark compare [OLD] [NEW]". NEW defaults to
+// the latest run and OLD to the baseline, or the run before NEW when no
+// baseline is set. It exits 1 when a benchmark regressed.
+func runCompare(args []string) int {
+	fs := flag.NewFlagSet("compare", flag.ContinueOnError)
+	dir := fs.String("results-dir", "results", "Directory runs are saved to")
+	alpha := fs.Float64("alpha", 0.05, "Significance level of the per-benchmark Welch t-test")
+	threshold := fs.Float64("threshold", 5, "Smallest throughput drop, in percent, reported as a regression")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: benchmark compare [flags] [OLD] [NEW]\n\n")
+		fmt.Fprintf(os.Stderr, "OLD and NEW are run IDs, run files, \"latest\" or \"baseline\". NEW defaults to\n")
+		fmt.Fprintf(os.Stderr, "latest; OLD to the baseline, or to the run before NEW when no baseline is set.\n")
+		fmt.Fprintf(os.Stderr, "Exits 1 when a benchmark regressed.\n\nFlags:\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() > 2 {
+		fs.Usage()
+		return 2
+	}
+
+	newRef, oldRef := "latest", ""
+	switch fs.NArg() {
+	case 1:
+		newRef = fs.Arg(0)
+	case 2:
+		oldRef, newRef = fs.Arg(0), fs.Arg(1)
+	}
+	cur, err := resolveRun(*dir, newRef)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+	if oldRef == "" {
+		oldRef, err = readBaseline(*dir)
+		if err == nil && (oldRef == "" || oldRef == cur.ID) {
+			oldRef, err = previousRun(*dir, cur.ID)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 2
+		}
+	}
+	old, err := resolveRun(*dir, oldRef)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+
+	diffs := compareRuns(old, cur, *alpha, *threshold/100)
+	printDiffs(os.Stdout, old, cur, diffs)
+	regressions := 0
+	for _, d := range diffs {
+		if d.Verdict == verdictRegression {
+			regressions++
+		}
+	}
+	if regressions > 0 {
+		fmt.Printf("\n%d regression(s) at p < %g\n", regressions, *alpha)
+		return 1
+	}
+	return 0
+}
+
+// runBaseline implements "benchmark baseline [RUN]", which makes RUN
+// (default latest) the run compare measures against.
+func runBaseline(args []string) int {
+	fs := flag.NewFlagSet("baseline", flag.ContinueOnError)
+	dir := fs.String("results-dir", "results", "Directory runs are saved to")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: benchmark baseline [flags] [RUN]\n\n")
+		fmt.Fprintf(os.Stderr, "Makes RUN (a run ID or \"latest\", default latest) the baseline of \"benchmark compare\".\n\nFlags:\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	ref := "latest"
+	switch fs.NArg() {
+	case 0:
+	case 1:
+		ref = fs.Arg(0)
+	default:
+		fs.Usage()
+		return 2
+	}
+	rec, err := resolveRun(*dir, ref)
+	if err == nil {
+		err = writeBaseline(*dir, rec.ID)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+	fmt.Printf("Baseline set to %s\n", rec.ID)
+	return 0
+}
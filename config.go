@@ -4,7 +4,10 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
 )
 
 type Config struct {
@@ -16,61 +19,203 @@ type Config struct {
 	InfluxBucket string
 	NSQAddr      string
 	VMURL        string
+	PromRWURL    string
+	PromQueryURL string
+
+	// GTSDBConns and GTSDBPipeline size the gtsdb driver's connection pool.
+	GTSDBConns    int
+	GTSDBPipeline int
+
+	// InfluxPrecision, InfluxGzip and InfluxBatch configure the influx-http driver.
+	InfluxPrecision string
+	InfluxGzip      bool
+	InfluxBatch     int
 
 	Count   int
 	Sensors int
 	Runs    int
 	Warmup  int
 
+	// Agg is the per-bucket reduction of the Downsampled Read benchmark.
+	Agg string
+
+	// Rate switches write and read runners to open-loop mode (ops/sec, 0 = closed-loop).
+	Rate        float64
+	MaxInFlight int
+
+	// Duration and Ramp switch write and read runners to time-based runs.
+	Duration time.Duration
+	Ramp     *rampRate
+
+	// Concurrency lists the -concurrency sweep levels. Workers is the level
+	// a benchmark is currently running at; 0 means its built-in default.
+	Concurrency []int
+	Workers     int
+
+	// Cardinality lists the -cardinality keyspace sizes; when set, the
+	// high-cardinality suite replaces the built-in benchmarks. KeyDist picks
+	// the keys it writes and reads.
+	Cardinality []int
+	KeyDist     keyDist
+
 	Format     string
 	Databases  []string
 	Benchmarks []string
+
+	// Scenario, when set, replaces the built-in benchmarks with a workload file.
+	Scenario *Scenario
+
+	// Verify writes a seeded dataset of VerifyPoints points per sensor and
+	// checks it reads back intact before benchmarking.
+	Verify       bool
+	VerifyPoints int
+
+	// Fault, when set, routes every driver connection through a proxy
+	// that injects these network faults.
+	Fault *FaultProfile
+
+	// ResultsDir is the run store each run is saved to; empty disables
+	// saving. GTSDBCommit is recorded with the run, and Flags holds the
+	// flags set on the command line.
+	ResultsDir  string
+	GTSDBCommit string
+	Flags       map[string]string
+
+	// Servers, when set, locates each driver's server process and data so
+	// their resource usage is sampled every SampleInterval.
+	Servers        *serverConfig
+	SampleInterval time.Duration
 }
 
-var validDBs = map[string]bool{"gtsdb": true, "influx": true, "nsq": true, "vm": true}
-var validBenches = map[string]bool{
-	"Write (seq)":     true,
-	"Read (single)":   true,
-	"Batch Write":     true,
-	"Multi-Key Write": true,
-	"Pipeline Write":  true,
-	"Multi-Key Read":  true,
-	"Pub/Sub":         true,
-	"all":             true,
+// benchmarkNames returns the names accepted as positional arguments.
+func benchmarkNames() []string {
+	names := make([]string, 0, len(benchmarks)+1)
+	for _, b := range benchmarks {
+		names = append(names, b.Name)
+	}
+	return append(names, "all")
 }
 
 func ParseConfig() *Config {
 	cfg := &Config{}
 
-	flag.StringVar(&cfg.GTSDBAddr, "gtsdb-addr", "localhost:5555", "GTSDB TCP address")
-	flag.StringVar(&cfg.GTSDBHTTP, "gtsdb-http", "localhost:5556", "GTSDB HTTP address")
-	flag.StringVar(&cfg.InfluxURL, "influx-url", "http://localhost:8086", "InfluxDB URL")
-	flag.StringVar(&cfg.InfluxToken, "influx-token", os.Getenv("INFLUX_TOKEN"), "InfluxDB token (env: INFLUX_TOKEN)")
-	flag.StringVar(&cfg.InfluxOrg, "influx-org", "bench", "InfluxDB organization")
-	flag.StringVar(&cfg.InfluxBucket, "influx-bucket", "bench", "InfluxDB bucket")
-	flag.StringVar(&cfg.NSQAddr, "nsq-addr", "localhost:4150", "NSQ TCP address")
-	flag.StringVar(&cfg.VMURL, "vm-url", "http://localhost:8428", "VictoriaMetrics URL")
+	registerDriverFlags(flag.CommandLine, cfg)
 
 	flag.IntVar(&cfg.Count, "count", 10000, "Points per write benchmark")
 	flag.IntVar(&cfg.Sensors, "sensors", 10, "Number of sensors for multi-write")
 	flag.IntVar(&cfg.Runs, "runs", 3, "Number of benchmark runs")
+	flag.StringVar(&cfg.Agg, "agg", "avg", "Per-bucket aggregation for \"Downsampled Read\": "+strings.Join(aggregations, ", "))
 	flag.IntVar(&cfg.Warmup, "warmup", 1000, "Warmup iterations")
+	flag.IntVar(&cfg.MaxInFlight, "max-inflight", 64, "Maximum concurrent operations in open-loop mode")
+	flag.DurationVar(&cfg.Duration, "duration", 0, "Run write/read benchmarks for a fixed time per run instead of -count ops")
+	flag.BoolVar(&cfg.Verify, "verify", false, "Check each database returns a seeded dataset intact and flag those that do not")
+	flag.IntVar(&cfg.VerifyPoints, "verify-points", 1000, "Points per sensor written and read back by -verify")
+	flag.StringVar(&cfg.ResultsDir, "results-dir", "results", "Directory runs are saved to for \"benchmark compare\"; empty disables saving")
+	flag.DurationVar(&cfg.SampleInterval, "sample-interval", 250*time.Millisecond, "How often -servers resource usage is sampled")
+	flag.StringVar(&cfg.GTSDBCommit, "gtsdb-commit", "", "GTSDB git commit recorded with the run (default: HEAD of the repository in ..)")
 
-	dbStr := flag.String("db", "gtsdb,influx", "Databases: gtsdb,influx,nsq,vm")
+	dbStr := flag.String("db", "gtsdb,influx", "Databases: "+strings.Join(driverNames(), ","))
 	formatStr := flag.String("format", "text", "Output format: text, json")
+	rateStr := flag.String("rate", "", "Open-loop target rate for write/read benchmarks, e.g. 50000/s")
+	serversPath := flag.String("servers", "", "Servers file (see \"benchmark servers\") whose processes and data directories are sampled during benchmarks")
+	scenarioPath := flag.String("scenario", "", "Run a workload file (YAML or JSON) instead of the built-in benchmarks")
+	concurrencyStr := flag.String("concurrency", "", "Run each benchmark at every worker count in this list, e.g. 1,2,4,8,16,64")
+	cardinalityStr := flag.String("cardinality", "", "Run the high-cardinality suite at every keyspace size in this list, e.g. 10k,100k,1M")
+	keyDistStr := flag.String("key-dist", "uniform", "Key distribution for -cardinality: uniform, zipf[:s] or hot[:keys:share]")
+	faultStr := flag.String("fault", "", "Route driver traffic through a fault-injection proxy: "+strings.Join(faultPresetNames(), ", ")+" or e.g. latency=20ms,jitter=5ms,bandwidth=1MB,reset=0.01,partial=0.01")
+	rampStr := flag.String("ramp", "", "Open-loop linear rate ramp for write/read benchmarks, e.g. 10s:1000->100000ops")
 
 	flag.Usage = func() {
 		fmt.Fprintf(os.Stderr, "Usage: benchmark [flags] [benchmark...]\n\n")
 		fmt.Fprintf(os.Stderr, "Flags:\n")
 		flag.PrintDefaults()
-		fmt.Fprintf(os.Stderr, "\nBenchmarks: Write (seq), Read (single), Batch Write, Multi-Key Write, Pipeline Write, Multi-Key Read, Pub/Sub, all\n")
+		fmt.Fprintf(os.Stderr, "\nBenchmarks: %s\n", strings.Join(benchmarkNames(), ", "))
 		fmt.Fprintf(os.Stderr, "\nExample: benchmark -count=100000 -runs=5 \"Write (seq)\" \"Multi-Key Write\"\n")
+		fmt.Fprintf(os.Stderr, "Open-loop: benchmark -rate=50000/s -max-inflight=128 \"Write (seq)\" \"Read (single)\"\n")
+		fmt.Fprintf(os.Stderr, "Scenario: benchmark -scenario=scenarios/mixed.yaml\n")
+		fmt.Fprintf(os.Stderr, "Verify: benchmark -verify -verify-points=500 \"Write (seq)\"\n")
+		fmt.Fprintf(os.Stderr, "Scalability: benchmark -concurrency=1,2,4,8,16,64 \"Write (seq)\" \"Read (single)\"\n")
+		fmt.Fprintf(os.Stderr, "Cardinality: benchmark -cardinality=10k,100k,1M -key-dist=zipf:1.2\n")
+		fmt.Fprintf(os.Stderr, "Faults: benchmark -fault=wan  or  benchmark -fault=latency=5ms,reset=0.001 \"Write (seq)\"\n")
+		fmt.Fprintf(os.Stderr, "Resources: benchmark servers start && benchmark -servers=servers.yaml\n")
+		fmt.Fprintf(os.Stderr, "Time-based: benchmark -duration=60s \"Write (seq)\"  or  benchmark -ramp=10s:1000->100000ops \"Write (seq)\"\n")
+		fmt.Fprintf(os.Stderr, "\nSubcommands: %s (run \"benchmark <subcommand> -h\" for help)\n", strings.Join(subcommandNames(), ", "))
 	}
 
 	flag.Parse()
 
 	cfg.Databases = parseCSV(*dbStr)
 	cfg.Format = *formatStr
+	cfg.Flags = setFlags(flag.CommandLine)
+
+	rate, err := parseRate(*rateStr)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		flag.Usage()
+		os.Exit(1)
+	}
+	cfg.Rate = rate
+
+	ramp, err := parseRamp(*rampStr)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		flag.Usage()
+		os.Exit(1)
+	}
+	cfg.Ramp = ramp
+
+	levels, err := parseConcurrency(*concurrencyStr)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		flag.Usage()
+		os.Exit(1)
+	}
+	cfg.Concurrency = levels
+
+	cardinality, err := parseCardinality(*cardinalityStr)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		flag.Usage()
+		os.Exit(1)
+	}
+	cfg.Cardinality = cardinality
+
+	dist, err := parseKeyDist(*keyDistStr)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		flag.Usage()
+		os.Exit(1)
+	}
+	cfg.KeyDist = dist
+
+	fault, err := parseFaultProfile(*faultStr)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		flag.Usage()
+		os.Exit(1)
+	}
+	cfg.Fault = fault
+
+	if *scenarioPath != "" {
+		sc, err := loadScenario(*scenarioPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		cfg.Scenario = sc
+		if len(sc.Databases) > 0 {
+			cfg.Databases = sc.Databases
+		}
+	}
+
+	if *serversPath != "" {
+		servers, err := loadServerConfig(*serversPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		cfg.Servers = servers
+	}
 
 	cfg.Benchmarks = flag.Args()
 	if len(cfg.Benchmarks) == 0 {
@@ -87,13 +232,12 @@ func ParseConfig() *Config {
 }
 
 func (c *Config) Validate() error {
-	for _, db := range c.Databases {
-		if !validDBs[db] {
-			return fmt.Errorf("unknown database: %s", db)
-		}
+	if err := c.validateDrivers(); err != nil {
+		return err
 	}
+	valid := benchmarkNames()
 	for _, b := range c.Benchmarks {
-		if !validBenches[b] {
+		if !contains(valid, b) {
 			return fmt.Errorf("unknown benchmark: %s", b)
 		}
 	}
@@ -103,8 +247,52 @@ func (c *Config) Validate() error {
 	if c.Runs <= 0 {
 		return fmt.Errorf("runs must be positive")
 	}
-	if c.InfluxToken == "" {
-		return fmt.Errorf("influx-token is required (set via --influx-token or INFLUX_TOKEN env)")
+	if c.Agg != "" && !contains(aggregations, c.Agg) {
+		return fmt.Errorf("unknown aggregation %q: expected %s", c.Agg, strings.Join(aggregations, ", "))
+	}
+	if c.Duration < 0 {
+		return fmt.Errorf("duration must not be negative")
+	}
+	if c.Ramp != nil && c.Rate > 0 {
+		return fmt.Errorf("-ramp and -rate are mutually exclusive")
+	}
+	if (c.Rate > 0 || c.Ramp != nil) && c.MaxInFlight <= 0 {
+		return fmt.Errorf("max-inflight must be positive")
+	}
+	if len(c.Concurrency) > 0 && (c.Rate > 0 || c.Ramp != nil) {
+		return fmt.Errorf("-concurrency does not apply to open-loop runs; size them with -max-inflight")
+	}
+	if len(c.Concurrency) > 0 && c.Scenario != nil {
+		return fmt.Errorf("-concurrency does not apply to -scenario; set concurrency in the workload file")
+	}
+	if len(c.Cardinality) > 0 && (c.Scenario != nil || len(c.Concurrency) > 0 || c.Rate > 0 || c.Ramp != nil) {
+		return fmt.Errorf("-cardinality runs its own suite and cannot be combined with -scenario, -concurrency, -rate or -ramp")
+	}
+	if c.Verify && (c.VerifyPoints <= 0 || c.Sensors <= 0) {
+		return fmt.Errorf("-verify needs positive verify-points and sensors")
+	}
+	if c.Verify && c.VerifyPoints > verifyMaxPoints {
+		return fmt.Errorf("-verify-points must be at most %d: point reads only look back an hour", verifyMaxPoints)
+	}
+	if c.Servers != nil && c.SampleInterval <= 0 {
+		return fmt.Errorf("-sample-interval must be positive with -servers")
+	}
+	return nil
+}
+
+// validateDrivers checks the selected databases exist and their
+// driver-specific settings.
+func (c *Config) validateDrivers() error {
+	for _, db := range c.Databases {
+		spec, ok := lookupDriver(db)
+		if !ok {
+			return fmt.Errorf("unknown database: %s", db)
+		}
+		if spec.Validate != nil {
+			if err := spec.Validate(c); err != nil {
+				return err
+			}
+		}
 	}
 	return nil
 }
@@ -125,6 +313,20 @@ func (c *Config) HasBench(name string) bool {
 	return contains(c.Benchmarks, name)
 }
 
+// openLoopSchedule returns the open-loop schedule selected by cfg, or nil
+// for closed-loop runs.
+func (c *Config) openLoopSchedule() openLoopSchedule {
+	switch {
+	case c.Ramp != nil:
+		return *c.Ramp
+	case c.Rate > 0 && c.Duration > 0:
+		return fixedRate{rate: c.Rate, duration: c.Duration}
+	case c.Rate > 0:
+		return fixedRate{rate: c.Rate, count: c.Count}
+	}
+	return nil
+}
+
 func parseCSV(s string) []string {
 	parts := strings.Split(s, ",")
 	var result []string
@@ -136,6 +338,23 @@ func parseCSV(s string) []string {
 	return result
 }
 
+// parseConcurrency parses a -concurrency list such as "1,2,4,8" into
+// ascending, de-duplicated worker counts.
+func parseConcurrency(s string) ([]int, error) {
+	var levels []int
+	for _, p := range parseCSV(s) {
+		n, err := strconv.Atoi(p)
+		if err != nil || n <= 0 {
+			return nil, fmt.Errorf("invalid concurrency level %q: expected a positive integer", p)
+		}
+		if !slices.Contains(levels, n) {
+			levels = append(levels, n)
+		}
+	}
+	slices.Sort(levels)
+	return levels, nil
+}
+
 func contains(slice []string, s string) bool {
 	for _, v := range slice {
 		if v == s {
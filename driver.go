@@ -2,6 +2,8 @@ package main
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"time"
 )
 
@@ -22,18 +24,126 @@ type Reader interface {
 	Read(ctx context.Context, key string, lastX int) (int, error)
 }
 
+// RangeReader reads all points of a key between start and end (unix seconds, inclusive).
+type RangeReader interface {
+	Driver
+	ReadRange(ctx context.Context, key string, start, end int64) (int, error)
+}
+
+// AggregateReader downsamples the points of a key between start and end
+// (unix seconds, inclusive) into buckets of bucket seconds, reducing each
+// bucket with agg (one of aggregations), and returns the number of buckets.
+type AggregateReader interface {
+	Driver
+	ReadAggregate(ctx context.Context, key string, start, end, bucket int64, agg string) (int, error)
+}
+
+// aggregations lists the per-bucket reductions AggregateReader accepts.
+var aggregations = []string{"avg", "min", "max"}
+
 type MultiReader interface {
 	Driver
 	MultiRead(ctx context.Context, keys []string, lastX int) (map[string]int, error)
 }
 
+// PointReader returns the last lastX points of a key with their values, in
+// the order the database returned them.
+type PointReader interface {
+	Driver
+	ReadPoints(ctx context.Context, key string, lastX int) ([]KeyedPoint, error)
+}
+
+// MultiPointReader is the multi-key counterpart of PointReader.
+type MultiPointReader interface {
+	Driver
+	MultiReadPoints(ctx context.Context, keys []string, lastX int) (map[string][]KeyedPoint, error)
+}
+
 type PubSuber interface {
 	Driver
 	PubSub(ctx context.Context, key string, count int) (time.Duration, error)
 }
 
+// KeyCreator creates empty series ahead of their first write. Drivers
+// without it create a series by writing its first point.
+type KeyCreator interface {
+	Driver
+	CreateKeys(ctx context.Context, keys []string) error
+}
+
+// MemoryReporter reports the resident memory of the database server.
+type MemoryReporter interface {
+	Driver
+	ServerMemory(ctx context.Context) (uint64, error)
+}
+
+// KeyLister lists every key the database holds and returns how many there
+// are.
+type KeyLister interface {
+	Driver
+	ListKeys(ctx context.Context) (int, error)
+}
+
+// KeyRenamer renames a key, keeping its points.
+type KeyRenamer interface {
+	Driver
+	RenameKey(ctx context.Context, from, to string) error
+}
+
+// KeyDeleter deletes a key and all of its points.
+type KeyDeleter interface {
+	Driver
+	DeleteKey(ctx context.Context, key string) error
+}
+
+// BulkLoader ingests points of one key through the database's bulk import
+// path rather than its regular writes.
+type BulkLoader interface {
+	Driver
+	BulkLoad(ctx context.Context, key string, points []KeyedPoint) error
+}
+
+// Flusher forces buffered writes to durable storage.
+type Flusher interface {
+	Driver
+	Flush(ctx context.Context) error
+}
+
+// Exporter streams every point of a key out of the database and returns how
+// many there were.
+type Exporter interface {
+	Driver
+	Export(ctx context.Context, key string) (int, error)
+}
+
 type KeyedPoint struct {
 	Key       string
 	Value     float64
 	Timestamp int64
 }
+
+// partialWriteError is returned by WriteBatch when only some of the points
+// were stored.
+type partialWriteError struct {
+	Failed int
+	Total  int
+	Err    error
+}
+
+func (e *partialWriteError) Error() string {
+	return fmt.Sprintf("%d of %d points not written: %v", e.Failed, e.Total, e.Err)
+}
+
+func (e *partialWriteError) Unwrap() error { return e.Err }
+
+// writeFailures returns how many of n points a WriteBatch error covers.
+func writeFailures(err error, n int) uint64 {
+	var pe *partialWriteError
+	if errors.As(err, &pe) {
+		return uint64(min(pe.Failed, n))
+	}
+	if err != nil {
+		return uint64(n)
+	}
+	return 0
+}
@@ -0,0 +1,418 @@
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeFault selects how fakeGTSDB answers one request.
+type fakeFault int
+
+const (
+	fakeOK      fakeFault = iota
+	fakeReject            // answer {"success":false}
+	fakeDrop              // close the connection without answering
+	fakeGarbage           // answer with a malformed JSON line or a truncated binary frame
+)
+
+// fakeGTSDBConfig injects latency and faults into a fakeGTSDB.
+type fakeGTSDBConfig struct {
+	// Latency delays every response.
+	Latency time.Duration
+	// Fault, if set, decides the fate of each request. seq counts requests
+	// across all connections, starting at 1.
+	Fault func(op string, seq int64) fakeFault
+	// MaxBatch rejects batch-writes with more points; 0 means gtsdbMaxBatch.
+	MaxBatch int
+}
+
+// fakeGTSDB is an in-process server speaking GTSDB's newline-delimited JSON
+// protocol: write, batch-write, data-patch, read, multi-read, subscribe,
+// unsubscribe, initkey, keys, renamekey, delete and flush, with JSON or
+// binary read responses.
+type fakeGTSDB struct {
+	cfg  fakeGTSDBConfig
+	ln   net.Listener
+	seq  atomic.Int64
+	ops  sync.Map // operation name -> *atomic.Int64
+	mu   sync.Mutex
+	data map[string][]gtsdbDataPoint
+	subs map[string][]*fakeConn
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+type fakeConn struct {
+	net.Conn
+	mu sync.Mutex // serialises responses and subscription pushes
+	w  *bufio.Writer
+}
+
+func (c *fakeConn) send(b []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, err := c.w.Write(b); err != nil {
+		return err
+	}
+	return c.w.Flush()
+}
+
+type fakeRequest struct {
+	Operation string                  `json:"operation"`
+	Key       string                  `json:"key"`
+	Keys      []string                `json:"keys"`
+	Write     struct{ Value float64 } `json:"write"`
+	Points    []gtsdbDataPoint        `json:"points"`
+	ToKey     string                  `json:"toKey"`
+	Data      string                  `json:"data"`
+	Read      struct {
+		LastX        int   `json:"lastx"`
+		Start        int64 `json:"start_timestamp"`
+		End          int64 `json:"end_timestamp"`
+		Downsampling int64 `json:"downsampling"`
+	} `json:"read"`
+	ResponseFormat string `json:"response_format"`
+}
+
+// newFakeGTSDB starts a fake server on a loopback port. It stops when the
+// test ends.
+func newFakeGTSDB(t *testing.T, cfg fakeGTSDBConfig) *fakeGTSDB {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.MaxBatch == 0 {
+		cfg.MaxBatch = gtsdbMaxBatch
+	}
+	s := &fakeGTSDB{
+		cfg:  cfg,
+		ln:   ln,
+		data: make(map[string][]gtsdbDataPoint),
+		subs: make(map[string][]*fakeConn),
+		quit: make(chan struct{}),
+	}
+	s.wg.Add(1)
+	go s.serve()
+	t.Cleanup(func() {
+		close(s.quit)
+		ln.Close()
+		s.wg.Wait()
+	})
+	return s
+}
+
+func (s *fakeGTSDB) Addr() string { return s.ln.Addr().String() }
+
+// count returns how many requests of the given operation were received.
+func (s *fakeGTSDB) count(op string) int64 {
+	if n, ok := s.ops.Load(op); ok {
+		return n.(*atomic.Int64).Load()
+	}
+	return 0
+}
+
+// points returns a copy of everything stored under key.
+func (s *fakeGTSDB) points(key string) []gtsdbDataPoint {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]gtsdbDataPoint(nil), s.data[key]...)
+}
+
+func (s *fakeGTSDB) serve() {
+	defer s.wg.Done()
+	var conns sync.WaitGroup
+	defer conns.Wait()
+	for {
+		nc, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+		c := &fakeConn{Conn: nc, w: bufio.NewWriter(nc)}
+		conns.Add(1)
+		go func() {
+			defer conns.Done()
+			s.handle(c)
+		}()
+	}
+}
+
+func (s *fakeGTSDB) handle(c *fakeConn) {
+	defer c.Close()
+	defer s.unsubscribeAll(c)
+	// Close the connection when the server stops so handlers do not
+	// outlive the test.
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-done:
+		case <-s.quit:
+			c.Close()
+		}
+	}()
+
+	r := bufio.NewReaderSize(c, 1<<20)
+	for {
+		line, err := r.ReadBytes('\n')
+		if err != nil {
+			return
+		}
+		var req fakeRequest
+		if err := json.Unmarshal(line, &req); err != nil {
+			c.send([]byte(`{"success":false,"message":"invalid json"}` + "\n"))
+			continue
+		}
+		n, _ := s.ops.LoadOrStore(req.Operation, new(atomic.Int64))
+		n.(*atomic.Int64).Add(1)
+
+		fault := fakeOK
+		if s.cfg.Fault != nil {
+			fault = s.cfg.Fault(req.Operation, s.seq.Add(1))
+		}
+		if s.cfg.Latency > 0 {
+			time.Sleep(s.cfg.Latency)
+		}
+		switch fault {
+		case fakeDrop:
+			return
+		case fakeReject:
+			c.send([]byte(`{"success":false,"message":"injected fault"}` + "\n"))
+			continue
+		}
+
+		resp := s.respond(c, &req)
+		if fault == fakeGarbage {
+			if req.ResponseFormat == "binary" {
+				// Keep the length prefix consistent so the client reads the
+				// whole frame and has to notice the truncated body itself.
+				body := resp[4 : 4+(len(resp)-4)/2]
+				resp = append(binary.BigEndian.AppendUint32(nil, uint32(len(body))), body...)
+			} else {
+				resp = append(resp[:len(resp)/2], '\n')
+			}
+		}
+		if err := c.send(resp); err != nil {
+			return
+		}
+	}
+}
+
+// ServeHTTP answers one JSON operation per POST, as GTSDB's HTTP front-end
+// does. Subscriptions and injected faults are TCP-only.
+func (s *fakeGTSDB) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req fakeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"success":false,"message":"invalid json"}`, http.StatusBadRequest)
+		return
+	}
+	n, _ := s.ops.LoadOrStore(req.Operation, new(atomic.Int64))
+	n.(*atomic.Int64).Add(1)
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(s.respond(nil, &req))
+}
+
+func (s *fakeGTSDB) respond(c *fakeConn, req *fakeRequest) []byte {
+	switch req.Operation {
+	case "write":
+		p := gtsdbDataPoint{Key: req.Key, Timestamp: time.Now().Unix(), Value: req.Write.Value}
+		s.store([]gtsdbDataPoint{p})
+		return okLine("Data point stored")
+	case "batch-write":
+		if len(req.Points) > s.cfg.MaxBatch {
+			return []byte(fmt.Sprintf(`{"success":false,"message":"batch of %d exceeds %d"}`+"\n", len(req.Points), s.cfg.MaxBatch))
+		}
+		s.store(req.Points)
+		return okLine("Batch stored")
+	case "initkey":
+		s.mu.Lock()
+		if _, ok := s.data[req.Key]; !ok {
+			s.data[req.Key] = nil
+		}
+		s.mu.Unlock()
+		return okLine("Key initialized")
+	case "subscribe":
+		s.mu.Lock()
+		s.subs[req.Key] = append(s.subs[req.Key], c)
+		s.mu.Unlock()
+		return okLine("Subscribed")
+	case "unsubscribe":
+		s.unsubscribe(c, req.Key)
+		return okLine("Unsubscribed")
+	case "data-patch":
+		var points []gtsdbDataPoint
+		for _, line := range strings.Split(req.Data, "\n") {
+			var p gtsdbDataPoint
+			if _, err := fmt.Sscanf(line, "%d,%g", &p.Timestamp, &p.Value); err != nil {
+				return []byte(fmt.Sprintf(`{"success":false,"message":"bad line %q"}`+"\n", line))
+			}
+			p.Key = req.Key
+			points = append(points, p)
+		}
+		s.store(points)
+		return okLine("Data patched")
+	case "keys":
+		s.mu.Lock()
+		keys := make([]string, 0, len(s.data))
+		for key := range s.data {
+			keys = append(keys, key)
+		}
+		s.mu.Unlock()
+		resp, _ := json.Marshal(map[string]any{"success": true, "data": keys})
+		return append(resp, '\n')
+	case "renamekey":
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		pts, ok := s.data[req.Key]
+		if !ok {
+			return []byte(`{"success":false,"message":"key not found"}` + "\n")
+		}
+		delete(s.data, req.Key)
+		for i := range pts {
+			pts[i].Key = req.ToKey
+		}
+		s.data[req.ToKey] = pts
+		return okLine("Key renamed")
+	case "delete":
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if _, ok := s.data[req.Key]; !ok {
+			return []byte(`{"success":false,"message":"key not found"}` + "\n")
+		}
+		delete(s.data, req.Key)
+		return okLine("Key deleted")
+	case "flush":
+		return okLine("Flushed")
+	case "read":
+		return s.encodeRead(req, []string{req.Key}, false)
+	case "multi-read":
+		return s.encodeRead(req, req.Keys, true)
+	}
+	return []byte(fmt.Sprintf(`{"success":false,"message":"unknown operation %q"}`+"\n", req.Operation))
+}
+
+func okLine(msg string) []byte {
+	return []byte(`{"success":true,"message":"` + msg + `"}` + "\n")
+}
+
+// store appends points, keeping each key sorted by timestamp, and pushes
+// them to subscribers.
+func (s *fakeGTSDB) store(points []gtsdbDataPoint) {
+	s.mu.Lock()
+	var pushes []func()
+	touched := make(map[string]bool)
+	for _, p := range points {
+		s.data[p.Key] = append(s.data[p.Key], p)
+		touched[p.Key] = true
+		msg, _ := json.Marshal(p)
+		for _, sub := range s.subs[p.Key] {
+			sub := sub
+			pushes = append(pushes, func() { sub.send(append(msg, '\n')) })
+		}
+	}
+	for key := range touched {
+		pts := s.data[key]
+		sort.SliceStable(pts, func(i, j int) bool { return pts[i].Timestamp < pts[j].Timestamp })
+	}
+	s.mu.Unlock()
+	for _, push := range pushes {
+		push()
+	}
+}
+
+func (s *fakeGTSDB) unsubscribeAll(c *fakeConn) {
+	s.unsubscribe(c, "")
+}
+
+// unsubscribe removes c's subscription to key, or to every key if key is
+// empty.
+func (s *fakeGTSDB) unsubscribe(c *fakeConn, key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for k, subs := range s.subs {
+		if key != "" && k != key {
+			continue
+		}
+		kept := subs[:0]
+		for _, sub := range subs {
+			if sub != c {
+				kept = append(kept, sub)
+			}
+		}
+		s.subs[k] = kept
+	}
+}
+
+// selectPoints applies the read parameters to one key's points.
+func (s *fakeGTSDB) selectPoints(req *fakeRequest, key string) []gtsdbDataPoint {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	pts := s.data[key]
+	if req.Read.LastX > 0 {
+		pts = pts[max(0, len(pts)-req.Read.LastX):]
+	} else {
+		var in []gtsdbDataPoint
+		for _, p := range pts {
+			if p.Timestamp >= req.Read.Start && p.Timestamp <= req.Read.End {
+				in = append(in, p)
+			}
+		}
+		pts = in
+		if req.Read.Downsampling > 0 {
+			pts = downsample(pts, req.Read.Start, req.Read.Downsampling)
+		}
+	}
+	return append([]gtsdbDataPoint{}, pts...)
+}
+
+// downsample averages time-ordered points into buckets of width seconds
+// starting at start, stamping each with its bucket's start.
+func downsample(pts []gtsdbDataPoint, start, width int64) []gtsdbDataPoint {
+	var out []gtsdbDataPoint
+	var n int
+	for _, p := range pts {
+		bucket := start + (p.Timestamp-start)/width*width
+		if len(out) == 0 || out[len(out)-1].Timestamp != bucket {
+			out = append(out, gtsdbDataPoint{Key: p.Key, Timestamp: bucket})
+			n = 0
+		}
+		last := &out[len(out)-1]
+		n++
+		last.Value += (p.Value - last.Value) / float64(n)
+	}
+	return out
+}
+
+func (s *fakeGTSDB) encodeRead(req *fakeRequest, keys []string, multi bool) []byte {
+	if req.ResponseFormat == "binary" {
+		sections := make([]gtsdbKeyData, len(keys))
+		for i, key := range keys {
+			sections[i] = gtsdbKeyData{Key: key, Points: s.selectPoints(req, key)}
+		}
+		body := appendBinaryFrame(nil, sections)
+		return append(binary.BigEndian.AppendUint32(nil, uint32(len(body))), body...)
+	}
+
+	var data any
+	if multi {
+		m := make(map[string][]gtsdbDataPoint, len(keys))
+		for _, key := range keys {
+			m[key] = s.selectPoints(req, key)
+		}
+		data = m
+	} else {
+		data = s.selectPoints(req, keys[0])
+	}
+	out, _ := json.Marshal(map[string]any{"success": true, "data": data})
+	return append(out, '\n')
+}
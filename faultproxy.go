@@ -0,0 +1,350 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"math/rand/v2"
+	"net"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FaultProfile describes the network conditions a faultProxy imposes. Delays
+// and bandwidth apply to each direction separately.
+type FaultProfile struct {
+	Name      string
+	Latency   time.Duration // one-way delay added to every chunk
+	Jitter    time.Duration // extra random delay in [0, Jitter)
+	Bandwidth int64         // bytes per second per direction; 0 = unlimited
+	Reset     float64       // probability per chunk of resetting the connection
+	Partial   float64       // probability per chunk of forwarding only part of it, then closing
+}
+
+// faultPresets are the profiles accepted by name in -fault.
+var faultPresets = map[string]FaultProfile{
+	"lan":    {Latency: 200 * time.Microsecond, Jitter: 100 * time.Microsecond},
+	"wan":    {Latency: 20 * time.Millisecond, Jitter: 5 * time.Millisecond, Bandwidth: 10 << 20},
+	"mobile": {Latency: 60 * time.Millisecond, Jitter: 30 * time.Millisecond, Bandwidth: 1 << 20, Reset: 0.001},
+	"lossy":  {Latency: 5 * time.Millisecond, Jitter: 5 * time.Millisecond, Reset: 0.01, Partial: 0.01},
+}
+
+// parseFaultProfile parses -fault: either a preset name or a comma-separated
+// list such as "latency=20ms,jitter=5ms,bandwidth=1MB,reset=0.01,partial=0.01".
+func parseFaultProfile(s string) (*FaultProfile, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil, nil
+	}
+	if p, ok := faultPresets[s]; ok {
+		p.Name = s
+		return &p, nil
+	}
+
+	p := &FaultProfile{Name: s}
+	for _, part := range strings.Split(s, ",") {
+		k, v, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid fault setting %q: expected key=value or one of %s", part, strings.Join(faultPresetNames(), ", "))
+		}
+		var err error
+		switch k {
+		case "latency":
+			p.Latency, err = time.ParseDuration(v)
+		case "jitter":
+			p.Jitter, err = time.ParseDuration(v)
+		case "bandwidth":
+			p.Bandwidth, err = parseByteRate(v)
+		case "reset":
+			p.Reset, err = parseProbability(v)
+		case "partial":
+			p.Partial, err = parseProbability(v)
+		default:
+			return nil, fmt.Errorf("unknown fault setting %q", k)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid fault %s %q: %w", k, v, err)
+		}
+	}
+	if p.Latency < 0 || p.Jitter < 0 {
+		return nil, fmt.Errorf("fault latency and jitter must not be negative")
+	}
+	return p, nil
+}
+
+func faultPresetNames() []string {
+	return slices.Sorted(maps.Keys(faultPresets))
+}
+
+// parseByteRate parses a per-second byte count such as "512KB" or "10MB".
+func parseByteRate(s string) (int64, error) {
+	s = strings.TrimSuffix(strings.ToUpper(strings.TrimSpace(s)), "/S")
+	mult := int64(1)
+	for _, u := range []struct {
+		suffix string
+		mult   int64
+	}{{"GB", 1 << 30}, {"MB", 1 << 20}, {"KB", 1 << 10}, {"B", 1}} {
+		if strings.HasSuffix(s, u.suffix) {
+			s, mult = strings.TrimSuffix(s, u.suffix), u.mult
+			break
+		}
+	}
+	n, err := strconv.ParseFloat(s, 64)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("expected a positive size like 1MB")
+	}
+	return int64(n * float64(mult)), nil
+}
+
+func parseProbability(s string) (float64, error) {
+	p, err := strconv.ParseFloat(s, 64)
+	if err != nil || p < 0 || p > 1 {
+		return 0, fmt.Errorf("expected a probability between 0 and 1")
+	}
+	return p, nil
+}
+
+// faultProxy forwards TCP connections from a loopback port to target,
+// applying a FaultProfile to the traffic in both directions.
+type faultProxy struct {
+	profile FaultProfile
+	target  string
+	ln      net.Listener
+
+	mu    sync.Mutex
+	conns map[net.Conn]struct{}
+	wg    sync.WaitGroup
+}
+
+func newFaultProxy(target string, profile FaultProfile) (*faultProxy, error) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	p := &faultProxy{profile: profile, target: target, ln: ln, conns: make(map[net.Conn]struct{})}
+	p.wg.Add(1)
+	go p.serve()
+	return p, nil
+}
+
+// Addr is the address drivers should dial instead of target.
+func (p *faultProxy) Addr() string { return p.ln.Addr().String() }
+
+func (p *faultProxy) Close() error {
+	err := p.ln.Close()
+	p.mu.Lock()
+	for c := range p.conns {
+		c.Close()
+	}
+	p.mu.Unlock()
+	p.wg.Wait()
+	return err
+}
+
+func (p *faultProxy) track(c net.Conn, add bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if add {
+		p.conns[c] = struct{}{}
+	} else {
+		delete(p.conns, c)
+	}
+}
+
+func (p *faultProxy) serve() {
+	defer p.wg.Done()
+	for {
+		client, err := p.ln.Accept()
+		if err != nil {
+			return
+		}
+		p.wg.Add(1)
+		go func() {
+			defer p.wg.Done()
+			p.handle(client)
+		}()
+	}
+}
+
+func (p *faultProxy) handle(client net.Conn) {
+	server, err := net.Dial("tcp", p.target)
+	if err != nil {
+		client.Close()
+		return
+	}
+	p.track(client, true)
+	p.track(server, true)
+	defer func() {
+		p.track(client, false)
+		p.track(server, false)
+	}()
+
+	// Either direction failing, or a fault firing, tears down both sides.
+	var once sync.Once
+	teardown := func(reset bool) {
+		once.Do(func() {
+			if reset {
+				for _, c := range []net.Conn{client, server} {
+					if tc, ok := c.(*net.TCPConn); ok {
+						tc.SetLinger(0)
+					}
+				}
+			}
+			client.Close()
+			server.Close()
+		})
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() { defer wg.Done(); p.pipe(server, client, teardown) }()
+	go func() { defer wg.Done(); p.pipe(client, server, teardown) }()
+	wg.Wait()
+}
+
+// delayedChunk is a chunk of bytes waiting for its delivery time.
+type delayedChunk struct {
+	data []byte
+	due  time.Time
+}
+
+// pipe copies src to dst. Reads are stamped with a delivery time and handed
+// to a writer goroutine, so latency delays data without stalling pipelined
+// requests behind each other. Chunks are never reordered.
+func (p *faultProxy) pipe(dst, src net.Conn, teardown func(reset bool)) {
+	chunks := make(chan delayedChunk, 1024)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		var sent time.Time // when the bandwidth cap next allows a write
+		for c := range chunks {
+			time.Sleep(time.Until(c.due))
+			data := c.data
+			switch {
+			case p.profile.Reset > 0 && rand.Float64() < p.profile.Reset:
+				teardown(true)
+				return
+			case p.profile.Partial > 0 && rand.Float64() < p.profile.Partial:
+				dst.Write(data[:rand.IntN(len(data))])
+				teardown(false)
+				return
+			}
+			if p.profile.Bandwidth > 0 {
+				now := time.Now()
+				if sent.Before(now) {
+					sent = now
+				}
+				sent = sent.Add(time.Duration(float64(len(data)) / float64(p.profile.Bandwidth) * float64(time.Second)))
+				time.Sleep(time.Until(sent))
+			}
+			if _, err := dst.Write(data); err != nil {
+				teardown(false)
+				return
+			}
+		}
+	}()
+
+	var last time.Time
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := src.Read(buf)
+		if n > 0 {
+			due := time.Now().Add(p.profile.Latency)
+			if p.profile.Jitter > 0 {
+				due = due.Add(rand.N(p.profile.Jitter))
+			}
+			if due.Before(last) {
+				due = last
+			}
+			last = due
+			select {
+			case chunks <- delayedChunk{data: append([]byte(nil), buf[:n]...), due: due}:
+			case <-done:
+				return
+			}
+		}
+		if err != nil {
+			close(chunks)
+			<-done
+			if err == io.EOF {
+				// Half-close so the peer sees EOF after the delayed data.
+				if tc, ok := dst.(*net.TCPConn); ok {
+					tc.CloseWrite()
+					return
+				}
+			}
+			teardown(false)
+			return
+		}
+	}
+}
+
+// endpoint is a driver address that can be routed through a faultProxy.
+type endpoint struct {
+	addr  *string // host:port, or a URL when isURL is set
+	isURL bool
+}
+
+func tcpEndpoint(addr *string) endpoint { return endpoint{addr: addr} }
+func urlEndpoint(addr *string) endpoint { return endpoint{addr: addr, isURL: true} }
+
+// startFaultProxies routes every endpoint of the selected drivers through
+// a proxy applying cfg.Fault, rewriting the addresses in cfg. The returned
+// function stops the proxies.
+func startFaultProxies(cfg *Config) (func(), error) {
+	var proxies []*faultProxy
+	stop := func() {
+		for _, p := range proxies {
+			p.Close()
+		}
+	}
+	rewritten := make(map[*string]bool)
+	for _, name := range cfg.Databases {
+		spec, ok := lookupDriver(name)
+		if !ok || spec.Endpoints == nil {
+			continue
+		}
+		for _, ep := range spec.Endpoints(cfg) {
+			if rewritten[ep.addr] {
+				continue
+			}
+			rewritten[ep.addr] = true
+
+			target := *ep.addr
+			var u *url.URL
+			if ep.isURL {
+				var err error
+				if u, err = url.Parse(target); err != nil || u.Host == "" {
+					stop()
+					return nil, fmt.Errorf("%s: cannot proxy URL %q", name, target)
+				}
+				target = u.Host
+				if u.Port() == "" {
+					if u.Scheme == "https" {
+						target += ":443"
+					} else {
+						target += ":80"
+					}
+				}
+			}
+			p, err := newFaultProxy(target, *cfg.Fault)
+			if err != nil {
+				stop()
+				return nil, fmt.Errorf("%s: fault proxy: %w", name, err)
+			}
+			proxies = append(proxies, p)
+			if u != nil {
+				u.Host = p.Addr()
+				*ep.addr = u.String()
+			} else {
+				*ep.addr = p.Addr()
+			}
+			fmt.Printf("Fault proxy %q: %s -> %s\n", cfg.Fault.Name, p.Addr(), target)
+		}
+	}
+	return stop, nil
+}
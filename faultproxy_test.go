@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseFaultProfile(t *testing.T) {
+	p, err := parseFaultProfile("wan")
+	if err != nil || p.Name != "wan" || p.Latency != faultPresets["wan"].Latency {
+		t.Errorf("preset: unexpected %+v, %v", p, err)
+	}
+
+	p, err = parseFaultProfile("latency=20ms,jitter=5ms,bandwidth=1.5MB,reset=0.01,partial=0.5")
+	if err != nil {
+		t.Fatalf("custom: %v", err)
+	}
+	want := FaultProfile{
+		Name:      "latency=20ms,jitter=5ms,bandwidth=1.5MB,reset=0.01,partial=0.5",
+		Latency:   20 * time.Millisecond,
+		Jitter:    5 * time.Millisecond,
+		Bandwidth: 3 << 19,
+		Reset:     0.01,
+		Partial:   0.5,
+	}
+	if *p != want {
+		t.Errorf("custom: expected %+v, got %+v", want, *p)
+	}
+
+	if p, err := parseFaultProfile(""); p != nil || err != nil {
+		t.Errorf("empty: expected no profile, got %+v, %v", p, err)
+	}
+	for _, bad := range []string{"satellite", "latency=fast", "reset=2", "bandwidth=0", "drop=0.1", "latency=-1ms"} {
+		if _, err := parseFaultProfile(bad); err == nil {
+			t.Errorf("%q: expected error", bad)
+		}
+	}
+}
+
+func TestFaultProxyAddsLatencyAndPreservesData(t *testing.T) {
+	const latency = 15 * time.Millisecond
+	srv := newFakeGTSDB(t, fakeGTSDBConfig{})
+	proxy, err := newFaultProxy(srv.Addr(), FaultProfile{Latency: latency, Jitter: time.Millisecond, Bandwidth: 1 << 20})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer proxy.Close()
+
+	d := newGTSDBDriver(proxy.Addr(), 2, 16)
+	ctx := context.Background()
+	if err := d.Connect(ctx); err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer d.Close()
+
+	points := make([]KeyedPoint, 2000)
+	for i := range points {
+		points[i] = KeyedPoint{Key: "k", Value: float64(i) / 4, Timestamp: int64(1700000000 + i)}
+	}
+	if err := d.WriteBatch(ctx, points); err != nil {
+		t.Fatalf("write batch: %v", err)
+	}
+
+	start := time.Now()
+	got, err := d.ReadPoints(ctx, "k", len(points))
+	if err != nil {
+		t.Fatalf("read points: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 2*latency {
+		t.Errorf("expected a round trip of at least %v, took %v", 2*latency, elapsed)
+	}
+	if len(got) != len(points) {
+		t.Fatalf("expected %d points, got %d", len(points), len(got))
+	}
+	for i := range points {
+		if got[i] != points[i] {
+			t.Fatalf("point %d: expected %+v, got %+v", i, points[i], got[i])
+		}
+	}
+}
+
+func TestFaultProxyInjectsResetsAndPartialWrites(t *testing.T) {
+	srv := newFakeGTSDB(t, fakeGTSDBConfig{})
+	for _, profile := range []FaultProfile{{Reset: 1}, {Partial: 1}} {
+		proxy, err := newFaultProxy(srv.Addr(), profile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		d := newGTSDBDriver(proxy.Addr(), 2, 16)
+		if err := d.Connect(context.Background()); err != nil {
+			t.Fatalf("connect: %v", err)
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		if err := d.Write(ctx, "k", 1); err == nil {
+			t.Errorf("%+v: expected write through a faulty link to fail", profile)
+		}
+		cancel()
+		d.Close()
+		proxy.Close()
+	}
+}
+
+func TestStartFaultProxiesRewritesEndpoints(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, r.URL.Path)
+	}))
+	defer backend.Close()
+	gtsdb := newFakeGTSDB(t, fakeGTSDBConfig{})
+
+	cfg := &Config{
+		Databases: []string{"gtsdb", "influx", "influx-http"},
+		GTSDBAddr: gtsdb.Addr(),
+		InfluxURL: backend.URL + "/prefix",
+		Fault:     &FaultProfile{Name: "test", Latency: time.Millisecond},
+	}
+	stop, err := startFaultProxies(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stop()
+
+	if cfg.GTSDBAddr == gtsdb.Addr() || cfg.InfluxURL == backend.URL+"/prefix" {
+		t.Fatalf("expected endpoints to be rewritten, got %s and %s", cfg.GTSDBAddr, cfg.InfluxURL)
+	}
+	if !strings.HasSuffix(cfg.InfluxURL, "/prefix") {
+		t.Errorf("expected URL path to be kept, got %s", cfg.InfluxURL)
+	}
+
+	resp, err := http.Get(cfg.InfluxURL)
+	if err != nil {
+		t.Fatalf("request through proxy: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected 200 through proxy, got %d", resp.StatusCode)
+	}
+
+	d := newGTSDBDriver(cfg.GTSDBAddr, 2, 16)
+	if err := d.Connect(context.Background()); err != nil {
+		t.Fatalf("connect through proxy: %v", err)
+	}
+	defer d.Close()
+	if err := d.Write(context.Background(), "k", 1); err != nil {
+		t.Errorf("write through proxy: %v", err)
+	}
+}
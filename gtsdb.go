@@ -4,12 +4,14 @@ import (
 	"bufio"
 	"context"
 	"encoding/binary"
+	"flag"
 	"fmt"
 	"io"
+	"math"
 	"math/rand/v2"
 	"net"
+	"os"
 	"strings"
-	"sync"
 	"time"
 
 	json "github.com/bytedance/sonic"
@@ -17,134 +19,231 @@ import (
 
 type gtsdbDriver struct {
 	tcpAddr string
-	conn    net.Conn
-	reader  *bufio.Reader
-	mu      sync.Mutex
+	pool    *gtsdbPool
 }
 
-func newGTSDBDriver(tcpAddr string) *gtsdbDriver {
+// newGTSDBDriver creates a driver that spreads requests over conns pipelined
+// connections, each with up to pipeline requests in flight.
+func newGTSDBDriver(tcpAddr string, conns, pipeline int) *gtsdbDriver {
 	return &gtsdbDriver{
 		tcpAddr: tcpAddr,
+		pool:    newGTSDBPool(tcpAddr, conns, pipeline),
 	}
 }
 
+// gtsdbMaxBatch is the largest number of points GTSDB accepts in one batch-write.
+const gtsdbMaxBatch = 10000
+
+func init() {
+	registerDriver(&driverSpec{
+		Name: "gtsdb",
+		Flags: func(fs *flag.FlagSet, cfg *Config) {
+			fs.StringVar(&cfg.GTSDBAddr, "gtsdb-addr", "localhost:5555", "GTSDB TCP address")
+			fs.IntVar(&cfg.GTSDBConns, "gtsdb-conns", 8, "GTSDB TCP connections in the driver's pool")
+			fs.IntVar(&cfg.GTSDBPipeline, "gtsdb-pipeline", 128, "Maximum in-flight requests per GTSDB connection")
+		},
+		Validate: func(cfg *Config) error {
+			if cfg.GTSDBConns <= 0 || cfg.GTSDBPipeline <= 0 {
+				return fmt.Errorf("gtsdb-conns and gtsdb-pipeline must be positive")
+			}
+			return nil
+		},
+		New: func(cfg *Config) Driver {
+			return newGTSDBDriver(cfg.GTSDBAddr, cfg.GTSDBConns, cfg.GTSDBPipeline)
+		},
+		Endpoints: func(cfg *Config) []endpoint { return []endpoint{tcpEndpoint(&cfg.GTSDBAddr)} },
+		MaxBatch:  gtsdbMaxBatch,
+		// Downsampling only averages.
+		Aggregations: []string{"avg"},
+		Runners: map[string]benchRunner{
+			"Multi-Key Read": func(cfg *Config, d Driver, _ driverCaps) *BenchmarkResult {
+				g := d.(*gtsdbDriver)
+				fmt.Println("Pre-loading GTSDB...")
+				if err := g.CreateKeys(context.Background(), sensorKeys(cfg.Sensors)); err != nil {
+					fmt.Fprintf(os.Stderr, "%s initkey: %v\n", g.Name(), err)
+				}
+				time.Sleep(100 * time.Millisecond)
+				if err := g.preloadTCP(cfg.Sensors, multiKeyPoints); err != nil {
+					fmt.Fprintf(os.Stderr, "%s preload: %v\n", g.Name(), err)
+				}
+				fmt.Println("Pre-load done.")
+				return runMultiRead(g, cfg.Sensors, multiKeyPoints, cfg.Runs, cfg.Workers)
+			},
+		},
+	})
+}
+
 func (d *gtsdbDriver) Name() string { return "GTSDB" }
 
 func (d *gtsdbDriver) Connect(ctx context.Context) error {
-	var dialer net.Dialer
-	conn, err := dialer.DialContext(ctx, "tcp", d.tcpAddr)
-	if err != nil {
-		return fmt.Errorf("gtsdb connect: %w", err)
-	}
-	d.conn = conn
-	d.reader = bufio.NewReader(conn)
-	return nil
+	return d.pool.connect(ctx)
 }
 
 func (d *gtsdbDriver) Close() error {
-	if d.conn != nil {
-		return d.conn.Close()
-	}
+	d.pool.close()
 	return nil
 }
 
-func (d *gtsdbDriver) Write(ctx context.Context, key string, value float64) error {
-	d.mu.Lock()
-	defer d.mu.Unlock()
-	return d.writeLocked(key, value)
+// gtsdbAck is GTSDB's reply to write-style operations.
+type gtsdbAck struct {
+	Success bool   `json:"success"`
+	Message string `json:"message"`
 }
 
-func (d *gtsdbDriver) writeLocked(key string, value float64) error {
-	payload := fmt.Sprintf(`{"operation":"write","key":"%s","write":{"value":%f}}`, key, value)
-	if _, err := d.conn.Write(append([]byte(payload), '\n')); err != nil {
-		return err
+// checkAck reports a rejected or unparseable acknowledgement of op.
+func checkAck(op string, resp []byte) error {
+	var ack gtsdbAck
+	if err := json.Unmarshal(resp, &ack); err != nil {
+		return fmt.Errorf("%s: parse error: %w", op, err)
 	}
-	_, err := d.reader.ReadBytes('\n')
-	return err
+	if !ack.Success {
+		return fmt.Errorf("%s failed: %s", op, ack.Message)
+	}
+	return nil
 }
 
-func (d *gtsdbDriver) writePipelined(ctx context.Context, key string, values []float64) (int, error) {
-	d.mu.Lock()
-	defer d.mu.Unlock()
-
-	for _, v := range values {
-		payload := fmt.Sprintf(`{"operation":"write","key":"%s","write":{"value":%f}}`, key, v)
-		if _, err := d.conn.Write(append([]byte(payload), '\n')); err != nil {
-			return 0, err
-		}
+// request sends a write-style operation and checks its acknowledgement.
+func (d *gtsdbDriver) request(ctx context.Context, op, payload string) error {
+	resp, err := d.pool.do(ctx, append([]byte(payload), '\n'), gtsdbLine)
+	if err != nil {
+		return err
 	}
+	return checkAck(op, resp)
+}
 
-	success := 0
-	for i := 0; i < len(values); i++ {
-		if _, err := d.reader.ReadBytes('\n'); err != nil {
-			return success, err
-		}
-		success++
+// read sends a read-style operation that asks for a binary response and
+// decodes it.
+func (d *gtsdbDriver) read(ctx context.Context, payload string) ([]gtsdbKeyData, error) {
+	frame, err := d.pool.do(ctx, append([]byte(payload), '\n'), gtsdbFrame)
+	if err != nil {
+		return nil, err
 	}
-	return success, nil
+	return decodeBinaryFrame(frame)
 }
 
-func (d *gtsdbDriver) WriteBatch(ctx context.Context, points []KeyedPoint) error {
-	return d.writeBatchTCP(ctx, points)
+func (d *gtsdbDriver) Write(ctx context.Context, key string, value float64) error {
+	payload := fmt.Sprintf(`{"operation":"write","key":"%s","write":{"value":%f}}`, key, value)
+	return d.request(ctx, "write", payload)
 }
 
-// writeBatchTCP sends a batch-write via a fresh TCP connection (avoids shared-state issues).
-func (d *gtsdbDriver) writeBatchTCP(ctx context.Context, points []KeyedPoint) error {
-	return gtsdbBatchWriteFresh(d.tcpAddr, points)
+// WriteBatch sends points as pipelined batch-writes of up to gtsdbMaxBatch
+// points. If some chunks are rejected it returns a *partialWriteError
+// counting their points.
+func (d *gtsdbDriver) WriteBatch(ctx context.Context, points []KeyedPoint) error {
+	return d.sendChunked(ctx, "batch-write", points, encodeBatchWrite)
 }
 
-// gtsdbBatchWriteFresh opens a new TCP connection, sends batch-write(s), and closes it.
-// Sends all points in chunks of up to 10000 (GTSDB's batch limit).
-func gtsdbBatchWriteFresh(tcpAddr string, points []KeyedPoint) error {
-	const pointsPerChunk = 10000
+// BulkLoad ingests points for key as pipelined data-patch requests, GTSDB's
+// CSV import, of up to gtsdbMaxBatch points.
+func (d *gtsdbDriver) BulkLoad(ctx context.Context, key string, points []KeyedPoint) error {
+	return d.sendChunked(ctx, "data-patch", points, func(batch []KeyedPoint) []byte {
+		return encodeDataPatch(key, batch)
+	})
+}
 
-	conn, err := net.Dial("tcp", tcpAddr)
-	if err != nil {
-		return err
+// sendChunked pipelines op requests of up to gtsdbMaxBatch points, built by
+// encode, and returns a *partialWriteError when only some are rejected.
+func (d *gtsdbDriver) sendChunked(ctx context.Context, op string, points []KeyedPoint, encode func([]KeyedPoint) []byte) error {
+	type chunk struct {
+		call *gtsdbCall
+		n    int
 	}
-	defer conn.Close()
-	reader := bufio.NewReader(conn)
-
-	for i := 0; i < len(points); i += pointsPerChunk {
-		end := i + pointsPerChunk
-		if end > len(points) {
-			end = len(points)
-		}
-		chunk := points[i:end]
-
-		var sb strings.Builder
-		sb.WriteString(`{"operation":"batch-write","points":[`)
-		for j, p := range chunk {
-			if j > 0 {
-				sb.WriteByte(',')
-			}
-			sb.WriteString(fmt.Sprintf(`{"key":"%s","value":%f,"timestamp":%d}`, p.Key, p.Value, p.Timestamp))
+	var chunks []chunk
+	var failed int
+	var firstErr error
+	fail := func(n int, err error) {
+		failed += n
+		if firstErr == nil {
+			firstErr = err
 		}
-		sb.WriteString(`]}`)
-		payload := sb.String()
+	}
 
-		_, err = conn.Write(append([]byte(payload), '\n'))
+	for i := 0; i < len(points); i += gtsdbMaxBatch {
+		batch := points[i:min(i+gtsdbMaxBatch, len(points))]
+		call, err := d.pool.send(ctx, encode(batch), gtsdbLine)
 		if err != nil {
-			return err
+			fail(len(batch), err)
+			continue
+		}
+		chunks = append(chunks, chunk{call, len(batch)})
+	}
+	for _, c := range chunks {
+		resp, err := c.call.wait(ctx)
+		if err == nil {
+			err = checkAck(op, resp)
 		}
-
-		resp, err := reader.ReadBytes('\n')
 		if err != nil {
-			return err
+			fail(c.n, err)
 		}
+	}
 
-		var result struct {
-			Success bool   `json:"success"`
-			Message string `json:"message"`
-		}
-		if err := json.Unmarshal(resp, &result); err != nil {
-			return fmt.Errorf("batch-write: parse error: %w", err)
+	switch {
+	case failed == 0:
+		return nil
+	case failed == len(points):
+		return firstErr
+	}
+	return &partialWriteError{Failed: failed, Total: len(points), Err: firstErr}
+}
+
+// encodeBatchWrite builds one newline-terminated batch-write request.
+func encodeBatchWrite(points []KeyedPoint) []byte {
+	var sb strings.Builder
+	sb.WriteString(`{"operation":"batch-write","points":[`)
+	for j, p := range points {
+		if j > 0 {
+			sb.WriteByte(',')
 		}
-		if !result.Success {
-			return fmt.Errorf("batch-write failed: %s", result.Message)
+		sb.WriteString(fmt.Sprintf(`{"key":"%s","value":%f,"timestamp":%d}`, p.Key, p.Value, p.Timestamp))
+	}
+	sb.WriteString("]}\n")
+	return []byte(sb.String())
+}
+
+// encodeDataPatch builds one newline-terminated data-patch request carrying
+// points as "timestamp,value" CSV lines.
+func encodeDataPatch(key string, points []KeyedPoint) []byte {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, `{"operation":"data-patch","key":"%s","data":"`, key)
+	for j, p := range points {
+		if j > 0 {
+			sb.WriteString(`\n`)
 		}
+		fmt.Fprintf(&sb, "%d,%f", p.Timestamp, p.Value)
 	}
-	return nil
+	sb.WriteString("\"}\n")
+	return []byte(sb.String())
+}
+
+// ListKeys lists every key and returns how many there are.
+func (d *gtsdbDriver) ListKeys(ctx context.Context) (int, error) {
+	resp, err := d.pool.do(ctx, []byte(`{"operation":"keys"}`+"\n"), gtsdbLine)
+	if err != nil {
+		return 0, err
+	}
+	var keys struct {
+		gtsdbAck
+		Data []string `json:"data"`
+	}
+	if err := json.Unmarshal(resp, &keys); err != nil {
+		return 0, fmt.Errorf("keys: parse error: %w", err)
+	}
+	if !keys.Success {
+		return 0, fmt.Errorf("keys failed: %s", keys.Message)
+	}
+	return len(keys.Data), nil
+}
+
+func (d *gtsdbDriver) RenameKey(ctx context.Context, from, to string) error {
+	return d.request(ctx, "renamekey", fmt.Sprintf(`{"operation":"renamekey","key":"%s","toKey":"%s"}`, from, to))
+}
+
+func (d *gtsdbDriver) DeleteKey(ctx context.Context, key string) error {
+	return d.request(ctx, "delete", fmt.Sprintf(`{"operation":"delete","key":"%s"}`, key))
+}
+
+func (d *gtsdbDriver) Flush(ctx context.Context) error {
+	return d.request(ctx, "flush", `{"operation":"flush"}`)
 }
 
 // gtsdbReadResponse matches the JSON structure returned by GTSDB's read operation.
@@ -160,14 +259,65 @@ type gtsdbDataPoint struct {
 }
 
 func (d *gtsdbDriver) Read(ctx context.Context, key string, lastX int) (int, error) {
-	d.mu.Lock()
-	defer d.mu.Unlock()
+	payload := fmt.Sprintf(`{"operation":"read","key":"%s","read":{"lastx":%d},"response_format":"binary"}`, key, lastX)
+	keys, err := d.read(ctx, payload)
+	return countPoints(keys), err
+}
+
+func (d *gtsdbDriver) ReadRange(ctx context.Context, key string, start, end int64) (int, error) {
+	payload := fmt.Sprintf(`{"operation":"read","key":"%s","read":{"start_timestamp":%d,"end_timestamp":%d},"response_format":"binary"}`, key, start, end)
+	keys, err := d.read(ctx, payload)
+	return countPoints(keys), err
+}
+
+// ReadAggregate uses GTSDB's downsampling, which averages each bucket.
+func (d *gtsdbDriver) ReadAggregate(ctx context.Context, key string, start, end, bucket int64, agg string) (int, error) {
+	if agg != "avg" {
+		return 0, fmt.Errorf("gtsdb downsampling only averages, not %s", agg)
+	}
+	payload := fmt.Sprintf(`{"operation":"read","key":"%s","read":{"start_timestamp":%d,"end_timestamp":%d,"downsampling":%d},"response_format":"binary"}`, key, start, end, bucket)
+	keys, err := d.read(ctx, payload)
+	return countPoints(keys), err
+}
 
+// ReadPoints reads the last lastX points of key through the binary response format.
+func (d *gtsdbDriver) ReadPoints(ctx context.Context, key string, lastX int) ([]KeyedPoint, error) {
 	payload := fmt.Sprintf(`{"operation":"read","key":"%s","read":{"lastx":%d},"response_format":"binary"}`, key, lastX)
-	if _, err := d.conn.Write(append([]byte(payload), '\n')); err != nil {
-		return 0, err
+	keys, err := d.read(ctx, payload)
+	if err != nil {
+		return nil, err
+	}
+	var points []KeyedPoint
+	for _, k := range keys {
+		points = append(points, keyedPoints(k.Points)...)
+	}
+	return points, nil
+}
+
+// MultiReadPoints is MultiRead returning the decoded points of every key.
+func (d *gtsdbDriver) MultiReadPoints(ctx context.Context, keys []string, lastX int) (map[string][]KeyedPoint, error) {
+	data, err := d.read(ctx, multiReadPayload(keys, lastX))
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string][]KeyedPoint, len(data))
+	for _, k := range data {
+		result[k.Key] = keyedPoints(k.Points)
 	}
-	return readBinaryCount(d.reader)
+	return result, nil
+}
+
+func multiReadPayload(keys []string, lastX int) string {
+	keysJSON, _ := json.Marshal(keys)
+	return fmt.Sprintf(`{"operation":"multi-read","keys":%s,"read":{"lastx":%d},"response_format":"binary"}`, string(keysJSON), lastX)
+}
+
+func keyedPoints(points []gtsdbDataPoint) []KeyedPoint {
+	out := make([]KeyedPoint, len(points))
+	for i, p := range points {
+		out[i] = KeyedPoint{Key: p.Key, Value: p.Value, Timestamp: p.Timestamp}
+	}
+	return out
 }
 
 // readBinaryFrame reads a length-prefixed binary frame from the reader.
@@ -184,78 +334,88 @@ func readBinaryFrame(reader *bufio.Reader) ([]byte, error) {
 	// Read exact frame data
 	data := make([]byte, frameLen)
 	if _, err := io.ReadFull(reader, data); err != nil {
-		return nil, err
+		return nil, fmt.Errorf("binary frame: read %d-byte body: %w", frameLen, err)
 	}
 	return data, nil
 }
 
-// readBinaryCount reads a binary response and returns the total number of data points.
-func readBinaryCount(reader *bufio.Reader) (int, error) {
-	data, err := readBinaryFrame(reader)
-	if err != nil {
-		return 0, err
-	}
-	return parseBinaryCount(data), nil
+// gtsdbKeyData is one key's section of a binary read response.
+type gtsdbKeyData struct {
+	Key    string
+	Points []gtsdbDataPoint
 }
 
-func parseBinaryCount(data []byte) int {
+// gtsdbPointSize is the encoded size of one point: int64 timestamp then
+// float64 value, both big-endian.
+const gtsdbPointSize = 16
+
+// decodeBinaryFrame decodes the body of a binary read response:
+//
+//	uint32 numKeys
+//	numKeys × { uint16 keyLen, key, uint32 count, count × (int64 ts, float64 value) }
+//
+// It fails on truncated sections and on trailing bytes. An empty frame
+// decodes to no keys.
+func decodeBinaryFrame(data []byte) ([]gtsdbKeyData, error) {
+	if len(data) == 0 {
+		return nil, nil
+	}
 	if len(data) < 4 {
-		return 0
+		return nil, fmt.Errorf("binary frame: %d bytes, too short for key count", len(data))
 	}
-	totalPoints := 0
-	offset := 0
-	numKeys := binary.BigEndian.Uint32(data[offset:])
-	offset += 4
-	for i := uint32(0); i < numKeys; i++ {
-		if offset+2 > len(data) {
-			break
-		}
-		keyLen := binary.BigEndian.Uint16(data[offset:])
-		offset += 2 + int(keyLen)
-		if offset+4 > len(data) {
-			break
-		}
-		pointCount := binary.BigEndian.Uint32(data[offset:])
-		offset += 4
-		totalPoints += int(pointCount)
-		offset += int(pointCount) * 16
+	numKeys := binary.BigEndian.Uint32(data)
+	offset := 4
+	// Every key section needs at least 6 bytes; reject counts the frame cannot hold
+	// before allocating for them.
+	if uint64(numKeys)*6 > uint64(len(data)-offset) {
+		return nil, fmt.Errorf("binary frame: %d keys do not fit in %d bytes", numKeys, len(data))
 	}
-	return totalPoints
-}
 
-// readBinaryMultiCount reads binary multi-data response and returns counts per key.
-func readBinaryMultiCount(reader *bufio.Reader) (map[string]int, error) {
-	data, err := readBinaryFrame(reader)
-	if err != nil {
-		return nil, err
-	}
-	if len(data) < 4 {
-		return nil, nil
-	}
-	result := make(map[string]int)
-	offset := 0
-	numKeys := binary.BigEndian.Uint32(data[offset:])
-	offset += 4
+	keys := make([]gtsdbKeyData, 0, numKeys)
 	for i := uint32(0); i < numKeys; i++ {
 		if offset+2 > len(data) {
-			break
+			return nil, fmt.Errorf("binary frame: key %d: truncated key length at offset %d", i, offset)
 		}
-		keyLen := binary.BigEndian.Uint16(data[offset:])
+		keyLen := int(binary.BigEndian.Uint16(data[offset:]))
 		offset += 2
-		if offset+int(keyLen) > len(data) {
-			break
+		if offset+keyLen > len(data) {
+			return nil, fmt.Errorf("binary frame: key %d: truncated key at offset %d", i, offset)
 		}
-		key := string(data[offset : offset+int(keyLen)])
-		offset += int(keyLen)
+		key := string(data[offset : offset+keyLen])
+		offset += keyLen
 		if offset+4 > len(data) {
-			break
+			return nil, fmt.Errorf("binary frame: key %q: truncated point count at offset %d", key, offset)
 		}
-		pointCount := binary.BigEndian.Uint32(data[offset:])
+		count := binary.BigEndian.Uint32(data[offset:])
 		offset += 4
-		result[key] = int(pointCount)
-		offset += int(pointCount) * 16
+		if uint64(count)*gtsdbPointSize > uint64(len(data)-offset) {
+			return nil, fmt.Errorf("binary frame: key %q: %d points overrun frame at offset %d", key, count, offset)
+		}
+
+		points := make([]gtsdbDataPoint, count)
+		for j := range points {
+			points[j] = gtsdbDataPoint{
+				Key:       key,
+				Timestamp: int64(binary.BigEndian.Uint64(data[offset:])),
+				Value:     math.Float64frombits(binary.BigEndian.Uint64(data[offset+8:])),
+			}
+			offset += gtsdbPointSize
+		}
+		keys = append(keys, gtsdbKeyData{Key: key, Points: points})
 	}
-	return result, nil
+	if offset != len(data) {
+		return nil, fmt.Errorf("binary frame: %d trailing bytes", len(data)-offset)
+	}
+	return keys, nil
+}
+
+// countPoints returns the total number of points across keys.
+func countPoints(keys []gtsdbKeyData) int {
+	total := 0
+	for _, k := range keys {
+		total += len(k.Points)
+	}
+	return total
 }
 
 // gtsdbReadFresh opens a new TCP connection, performs a read, and closes it.
@@ -285,15 +445,15 @@ func gtsdbReadFresh(tcpAddr, key string, lastX int) (int, error) {
 
 // MultiRead uses GTSDB's multi-read API to read from multiple keys in one TCP round-trip.
 func (d *gtsdbDriver) MultiRead(ctx context.Context, keys []string, lastX int) (map[string]int, error) {
-	d.mu.Lock()
-	defer d.mu.Unlock()
-
-	keysJSON, _ := json.Marshal(keys)
-	payload := fmt.Sprintf(`{"operation":"multi-read","keys":%s,"read":{"lastx":%d},"response_format":"binary"}`, string(keysJSON), lastX)
-	if _, err := d.conn.Write(append([]byte(payload), '\n')); err != nil {
+	data, err := d.read(ctx, multiReadPayload(keys, lastX))
+	if err != nil || data == nil {
 		return nil, err
 	}
-	return readBinaryMultiCount(d.reader)
+	result := make(map[string]int, len(data))
+	for _, k := range data {
+		result[k.Key] = len(k.Points)
+	}
+	return result, nil
 }
 
 func (d *gtsdbDriver) PubSub(ctx context.Context, key string, count int) (time.Duration, error) {
@@ -341,19 +501,34 @@ func (d *gtsdbDriver) PubSub(ctx context.Context, key string, count int) (time.D
 
 	select {
 	case <-received:
-		return time.Since(start), nil
+		elapsed := time.Since(start)
+		unsubPayload := fmt.Sprintf(`{"operation":"unsubscribe","key":"%s"}`, key)
+		subConn.Write(append([]byte(unsubPayload), '\n'))
+		return elapsed, nil
 	case <-ctx.Done():
 		return 0, ctx.Err()
 	}
 }
 
-func (d *gtsdbDriver) initKeys(numSensors int) error {
-	d.mu.Lock()
-	defer d.mu.Unlock()
-	for i := 0; i < numSensors; i++ {
-		cmd := fmt.Sprintf(`{"operation":"initkey","key":"bench_sensor_%d"}`, i)
-		d.conn.Write(append([]byte(cmd), '\n'))
-		d.reader.ReadBytes('\n')
+// CreateKeys creates keys with pipelined initkey requests.
+func (d *gtsdbDriver) CreateKeys(ctx context.Context, keys []string) error {
+	calls := make([]*gtsdbCall, 0, len(keys))
+	for _, key := range keys {
+		cmd := fmt.Sprintf(`{"operation":"initkey","key":"%s"}`, key)
+		call, err := d.pool.send(ctx, append([]byte(cmd), '\n'), gtsdbLine)
+		if err != nil {
+			return err
+		}
+		calls = append(calls, call)
+	}
+	for _, call := range calls {
+		resp, err := call.wait(ctx)
+		if err == nil {
+			err = checkAck("initkey", resp)
+		}
+		if err != nil {
+			return err
+		}
 	}
 	return nil
 }
@@ -368,7 +543,7 @@ func (d *gtsdbDriver) preloadTCP(numSensors, pointsPerSensor int) error {
 				Timestamp: 1700000000 + int64(j),
 			})
 		}
-		if err := gtsdbBatchWriteFresh(d.tcpAddr, points); err != nil {
+		if err := d.WriteBatch(context.Background(), points); err != nil {
 			return err
 		}
 	}
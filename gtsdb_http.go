@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// gtsdbHTTPDriver speaks GTSDB's JSON-over-HTTP front-end: one operation
+// per POST to /, over keep-alive connections.
+type gtsdbHTTPDriver struct {
+	url    string
+	client *http.Client
+}
+
+// newGTSDBHTTPDriver accepts a URL or a bare host:port.
+func newGTSDBHTTPDriver(addr string) *gtsdbHTTPDriver {
+	if !strings.Contains(addr, "://") {
+		addr = "http://" + addr
+	}
+	return &gtsdbHTTPDriver{
+		url: strings.TrimRight(addr, "/") + "/",
+		client: &http.Client{
+			Timeout: 30 * time.Second,
+			Transport: &http.Transport{
+				MaxIdleConnsPerHost: 100,
+				MaxConnsPerHost:     100,
+			},
+		},
+	}
+}
+
+func init() {
+	registerDriver(&driverSpec{
+		Name: "gtsdb-http",
+		Flags: func(fs *flag.FlagSet, cfg *Config) {
+			fs.StringVar(&cfg.GTSDBHTTP, "gtsdb-http", "localhost:5556", "GTSDB HTTP address or URL")
+		},
+		New: func(cfg *Config) Driver { return newGTSDBHTTPDriver(cfg.GTSDBHTTP) },
+		Endpoints: func(cfg *Config) []endpoint {
+			if strings.Contains(cfg.GTSDBHTTP, "://") {
+				return []endpoint{urlEndpoint(&cfg.GTSDBHTTP)}
+			}
+			return []endpoint{tcpEndpoint(&cfg.GTSDBHTTP)}
+		},
+		MaxBatch: gtsdbMaxBatch,
+		Server:   "gtsdb",
+	})
+}
+
+func (d *gtsdbHTTPDriver) Name() string { return "GTSDB (HTTP)" }
+
+// Connect checks the front-end answers a read.
+func (d *gtsdbHTTPDriver) Connect(ctx context.Context) error {
+	if _, err := d.post(ctx, `{"operation":"read","key":"bench_ping","Read":{"lastx":1}}`); err != nil {
+		return fmt.Errorf("gtsdb http not ready: %w", err)
+	}
+	return nil
+}
+
+func (d *gtsdbHTTPDriver) Close() error {
+	d.client.CloseIdleConnections()
+	return nil
+}
+
+// post sends one operation and returns the response body.
+func (d *gtsdbHTTPDriver) post(ctx context.Context, payload string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, "POST", d.url, strings.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("gtsdb http returned %d: %s", resp.StatusCode, strings.TrimSpace(string(body[:min(len(body), 512)])))
+	}
+	return body, nil
+}
+
+// request sends a write-style operation and checks its acknowledgement.
+func (d *gtsdbHTTPDriver) request(ctx context.Context, op, payload string) error {
+	resp, err := d.post(ctx, payload)
+	if err != nil {
+		return err
+	}
+	return checkAck(op, resp)
+}
+
+func (d *gtsdbHTTPDriver) Write(ctx context.Context, key string, value float64) error {
+	return d.request(ctx, "write", fmt.Sprintf(`{"operation":"write","key":"%s","Write":{"Value":%f}}`, key, value))
+}
+
+// WriteBatch posts batch-writes of up to gtsdbMaxBatch points. If some are
+// rejected it returns a *partialWriteError counting their points.
+func (d *gtsdbHTTPDriver) WriteBatch(ctx context.Context, points []KeyedPoint) error {
+	var failed int
+	var firstErr error
+	for i := 0; i < len(points); i += gtsdbMaxBatch {
+		batch := points[i:min(i+gtsdbMaxBatch, len(points))]
+		if err := d.request(ctx, "batch-write", string(encodeBatchWrite(batch))); err != nil {
+			failed += len(batch)
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	switch {
+	case failed == 0:
+		return nil
+	case failed == len(points):
+		return firstErr
+	}
+	return &partialWriteError{Failed: failed, Total: len(points), Err: firstErr}
+}
+
+func (d *gtsdbHTTPDriver) Read(ctx context.Context, key string, lastX int) (int, error) {
+	resp, err := d.post(ctx, fmt.Sprintf(`{"operation":"read","key":"%s","Read":{"lastx":%d}}`, key, lastX))
+	if err != nil {
+		return 0, err
+	}
+	var result gtsdbReadResponse
+	if err := json.Unmarshal(resp, &result); err != nil {
+		return 0, fmt.Errorf("read: parse error: %w", err)
+	}
+	if !result.Success {
+		return 0, checkAck("read", resp)
+	}
+	return len(result.Data), nil
+}
+
+func (d *gtsdbHTTPDriver) MultiRead(ctx context.Context, keys []string, lastX int) (map[string]int, error) {
+	keysJSON, _ := json.Marshal(keys)
+	resp, err := d.post(ctx, fmt.Sprintf(`{"operation":"multi-read","keys":%s,"Read":{"lastx":%d}}`, keysJSON, lastX))
+	if err != nil {
+		return nil, err
+	}
+	var result struct {
+		Success bool                        `json:"success"`
+		Data    map[string][]gtsdbDataPoint `json:"data"`
+	}
+	if err := json.Unmarshal(resp, &result); err != nil {
+		return nil, fmt.Errorf("multi-read: parse error: %w", err)
+	}
+	if !result.Success {
+		return nil, checkAck("multi-read", resp)
+	}
+	counts := make(map[string]int, len(result.Data))
+	for key, points := range result.Data {
+		counts[key] = len(points)
+	}
+	return counts, nil
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestGTSDBHTTPDriverAgainstFakeServer(t *testing.T) {
+	srv := newFakeGTSDB(t, fakeGTSDBConfig{})
+	front := httptest.NewServer(srv)
+	defer front.Close()
+
+	// A bare host:port is accepted like the default -gtsdb-http.
+	d := newGTSDBHTTPDriver(strings.TrimPrefix(front.URL, "http://"))
+	ctx := context.Background()
+	if err := d.Connect(ctx); err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer d.Close()
+
+	if err := d.Write(ctx, "a", 1.5); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if got := srv.points("a"); len(got) != 1 || got[0].Value != 1.5 {
+		t.Errorf("expected the written value, got %+v", got)
+	}
+
+	points := make([]KeyedPoint, gtsdbMaxBatch+500)
+	for i := range points {
+		points[i] = KeyedPoint{Key: "b", Value: float64(i), Timestamp: int64(1700000000 + i)}
+	}
+	if err := d.WriteBatch(ctx, points); err != nil {
+		t.Fatalf("write batch: %v", err)
+	}
+	if got := srv.count("batch-write"); got != 2 {
+		t.Errorf("expected 2 batch-write requests, got %d", got)
+	}
+
+	if n, err := d.Read(ctx, "b", 100); err != nil || n != 100 {
+		t.Errorf("read: expected 100 points, got %d, %v", n, err)
+	}
+	counts, err := d.MultiRead(ctx, []string{"a", "b"}, 10)
+	if err != nil || counts["a"] != 1 || counts["b"] != 10 {
+		t.Errorf("multi-read: unexpected %v, %v", counts, err)
+	}
+}
+
+func TestGTSDBHTTPDriverReportsRejectedBatches(t *testing.T) {
+	srv := newFakeGTSDB(t, fakeGTSDBConfig{MaxBatch: 5000})
+	front := httptest.NewServer(srv)
+	defer front.Close()
+	d := newGTSDBHTTPDriver(front.URL)
+
+	points := make([]KeyedPoint, gtsdbMaxBatch+500)
+	for i := range points {
+		points[i] = KeyedPoint{Key: "b", Value: float64(i), Timestamp: int64(1700000000 + i)}
+	}
+	err := d.WriteBatch(context.Background(), points)
+	if got := writeFailures(err, len(points)); got != gtsdbMaxBatch {
+		t.Errorf("expected the oversized chunk's %d points to fail, got %d (%v)", gtsdbMaxBatch, got, err)
+	}
+}
+
+func TestGTSDBHTTPDriverReportsFailedReads(t *testing.T) {
+	front := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"success":false,"message":"key not found"}`)
+	}))
+	defer front.Close()
+	d := newGTSDBHTTPDriver(front.URL)
+	ctx := context.Background()
+
+	if _, err := d.Read(ctx, "a", 10); err == nil || !strings.Contains(err.Error(), "key not found") {
+		t.Errorf("read: expected the server's failure, got %v", err)
+	}
+	if _, err := d.MultiRead(ctx, []string{"a"}, 10); err == nil || !strings.Contains(err.Error(), "key not found") {
+		t.Errorf("multi-read: expected the server's failure, got %v", err)
+	}
+}
@@ -0,0 +1,378 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"math"
+	"testing"
+	"time"
+)
+
+// appendBinaryFrame encodes keys as the body of a GTSDB binary read response.
+func appendBinaryFrame(b []byte, keys []gtsdbKeyData) []byte {
+	b = binary.BigEndian.AppendUint32(b, uint32(len(keys)))
+	for _, k := range keys {
+		b = binary.BigEndian.AppendUint16(b, uint16(len(k.Key)))
+		b = append(b, k.Key...)
+		b = binary.BigEndian.AppendUint32(b, uint32(len(k.Points)))
+		for _, p := range k.Points {
+			b = binary.BigEndian.AppendUint64(b, uint64(p.Timestamp))
+			b = binary.BigEndian.AppendUint64(b, math.Float64bits(p.Value))
+		}
+	}
+	return b
+}
+
+func TestDecodeBinaryFrameRoundTrip(t *testing.T) {
+	want := []gtsdbKeyData{
+		{Key: "a", Points: []gtsdbDataPoint{{Key: "a", Timestamp: 1700000000, Value: 1.5}, {Key: "a", Timestamp: 1700000001, Value: -2}}},
+		{Key: "empty"},
+		{Key: "b", Points: []gtsdbDataPoint{{Key: "b", Timestamp: 1, Value: math.MaxFloat64}}},
+	}
+	got, err := decodeBinaryFrame(appendBinaryFrame(nil, want))
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("expected %d keys, got %d", len(want), len(got))
+	}
+	for i := range want {
+		if got[i].Key != want[i].Key || len(got[i].Points) != len(want[i].Points) {
+			t.Fatalf("key %d: expected %+v, got %+v", i, want[i], got[i])
+		}
+		for j := range want[i].Points {
+			if got[i].Points[j] != want[i].Points[j] {
+				t.Errorf("key %d point %d: expected %+v, got %+v", i, j, want[i].Points[j], got[i].Points[j])
+			}
+		}
+	}
+
+	if keys, err := decodeBinaryFrame(nil); err != nil || keys != nil {
+		t.Errorf("expected empty frame to decode to nothing, got %v, %v", keys, err)
+	}
+}
+
+func TestDecodeBinaryFrameRejectsMalformed(t *testing.T) {
+	frame := appendBinaryFrame(nil, []gtsdbKeyData{
+		{Key: "sensor", Points: []gtsdbDataPoint{{Timestamp: 1, Value: 1}, {Timestamp: 2, Value: 2}}},
+	})
+	for n := 1; n < len(frame); n++ {
+		if _, err := decodeBinaryFrame(frame[:n]); err == nil {
+			t.Errorf("expected error for frame truncated to %d of %d bytes", n, len(frame))
+		}
+	}
+	if _, err := decodeBinaryFrame(append(frame, 0)); err == nil {
+		t.Error("expected error for trailing bytes")
+	}
+
+	huge := binary.BigEndian.AppendUint32(nil, math.MaxUint32)
+	if _, err := decodeBinaryFrame(huge); err == nil {
+		t.Error("expected error for key count larger than the frame")
+	}
+}
+
+func TestReadBinaryFrameReportsShortFrame(t *testing.T) {
+	var buf bytes.Buffer
+	body := appendBinaryFrame(nil, []gtsdbKeyData{{Key: "k", Points: []gtsdbDataPoint{{Timestamp: 1, Value: 1}}}})
+	binary.Write(&buf, binary.BigEndian, uint32(len(body)))
+	buf.Write(body[:len(body)-3])
+	if _, err := readBinaryFrame(bufio.NewReader(&buf)); err == nil {
+		t.Error("expected error for frame shorter than its length prefix")
+	}
+
+	buf.Reset()
+	binary.Write(&buf, binary.BigEndian, uint32(len(body)))
+	buf.Write(body)
+	frame, err := readBinaryFrame(bufio.NewReader(&buf))
+	if err != nil {
+		t.Fatalf("read frame: %v", err)
+	}
+	keys, err := decodeBinaryFrame(frame)
+	if n := countPoints(keys); err != nil || n != 1 {
+		t.Errorf("expected 1 point, got %d, %v", n, err)
+	}
+}
+
+func TestGTSDBDriverAgainstFakeServer(t *testing.T) {
+	srv := newFakeGTSDB(t, fakeGTSDBConfig{})
+	d := newGTSDBDriver(srv.Addr(), 2, 16)
+	ctx := context.Background()
+	if err := d.Connect(ctx); err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer d.Close()
+
+	if err := d.Write(ctx, "live", 1.5); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	points := []KeyedPoint{
+		{Key: "a", Value: 1, Timestamp: 1700000000},
+		{Key: "a", Value: 2, Timestamp: 1700000001},
+		{Key: "a", Value: 3, Timestamp: 1700000002},
+		{Key: "b", Value: 4, Timestamp: 1700000000},
+	}
+	if err := d.WriteBatch(ctx, points); err != nil {
+		t.Fatalf("write batch: %v", err)
+	}
+
+	if n, err := d.Read(ctx, "a", 2); err != nil || n != 2 {
+		t.Errorf("read: expected 2 points, got %d, %v", n, err)
+	}
+	if n, err := d.ReadRange(ctx, "a", 1700000001, 1700000002); err != nil || n != 2 {
+		t.Errorf("read range: expected 2 points, got %d, %v", n, err)
+	}
+	counts, err := d.MultiRead(ctx, []string{"a", "b", "live"}, 10)
+	if err != nil || counts["a"] != 3 || counts["b"] != 1 || counts["live"] != 1 {
+		t.Errorf("multi-read: unexpected counts %v, %v", counts, err)
+	}
+
+	got, err := d.ReadPoints(ctx, "a", 10)
+	if err != nil {
+		t.Fatalf("read points: %v", err)
+	}
+	for i, p := range got {
+		if p != points[i] {
+			t.Errorf("point %d: expected %+v, got %+v", i, points[i], p)
+		}
+	}
+	multi, err := d.MultiReadPoints(ctx, []string{"a", "b"}, 1)
+	if err != nil || len(multi["a"]) != 1 || multi["a"][0] != points[2] || multi["b"][0] != points[3] {
+		t.Errorf("multi-read points: unexpected %v, %v", multi, err)
+	}
+}
+
+func TestGTSDBWriteBatchChunksAndReportsRejection(t *testing.T) {
+	srv := newFakeGTSDB(t, fakeGTSDBConfig{})
+	d := newGTSDBDriver(srv.Addr(), 2, 16)
+	defer d.Close()
+	points := make([]KeyedPoint, 2*gtsdbMaxBatch+1)
+	for i := range points {
+		points[i] = KeyedPoint{Key: "k", Value: float64(i), Timestamp: int64(i)}
+	}
+	if err := d.WriteBatch(context.Background(), points); err != nil {
+		t.Fatalf("batch write: %v", err)
+	}
+	if n := srv.count("batch-write"); n != 3 {
+		t.Errorf("expected 3 batch-write requests, got %d", n)
+	}
+	if n := len(srv.points("k")); n != len(points) {
+		t.Errorf("expected %d stored points, got %d", len(points), n)
+	}
+
+	// Reject only the second chunk: the other two are stored and the error
+	// counts exactly the rejected points.
+	rejecting := newFakeGTSDB(t, fakeGTSDBConfig{Fault: func(op string, seq int64) fakeFault {
+		if op == "batch-write" && seq == 2 {
+			return fakeReject
+		}
+		return fakeOK
+	}})
+	d = newGTSDBDriver(rejecting.Addr(), 1, 16)
+	defer d.Close()
+	err := d.WriteBatch(context.Background(), points)
+	if got := writeFailures(err, len(points)); got != gtsdbMaxBatch {
+		t.Errorf("expected %d failed points, got %d (%v)", gtsdbMaxBatch, got, err)
+	}
+	if n := len(rejecting.points("k")); n != gtsdbMaxBatch+1 {
+		t.Errorf("expected %d stored points, got %d", gtsdbMaxBatch+1, n)
+	}
+}
+
+func TestGTSDBPoolPipelinesConcurrentWriters(t *testing.T) {
+	const latency = 10 * time.Millisecond
+	srv := newFakeGTSDB(t, fakeGTSDBConfig{Latency: latency})
+	d := newGTSDBDriver(srv.Addr(), 2, 64)
+	if err := d.Connect(context.Background()); err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer d.Close()
+
+	// The fake answers each connection's requests one at a time, so 8
+	// writers sharing 2 connections finish in about 8/2 round trips of
+	// latency per write each — far less than 8× if calls were serialised.
+	start := time.Now()
+	r := runPipelinedWrite(d, "pipe", 80, 1, 8)
+	elapsed := time.Since(start)
+	if r.SuccessRate() != 100 {
+		t.Errorf("expected all writes to succeed, got %.1f%%", r.SuccessRate())
+	}
+	if n := len(srv.points("pipe")); n != 80 {
+		t.Errorf("expected 80 stored points, got %d", n)
+	}
+	if serial := 80 * latency; elapsed >= serial {
+		t.Errorf("expected writers to share connections, took %v (serial: %v)", elapsed, serial)
+	}
+}
+
+func TestGTSDBPoolReconnectsAfterDrop(t *testing.T) {
+	srv := newFakeGTSDB(t, fakeGTSDBConfig{Fault: func(_ string, seq int64) fakeFault {
+		if seq == 3 {
+			return fakeDrop
+		}
+		return fakeOK
+	}})
+	d := newGTSDBDriver(srv.Addr(), 1, 16)
+	ctx := context.Background()
+	if err := d.Connect(ctx); err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer d.Close()
+
+	var failed int
+	for i := 0; i < 10; i++ {
+		if err := d.Write(ctx, "k", float64(i)); err != nil {
+			failed++
+		}
+	}
+	if failed != 1 {
+		t.Errorf("expected only the dropped write to fail, got %d failures", failed)
+	}
+	if n := len(srv.points("k")); n != 9 {
+		t.Errorf("expected 9 stored points after reconnecting, got %d", n)
+	}
+}
+
+func TestGTSDBReadAggregateDownsamples(t *testing.T) {
+	srv := newFakeGTSDB(t, fakeGTSDBConfig{})
+	d := newGTSDBDriver(srv.Addr(), 2, 16)
+	ctx := context.Background()
+	if err := d.Connect(ctx); err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer d.Close()
+
+	points := make([]KeyedPoint, 300)
+	for i := range points {
+		points[i] = KeyedPoint{Key: "r", Value: float64(i), Timestamp: int64(1700000000 + i)}
+	}
+	if err := d.WriteBatch(ctx, points); err != nil {
+		t.Fatalf("write batch: %v", err)
+	}
+
+	if n, err := d.ReadAggregate(ctx, "r", 1700000000, 1700000299, 60, "avg"); err != nil || n != 5 {
+		t.Errorf("expected 5 buckets, got %d, %v", n, err)
+	}
+	if n, err := d.ReadAggregate(ctx, "r", 1700000030, 1700000089, 60, "avg"); err != nil || n != 1 {
+		t.Errorf("expected 1 bucket, got %d, %v", n, err)
+	}
+	if _, err := d.ReadAggregate(ctx, "r", 1700000000, 1700000299, 60, "max"); err == nil {
+		t.Error("expected error for an aggregation GTSDB does not support")
+	}
+}
+
+func TestGTSDBKeyCreationUsesInitKey(t *testing.T) {
+	srv := newFakeGTSDB(t, fakeGTSDBConfig{})
+	d := newGTSDBDriver(srv.Addr(), 2, 16)
+	if err := d.Connect(context.Background()); err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer d.Close()
+
+	r := runKeyCreation(d, "hc_", 0, 2500, cardinalityBatch)
+	if r.SuccessRate() != 100 || r.OperationCount != 2500 {
+		t.Fatalf("expected 2500 keys created, got %d at %.1f%%", r.OperationCount, r.SuccessRate())
+	}
+	if got := srv.count("initkey"); got != 2500 {
+		t.Errorf("expected 2500 initkey requests, got %d", got)
+	}
+	if got := srv.count("batch-write"); got != 0 {
+		t.Errorf("expected no points written, got %d batch-writes", got)
+	}
+}
+
+func TestGTSDBAdminBenchmarksAgainstFakeServer(t *testing.T) {
+	srv := newFakeGTSDB(t, fakeGTSDBConfig{})
+	d := newGTSDBDriver(srv.Addr(), 2, 16)
+	if err := d.Connect(context.Background()); err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer d.Close()
+	spec, _ := lookupDriver("gtsdb")
+	cfg := &Config{Count: 2500, Runs: 1, Workers: 4}
+	caps := capsOf(cfg, spec, d)
+	if !caps.AggregateReader || capsOf(&Config{Agg: "max"}, spec, d).AggregateReader {
+		t.Error("expected GTSDB to downsample with avg only")
+	}
+
+	results := make(map[string]*BenchmarkResult)
+	for _, b := range benchmarks {
+		switch b.Name {
+		case "Key Listing", "Key Rename", "Key Delete", "Bulk Load", "Flush", "Export":
+			if !b.Supports(caps) {
+				t.Fatalf("%s: expected GTSDB to be supported", b.Name)
+			}
+			r := b.Run(cfg, d, caps)
+			if r.SuccessRate() != 100 {
+				t.Errorf("%s: success %.1f%%", b.Name, r.SuccessRate())
+			}
+			results[b.Name] = r
+			if b.Name == "Bulk Load" {
+				if got := len(srv.points(benchSensorKey)); got != cfg.Count {
+					t.Errorf("expected %d points loaded through data-patch, got %d", cfg.Count, got)
+				}
+			}
+		}
+	}
+	if len(results) != 6 {
+		t.Fatalf("expected 6 admin benchmarks, ran %d", len(results))
+	}
+	for op, want := range map[string]int64{"renamekey": adminKeys, "delete": adminKeys, "flush": flushesPerRun, "data-patch": 4} {
+		if got := srv.count(op); got != want {
+			t.Errorf("expected %d %s requests, got %d", want, op, got)
+		}
+	}
+	if got := srv.count("write"); got == 0 {
+		t.Error("expected background writes during rename and delete")
+	}
+	if n, err := d.ListKeys(context.Background()); err != nil || n < listingKeys+adminKeys {
+		t.Errorf("expected listing and renamed keys to remain, got %d, %v", n, err)
+	}
+}
+
+func TestGTSDBPubSubAgainstFakeServer(t *testing.T) {
+	srv := newFakeGTSDB(t, fakeGTSDBConfig{})
+	d := newGTSDBDriver(srv.Addr(), 2, 16)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := d.PubSub(ctx, "topic", 50); err != nil {
+		t.Fatalf("pubsub: %v", err)
+	}
+	if n := srv.count("subscribe"); n != 1 {
+		t.Errorf("expected 1 subscribe, got %d", n)
+	}
+}
+
+func TestGTSDBDriverInjectedLatencyAndFaults(t *testing.T) {
+	const latency = 20 * time.Millisecond
+	srv := newFakeGTSDB(t, fakeGTSDBConfig{
+		Latency: latency,
+		Fault: func(op string, seq int64) fakeFault {
+			if op == "read" && seq > 1 {
+				return fakeGarbage
+			}
+			return fakeOK
+		},
+	})
+	d := newGTSDBDriver(srv.Addr(), 2, 16)
+	ctx := context.Background()
+	if err := d.Connect(ctx); err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer d.Close()
+
+	start := time.Now()
+	if _, err := d.Read(ctx, "k", 1); err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < latency {
+		t.Errorf("expected read to take at least %v, took %v", latency, elapsed)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+	if _, err := d.Read(ctx, "k", 1); err == nil {
+		t.Error("expected truncated binary frame to be reported")
+	}
+}
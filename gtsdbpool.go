@@ -0,0 +1,224 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"sync"
+	"sync/atomic"
+)
+
+// gtsdbFraming selects how a response is delimited on the wire.
+type gtsdbFraming int
+
+const (
+	gtsdbLine  gtsdbFraming = iota // newline-terminated JSON
+	gtsdbFrame                     // length-prefixed binary frame
+)
+
+type gtsdbReply struct {
+	data []byte
+	err  error
+}
+
+// gtsdbCall is one request awaiting its response.
+type gtsdbCall struct {
+	framing gtsdbFraming
+	done    chan gtsdbReply
+}
+
+func (c *gtsdbCall) wait(ctx context.Context) ([]byte, error) {
+	select {
+	case r := <-c.done:
+		return r.data, r.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// gtsdbLink is one TCP connection and the calls waiting on it, in the order
+// their requests were sent. GTSDB answers requests on a connection in order,
+// so the head of pending owns the next response.
+type gtsdbLink struct {
+	conn    net.Conn
+	pending chan *gtsdbCall
+	closed  chan struct{}
+	once    sync.Once
+}
+
+func (l *gtsdbLink) close() {
+	l.once.Do(func() {
+		close(l.closed)
+		l.conn.Close()
+	})
+}
+
+// gtsdbConn is one slot of a gtsdbPool. Requests are pipelined: senders
+// write and queue a call, and a reader goroutine hands each response to the
+// call at the head of the queue. When the link breaks its queued calls fail
+// and the next request redials.
+type gtsdbConn struct {
+	addr  string
+	slots chan struct{} // bounds requests in flight
+	mu    sync.Mutex    // keeps queue order equal to send order
+	link  *gtsdbLink
+}
+
+func (c *gtsdbConn) send(ctx context.Context, payload []byte, framing gtsdbFraming) (*gtsdbCall, error) {
+	select {
+	case c.slots <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	call := &gtsdbCall{framing: framing, done: make(chan gtsdbReply, 1)}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.link == nil {
+		if err := c.dialLocked(ctx); err != nil {
+			<-c.slots
+			return nil, err
+		}
+	}
+	l := c.link
+	if _, err := l.conn.Write(payload); err != nil {
+		c.link = nil
+		l.close()
+		<-c.slots
+		return nil, err
+	}
+	l.pending <- call
+	return call, nil
+}
+
+func (c *gtsdbConn) dialLocked(ctx context.Context) error {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", c.addr)
+	if err != nil {
+		return fmt.Errorf("gtsdb connect: %w", err)
+	}
+	l := &gtsdbLink{
+		conn:    conn,
+		pending: make(chan *gtsdbCall, cap(c.slots)),
+		closed:  make(chan struct{}),
+	}
+	c.link = l
+	go c.readLoop(l)
+	return nil
+}
+
+func (c *gtsdbConn) readLoop(l *gtsdbLink) {
+	reader := bufio.NewReaderSize(l.conn, 64*1024)
+	for {
+		var call *gtsdbCall
+		select {
+		case call = <-l.pending:
+		case <-l.closed:
+			c.fail(l, net.ErrClosed)
+			return
+		}
+
+		var data []byte
+		var err error
+		if call.framing == gtsdbFrame {
+			data, err = readBinaryFrame(reader)
+		} else {
+			data, err = reader.ReadBytes('\n')
+		}
+		if err != nil {
+			err = fmt.Errorf("gtsdb connection lost: %w", err)
+			call.done <- gtsdbReply{err: err}
+			<-c.slots
+			c.fail(l, err)
+			return
+		}
+		call.done <- gtsdbReply{data: data}
+		<-c.slots
+	}
+}
+
+// fail retires l and fails every call still queued on it.
+func (c *gtsdbConn) fail(l *gtsdbLink, err error) {
+	c.mu.Lock()
+	if c.link == l {
+		c.link = nil
+	}
+	c.mu.Unlock()
+	l.close()
+	// No sender can queue on l any more, so draining empties it for good.
+	for {
+		select {
+		case call := <-l.pending:
+			call.done <- gtsdbReply{err: err}
+			<-c.slots
+		default:
+			return
+		}
+	}
+}
+
+func (c *gtsdbConn) close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.link != nil {
+		c.link.close()
+		c.link = nil
+	}
+}
+
+// gtsdbPool spreads requests round-robin over a fixed number of pipelined
+// connections.
+type gtsdbPool struct {
+	conns []*gtsdbConn
+	next  atomic.Uint32
+}
+
+// newGTSDBPool creates a pool of size connections to addr, each carrying at
+// most depth requests in flight. Connections are dialled on first use.
+func newGTSDBPool(addr string, size, depth int) *gtsdbPool {
+	p := &gtsdbPool{conns: make([]*gtsdbConn, max(size, 1))}
+	for i := range p.conns {
+		p.conns[i] = &gtsdbConn{addr: addr, slots: make(chan struct{}, max(depth, 1))}
+	}
+	return p
+}
+
+// connect dials every connection up front so an unreachable server is
+// reported by Connect rather than the first benchmark.
+func (p *gtsdbPool) connect(ctx context.Context) error {
+	for _, c := range p.conns {
+		c.mu.Lock()
+		var err error
+		if c.link == nil {
+			err = c.dialLocked(ctx)
+		}
+		c.mu.Unlock()
+		if err != nil {
+			p.close()
+			return err
+		}
+	}
+	return nil
+}
+
+// send writes payload on the next connection and returns the pending call.
+func (p *gtsdbPool) send(ctx context.Context, payload []byte, framing gtsdbFraming) (*gtsdbCall, error) {
+	c := p.conns[int(p.next.Add(1)-1)%len(p.conns)]
+	return c.send(ctx, payload, framing)
+}
+
+// do sends payload and waits for its response.
+func (p *gtsdbPool) do(ctx context.Context, payload []byte, framing gtsdbFraming) ([]byte, error) {
+	call, err := p.send(ctx, payload, framing)
+	if err != nil {
+		return nil, err
+	}
+	return call.wait(ctx)
+}
+
+func (p *gtsdbPool) close() {
+	for _, c := range p.conns {
+		c.close()
+	}
+}
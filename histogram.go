@@ -0,0 +1,185 @@
+package main
+
+import (
+	"math"
+	"math/bits"
+	"slices"
+	"sync"
+	"time"
+)
+
+// histSubBits controls histogram precision: each power-of-two range is split
+// into 2^(histSubBits-1) linear buckets, giving a relative error below
+// 1/2^(histSubBits-1), about 1.6%.
+const (
+	histSubBits    = 7
+	histSubCount   = 1 << histSubBits
+	histHalfCount  = histSubCount / 2
+	histBucketSize = histSubCount + (64-histSubBits)*histHalfCount
+)
+
+// latencyHistogram is an HDR-style log-linear histogram of per-operation
+// latencies in nanoseconds. It is safe for concurrent use.
+type latencyHistogram struct {
+	mu     sync.Mutex
+	counts []uint64
+	total  uint64
+	min    int64
+	max    int64
+	sum    float64
+}
+
+func newLatencyHistogram() *latencyHistogram {
+	return &latencyHistogram{counts: make([]uint64, histBucketSize), min: math.MaxInt64}
+}
+
+// histIndex maps a value to its bucket index.
+func histIndex(v uint64) int {
+	if v < histSubCount {
+		return int(v)
+	}
+	shift := bits.Len64(v) - histSubBits
+	m := v >> uint(shift)
+	return histSubCount + (shift-1)*histHalfCount + int(m-histHalfCount)
+}
+
+// histUpper returns the highest value that maps to bucket idx.
+func histUpper(idx int) uint64 {
+	if idx < histSubCount {
+		return uint64(idx)
+	}
+	shift := (idx-histSubCount)/histHalfCount + 1
+	m := uint64((idx-histSubCount)%histHalfCount + histHalfCount)
+	return (m+1)<<uint(shift) - 1
+}
+
+func (h *latencyHistogram) Record(d time.Duration) {
+	if d < 0 {
+		d = 0
+	}
+	h.mu.Lock()
+	h.recordLocked(int64(d), 1)
+	h.mu.Unlock()
+}
+
+func (h *latencyHistogram) recordLocked(v int64, n uint64) {
+	h.counts[histIndex(uint64(v))] += n
+	h.total += n
+	h.sum += float64(v) * float64(n)
+	if v < h.min {
+		h.min = v
+	}
+	if v > h.max {
+		h.max = v
+	}
+}
+
+// Merge adds all samples of o into h.
+func (h *latencyHistogram) Merge(o *latencyHistogram) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.total == 0 {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for i, c := range o.counts {
+		h.counts[i] += c
+	}
+	h.total += o.total
+	h.sum += o.sum
+	h.min = min(h.min, o.min)
+	h.max = max(h.max, o.max)
+}
+
+func (h *latencyHistogram) Count() uint64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.total
+}
+
+// Percentile returns the latency at quantile p (0..1), using the same
+// nearest-rank definition as percentile().
+func (h *latencyHistogram) Percentile(p float64) time.Duration {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.percentileLocked(p)
+}
+
+func (h *latencyHistogram) percentileLocked(p float64) time.Duration {
+	if h.total == 0 {
+		return 0
+	}
+	v := int64(countsQuantile(h.counts, h.total, p))
+	return time.Duration(min(max(v, h.min), h.max))
+}
+
+// countsQuantile returns the upper bound of the bucket holding quantile p
+// of the total values counted in counts.
+func countsQuantile(counts []uint64, total uint64, p float64) uint64 {
+	rank := uint64(math.Ceil(p * float64(total)))
+	if rank < 1 {
+		rank = 1
+	}
+	var seen uint64
+	for i, c := range counts {
+		seen += c
+		if seen >= rank {
+			return histUpper(i)
+		}
+	}
+	return histUpper(len(counts) - 1)
+}
+
+// histCount is the count of one histogram bucket.
+type histCount struct {
+	Bucket int
+	N      uint64
+}
+
+// countsSince returns the non-empty buckets h gained since mark, counts it
+// returned earlier (nil for all of them), and h's current counts.
+func (h *latencyHistogram) countsSince(mark []uint64) (delta []histCount, counts []uint64) {
+	h.mu.Lock()
+	counts = slices.Clone(h.counts)
+	h.mu.Unlock()
+	for i, c := range counts {
+		if mark != nil {
+			c -= mark[i]
+		}
+		if c > 0 {
+			delta = append(delta, histCount{i, c})
+		}
+	}
+	return delta, counts
+}
+
+// latencySummary holds the per-operation percentiles reported for a result.
+type latencySummary struct {
+	Count uint64
+	Mean  time.Duration
+	P50   time.Duration
+	P90   time.Duration
+	P99   time.Duration
+	P999  time.Duration
+	P9999 time.Duration
+	Max   time.Duration
+}
+
+func (h *latencyHistogram) Summary() latencySummary {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.total == 0 {
+		return latencySummary{}
+	}
+	return latencySummary{
+		Count: h.total,
+		Mean:  time.Duration(h.sum / float64(h.total)),
+		P50:   h.percentileLocked(0.50),
+		P90:   h.percentileLocked(0.90),
+		P99:   h.percentileLocked(0.99),
+		P999:  h.percentileLocked(0.999),
+		P9999: h.percentileLocked(0.9999),
+		Max:   time.Duration(h.max),
+	}
+}
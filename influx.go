@@ -1,25 +1,31 @@
 package main
 
 import (
+	"bufio"
 	"context"
+	"flag"
 	"fmt"
 	"io"
 	"math/rand/v2"
 	"net/http"
+	"os"
 	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
 
 	influxdb2 "github.com/influxdata/influxdb-client-go/v2"
+	"github.com/influxdata/influxdb-client-go/v2/api"
 )
 
 type influxDriver struct {
-	url    string
-	token  string
-	org    string
-	bucket string
-	client influxdb2.Client
+	url      string
+	token    string
+	org      string
+	bucket   string
+	client   influxdb2.Client
+	writeAPI api.WriteAPI
+	writes   *influxWriteTracker
 }
 
 func newInfluxDriver(url, token, org, bucket string) *influxDriver {
@@ -31,15 +37,66 @@ func newInfluxDriver(url, token, org, bucket string) *influxDriver {
 	}
 }
 
+func init() {
+	registerDriver(&driverSpec{
+		Name: "influx",
+		Flags: func(fs *flag.FlagSet, cfg *Config) {
+			fs.StringVar(&cfg.InfluxURL, "influx-url", "http://localhost:8086", "InfluxDB URL")
+			fs.StringVar(&cfg.InfluxToken, "influx-token", os.Getenv("INFLUX_TOKEN"), "InfluxDB token (env: INFLUX_TOKEN)")
+			fs.StringVar(&cfg.InfluxOrg, "influx-org", "bench", "InfluxDB organization")
+			fs.StringVar(&cfg.InfluxBucket, "influx-bucket", "bench", "InfluxDB bucket")
+		},
+		Validate: func(cfg *Config) error {
+			if cfg.InfluxToken == "" {
+				return fmt.Errorf("influx-token is required (set via --influx-token or INFLUX_TOKEN env)")
+			}
+			return nil
+		},
+		New: func(cfg *Config) Driver {
+			return newInfluxDriver(cfg.InfluxURL, cfg.InfluxToken, cfg.InfluxOrg, cfg.InfluxBucket)
+		},
+		Endpoints: func(cfg *Config) []endpoint { return []endpoint{urlEndpoint(&cfg.InfluxURL)} },
+		Module:    "github.com/influxdata/influxdb-client-go/v2",
+		Runners: map[string]benchRunner{
+			"Multi-Key Write": func(cfg *Config, d Driver, _ driverCaps) *BenchmarkResult {
+				return runMultiWriteInflux(d.(*influxDriver), cfg.Count/cfg.Sensors, cfg.Sensors, cfg.Runs, cfg.Workers)
+			},
+			"Multi-Key Read": func(cfg *Config, d Driver, _ driverCaps) *BenchmarkResult {
+				i := d.(*influxDriver)
+				fmt.Println("Pre-loading InfluxDB...")
+				if err := i.preload(cfg.Sensors, multiKeyPoints); err != nil {
+					fmt.Fprintf(os.Stderr, "%s preload: %v\n", i.Name(), err)
+				}
+				time.Sleep(500 * time.Millisecond) // wait for async flush to complete
+				fmt.Println("Pre-load done.")
+				return runReadManyInflux(i, cfg.Sensors, multiKeyPoints, cfg.Runs, cfg.Workers)
+			},
+		},
+	})
+}
+
 func (d *influxDriver) Name() string { return "InfluxDB" }
 
 func (d *influxDriver) Connect(ctx context.Context) error {
+	d.writes = &influxWriteTracker{next: &http.Transport{MaxIdleConnsPerHost: 100}}
+	// Retries are disabled so that every rejected batch is counted exactly
+	// once and never resurfaces as a late success.
 	d.client = influxdb2.NewClientWithOptions(d.url, d.token,
-		influxdb2.DefaultOptions().SetBatchSize(5000).SetFlushInterval(1000))
+		influxdb2.DefaultOptions().SetBatchSize(5000).SetFlushInterval(1000).SetMaxRetries(0).
+			SetHTTPClient(&http.Client{Timeout: 20 * time.Second, Transport: d.writes}))
 	_, err := d.client.Ready(ctx)
 	if err != nil {
 		return fmt.Errorf("influxdb not ready: %w", err)
 	}
+
+	// The async API only reports errors once its channel is being read.
+	d.writeAPI = d.client.WriteAPI(d.org, d.bucket)
+	errs := d.writeAPI.Errors()
+	go func() {
+		for err := range errs {
+			d.writes.setErr(err)
+		}
+	}()
 	return nil
 }
 
@@ -50,6 +107,10 @@ func (d *influxDriver) Close() error {
 	return nil
 }
 
+func (d *influxDriver) ServerMemory(ctx context.Context) (uint64, error) {
+	return scrapeResidentMemory(ctx, http.DefaultClient, strings.TrimRight(d.url, "/")+"/metrics")
+}
+
 func (d *influxDriver) Write(ctx context.Context, key string, value float64) error {
 	writeAPI := d.client.WriteAPIBlocking(d.org, d.bucket)
 	p := influxdb2.NewPoint(
@@ -61,8 +122,11 @@ func (d *influxDriver) Write(ctx context.Context, key string, value float64) err
 	return writeAPI.WritePoint(ctx, p)
 }
 
+// WriteBatch queues points on the async API and flushes it. Failures are
+// attributed by comparing the rejected-point count around the flush, so
+// concurrent callers may see each other's rejections.
 func (d *influxDriver) WriteBatch(ctx context.Context, points []KeyedPoint) error {
-	writeAPI := d.client.WriteAPI(d.org, d.bucket)
+	before := d.writes.failed()
 	for _, point := range points {
 		p := influxdb2.NewPoint(
 			"sensor_data",
@@ -70,9 +134,12 @@ func (d *influxDriver) WriteBatch(ctx context.Context, points []KeyedPoint) erro
 			map[string]interface{}{"value": point.Value},
 			time.Unix(point.Timestamp, 0),
 		)
-		writeAPI.WritePoint(p)
+		d.writeAPI.WritePoint(p)
+	}
+	d.writeAPI.Flush()
+	if n := d.writes.failed() - before; n > 0 {
+		return &partialWriteError{Failed: int(n), Total: len(points), Err: d.writes.lastErr()}
 	}
-	writeAPI.Flush()
 	return nil
 }
 
@@ -95,16 +162,108 @@ func (d *influxDriver) Read(ctx context.Context, key string, lastX int) (int, er
 	return count, nil
 }
 
-func (d *influxDriver) readMany(ctx context.Context, numSensors, pointsPerSensor int) (success, failure uint64) {
+func (d *influxDriver) ReadRange(ctx context.Context, key string, start, end int64) (int, error) {
+	queryAPI := d.client.QueryAPI(d.org)
+	// range() stop is exclusive; end is inclusive.
+	query := fmt.Sprintf(`from(bucket:"%s")
+	|> range(start: %d, stop: %d)
+	|> filter(fn: (r) => r["sensor_id"] == "%s")`, d.bucket, start, end+1, key)
+
+	records, err := queryAPI.Query(ctx, query)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for records.Next() {
+		count++
+	}
+	return count, records.Err()
+}
+
+func (d *influxDriver) ReadAggregate(ctx context.Context, key string, start, end, bucket int64, agg string) (int, error) {
+	records, err := d.client.QueryAPI(d.org).Query(ctx, fluxAggregateQuery(d.bucket, key, start, end, bucket, agg))
+	if err != nil {
+		return 0, err
+	}
+	count := 0
+	for records.Next() {
+		count++
+	}
+	return count, records.Err()
+}
+
+// ListKeys lists the sensor_id tag values of the bucket.
+func (d *influxDriver) ListKeys(ctx context.Context) (int, error) {
+	records, err := d.client.QueryAPI(d.org).Query(ctx, fluxKeysQuery(d.bucket))
+	if err != nil {
+		return 0, err
+	}
+	count := 0
+	for records.Next() {
+		count++
+	}
+	return count, records.Err()
+}
+
+// DeleteKey deletes every point of key with a delete predicate.
+func (d *influxDriver) DeleteKey(ctx context.Context, key string) error {
+	return d.client.DeleteAPI().DeleteWithName(ctx, d.org, d.bucket, time.Unix(0, 0), time.Now(), fmt.Sprintf(`sensor_id="%s"`, key))
+}
+
+// fluxKeysQuery lists every sensor_id in bucket.
+func fluxKeysQuery(bucket string) string {
+	return fmt.Sprintf(`import "influxdata/influxdb/schema"
+schema.tagValues(bucket: "%s", tag: "sensor_id", start: 0)`, bucket)
+}
+
+// fluxAggregateQuery reduces key's values between start and end (inclusive)
+// over windows of every seconds.
+func fluxAggregateQuery(bucket, key string, start, end, every int64, agg string) string {
+	fn := agg
+	if agg == "avg" {
+		fn = "mean"
+	}
+	return fmt.Sprintf(`from(bucket:"%s")
+	|> range(start: %d, stop: %d)
+	|> filter(fn: (r) => r["sensor_id"] == "%s" and r["_field"] == "value")
+	|> aggregateWindow(every: %ds, fn: %s, createEmpty: false)`, bucket, start, end+1, key, every, fn)
+}
+
+func (d *influxDriver) ReadPoints(ctx context.Context, key string, lastX int) ([]KeyedPoint, error) {
+	queryAPI := d.client.QueryAPI(d.org)
+	query := fmt.Sprintf(`from(bucket:"%s")
+	|> range(start: -1h)
+	|> filter(fn: (r) => r["sensor_id"] == "%s" and r["_field"] == "value")
+	|> tail(n:%d)`, d.bucket, key, lastX)
+
+	records, err := queryAPI.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	var points []KeyedPoint
+	for records.Next() {
+		v, ok := records.Record().Value().(float64)
+		if !ok {
+			return points, fmt.Errorf("unexpected value type %T", records.Record().Value())
+		}
+		points = append(points, KeyedPoint{Key: key, Value: v, Timestamp: records.Record().Time().Unix()})
+	}
+	return points, records.Err()
+}
+
+// readMany queries the last pointsPerSensor points of every sensor, from
+// workers goroutines (one per sensor when workers is 0).
+func (d *influxDriver) readMany(ctx context.Context, numSensors, pointsPerSensor, workers int) (success, failure uint64) {
 	httpClient := &http.Client{Transport: &http.Transport{MaxIdleConnsPerHost: 100}}
 	queryURL := fmt.Sprintf("%s/api/v2/query?org=%s", d.url, d.org)
+	if workers == 0 {
+		workers = numSensors
+	}
 
-	var wg sync.WaitGroup
-	wg.Add(numSensors)
-
-	for i := 0; i < numSensors; i++ {
-		go func(sensorIdx int) {
-			defer wg.Done()
+	splitWork(workers, numSensors, func(lo, hi int) {
+		for sensorIdx := lo; sensorIdx < hi; sensorIdx++ {
 			// Read last N points per sensor in a single query
 			flux := fmt.Sprintf(`from(bucket:"%s") |> range(start: 0) |> filter(fn: (r) => r._measurement == "sensor" and r.key == "sensor%d") |> sort(columns: ["_time"], desc: true) |> limit(n:%d)`, d.bucket, sensorIdx, pointsPerSensor)
 			req, _ := http.NewRequestWithContext(ctx, "POST", queryURL, strings.NewReader(flux))
@@ -127,15 +286,13 @@ func (d *influxDriver) readMany(ctx context.Context, numSensors, pointsPerSensor
 			} else {
 				atomic.AddUint64(&failure, uint64(pointsPerSensor))
 			}
-		}(i)
-	}
-	wg.Wait()
+		}
+	})
 	return
 }
 
 func (d *influxDriver) preload(numSensors, pointsPerSensor int) error {
-	writeAPI := d.client.WriteAPI(d.org, d.bucket)
-
+	before := d.writes.failed()
 	for i := 0; i < numSensors; i++ {
 		for j := 0; j < pointsPerSensor; j++ {
 			p := influxdb2.NewPoint(
@@ -144,25 +301,30 @@ func (d *influxDriver) preload(numSensors, pointsPerSensor int) error {
 				map[string]interface{}{"value": rand.Float64() * 100},
 				time.Unix(int64(1700000000+j), 0),
 			)
-			writeAPI.WritePoint(p)
+			d.writeAPI.WritePoint(p)
 		}
 	}
 
-	writeAPI.Flush()
+	d.writeAPI.Flush()
+	if n := d.writes.failed() - before; n > 0 {
+		return fmt.Errorf("influx rejected %d preload points: %v", n, d.writes.lastErr())
+	}
 	return nil
 }
 
-// multiWrite performs concurrent writes across multiple sensors.
-func (d *influxDriver) multiWrite(numPointsPerSensor, numSensors int) (success, failure uint64, elapsed time.Duration) {
-	writeAPI := d.client.WriteAPI(d.org, d.bucket)
+// multiWrite performs concurrent writes across multiple sensors from workers
+// goroutines (one per sensor when workers is 0). Points in batches the
+// server rejected are counted as failures.
+func (d *influxDriver) multiWrite(numPointsPerSensor, numSensors, workers int) (success, failure uint64, elapsed time.Duration) {
+	before := d.writes.failed()
 	start := time.Now()
+	if workers == 0 {
+		workers = numSensors
+	}
 
-	var wg sync.WaitGroup
-	wg.Add(numSensors)
-
-	for i := 0; i < numSensors; i++ {
-		go func(sid string) {
-			defer wg.Done()
+	splitWork(workers, numSensors, func(lo, hi int) {
+		for i := lo; i < hi; i++ {
+			sid := fmt.Sprintf("benchmark_sensor_%d", i)
 			for j := 0; j < numPointsPerSensor; j++ {
 				p := influxdb2.NewPoint(
 					"sensor_data",
@@ -170,14 +332,74 @@ func (d *influxDriver) multiWrite(numPointsPerSensor, numSensors int) (success,
 					map[string]interface{}{"value": rand.Float64() * 100},
 					time.Now(),
 				)
-				writeAPI.WritePoint(p)
-				atomic.AddUint64(&success, 1)
+				d.writeAPI.WritePoint(p)
 			}
-		}(fmt.Sprintf("benchmark_sensor_%d", i))
-	}
+		}
+	})
 
-	wg.Wait()
-	writeAPI.Flush()
+	d.writeAPI.Flush()
 	elapsed = time.Since(start)
+
+	total := uint64(numPointsPerSensor * numSensors)
+	failure = min(d.writes.failed()-before, total)
+	success = total - failure
 	return
 }
+
+// influxWriteTracker wraps the client's transport and counts the lines of
+// every /api/v2/write request the server did not accept. The async WriteAPI
+// only reports an error value, not which points it covered.
+type influxWriteTracker struct {
+	next  http.RoundTripper
+	lines atomic.Uint64
+	mu    sync.Mutex
+	err   error
+}
+
+func (t *influxWriteTracker) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !strings.HasSuffix(req.URL.Path, "/api/v2/write") || req.GetBody == nil {
+		return t.next.RoundTrip(req)
+	}
+	var n uint64
+	if body, err := req.GetBody(); err == nil {
+		n = countLines(body)
+		body.Close()
+	}
+	resp, err := t.next.RoundTrip(req)
+	switch {
+	case err != nil:
+		t.setErr(err)
+		t.lines.Add(n)
+	case resp.StatusCode >= 300:
+		t.setErr(fmt.Errorf("write returned %s", resp.Status))
+		t.lines.Add(n)
+	}
+	return resp, err
+}
+
+func (t *influxWriteTracker) failed() uint64 { return t.lines.Load() }
+
+func (t *influxWriteTracker) setErr(err error) {
+	t.mu.Lock()
+	t.err = err
+	t.mu.Unlock()
+}
+
+func (t *influxWriteTracker) lastErr() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.err
+}
+
+// countLines counts the non-empty lines read from r.
+func countLines(r io.Reader) uint64 {
+	var n uint64
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 1<<20)
+	for sc.Scan() {
+		if len(sc.Bytes()) > 0 {
+			n++
+		}
+	}
+	return n
+}
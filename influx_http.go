@@ -0,0 +1,326 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// influxHTTPDriver talks to InfluxDB's v2 HTTP API directly: it builds line
+// protocol itself and POSTs it to /api/v2/write, so results reflect the
+// server rather than influxdb-client-go's batching and retry queue.
+type influxHTTPDriver struct {
+	url       string
+	token     string
+	org       string
+	bucket    string
+	precision string
+	gzip      bool
+	batch     int
+	client    *http.Client
+}
+
+func newInfluxHTTPDriver(url, token, org, bucket, precision string, gzip bool, batch int) *influxHTTPDriver {
+	return &influxHTTPDriver{
+		url:       strings.TrimRight(url, "/"),
+		token:     token,
+		org:       org,
+		bucket:    bucket,
+		precision: precision,
+		gzip:      gzip,
+		batch:     batch,
+		client: &http.Client{
+			Timeout: 30 * time.Second,
+			Transport: &http.Transport{
+				MaxIdleConnsPerHost: 100,
+				MaxConnsPerHost:     100,
+			},
+		},
+	}
+}
+
+func init() {
+	registerDriver(&driverSpec{
+		Name: "influx-http",
+		Flags: func(fs *flag.FlagSet, cfg *Config) {
+			fs.StringVar(&cfg.InfluxPrecision, "influx-precision", "ns", "Timestamp precision for influx-http writes: s, ms, us, ns; writes to a key within one unit overwrite each other")
+			fs.BoolVar(&cfg.InfluxGzip, "influx-gzip", false, "Gzip influx-http write bodies")
+			fs.IntVar(&cfg.InfluxBatch, "influx-batch", 5000, "Maximum lines per influx-http write request")
+		},
+		Validate: func(cfg *Config) error {
+			if cfg.InfluxToken == "" {
+				return fmt.Errorf("influx-token is required (set via --influx-token or INFLUX_TOKEN env)")
+			}
+			if _, ok := influxPrecisions[cfg.InfluxPrecision]; !ok {
+				return fmt.Errorf("influx-precision must be one of s, ms, us, ns")
+			}
+			if cfg.InfluxBatch <= 0 {
+				return fmt.Errorf("influx-batch must be positive")
+			}
+			return nil
+		},
+		New: func(cfg *Config) Driver {
+			return newInfluxHTTPDriver(cfg.InfluxURL, cfg.InfluxToken, cfg.InfluxOrg, cfg.InfluxBucket,
+				cfg.InfluxPrecision, cfg.InfluxGzip, cfg.InfluxBatch)
+		},
+		Endpoints: func(cfg *Config) []endpoint { return []endpoint{urlEndpoint(&cfg.InfluxURL)} },
+		Server:    "influx",
+	})
+}
+
+// influxPrecisions maps a write precision to the length of one unit.
+var influxPrecisions = map[string]time.Duration{
+	"s":  time.Second,
+	"ms": time.Millisecond,
+	"us": time.Microsecond,
+	"ns": time.Nanosecond,
+}
+
+func (d *influxHTTPDriver) Name() string { return "InfluxDB (HTTP)" }
+
+func (d *influxHTTPDriver) Connect(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", d.url+"/ping", nil)
+	if err != nil {
+		return err
+	}
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("influxdb not ready: %w", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("influxdb not ready: ping returned %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func (d *influxHTTPDriver) Close() error {
+	d.client.CloseIdleConnections()
+	return nil
+}
+
+func (d *influxHTTPDriver) ServerMemory(ctx context.Context) (uint64, error) {
+	return scrapeResidentMemory(ctx, d.client, d.url+"/metrics")
+}
+
+func (d *influxHTTPDriver) Write(ctx context.Context, key string, value float64) error {
+	unit := influxPrecisions[d.precision]
+	line := appendInfluxLine(nil, key, value, time.Now().UnixNano()/int64(unit))
+	return d.post(ctx, line)
+}
+
+// WriteBatch sends points in requests of at most d.batch lines and stops at
+// the first request the server rejects; that request and every later one
+// count as not written.
+func (d *influxHTTPDriver) WriteBatch(ctx context.Context, points []KeyedPoint) error {
+	perSecond := int64(time.Second / influxPrecisions[d.precision])
+	var body []byte
+	for start := 0; start < len(points); start += d.batch {
+		end := min(start+d.batch, len(points))
+		body = body[:0]
+		for _, p := range points[start:end] {
+			body = appendInfluxLine(body, p.Key, p.Value, p.Timestamp*perSecond)
+		}
+		if err := d.post(ctx, body); err != nil {
+			if start == 0 {
+				return err
+			}
+			return &partialWriteError{Failed: len(points) - start, Total: len(points), Err: err}
+		}
+	}
+	return nil
+}
+
+func (d *influxHTTPDriver) post(ctx context.Context, lines []byte) error {
+	body := lines
+	if d.gzip {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		zw.Write(lines)
+		zw.Close()
+		body = buf.Bytes()
+	}
+
+	q := url.Values{"org": {d.org}, "bucket": {d.bucket}, "precision": {d.precision}}
+	req, err := http.NewRequestWithContext(ctx, "POST", d.url+"/api/v2/write?"+q.Encode(), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Token "+d.token)
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	if d.gzip {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("influx write returned %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+// appendInfluxLine appends one sensor_data line in the same shape the
+// client-library driver writes.
+func appendInfluxLine(b []byte, key string, value float64, ts int64) []byte {
+	b = append(b, "sensor_data,sensor_id="...)
+	for i := 0; i < len(key); i++ {
+		switch key[i] {
+		case ',', '=', ' ', '\\':
+			b = append(b, '\\')
+		}
+		b = append(b, key[i])
+	}
+	b = append(b, " value="...)
+	b = strconv.AppendFloat(b, value, 'g', -1, 64)
+	b = append(b, ' ')
+	b = strconv.AppendInt(b, ts, 10)
+	return append(b, '\n')
+}
+
+func (d *influxHTTPDriver) Read(ctx context.Context, key string, lastX int) (int, error) {
+	return d.query(ctx, fmt.Sprintf(`from(bucket:"%s")
+	|> range(start: -1h)
+	|> filter(fn: (r) => r["sensor_id"] == "%s")
+	|> limit(n:%d)`, d.bucket, key, lastX))
+}
+
+func (d *influxHTTPDriver) ReadRange(ctx context.Context, key string, start, end int64) (int, error) {
+	// range() stop is exclusive; end is inclusive.
+	return d.query(ctx, fmt.Sprintf(`from(bucket:"%s")
+	|> range(start: %d, stop: %d)
+	|> filter(fn: (r) => r["sensor_id"] == "%s")`, d.bucket, start, end+1, key))
+}
+
+func (d *influxHTTPDriver) ReadAggregate(ctx context.Context, key string, start, end, bucket int64, agg string) (int, error) {
+	return d.query(ctx, fluxAggregateQuery(d.bucket, key, start, end, bucket, agg))
+}
+
+func (d *influxHTTPDriver) ListKeys(ctx context.Context) (int, error) {
+	return d.query(ctx, fluxKeysQuery(d.bucket))
+}
+
+// DeleteKey deletes every point of key through /api/v2/delete.
+func (d *influxHTTPDriver) DeleteKey(ctx context.Context, key string) error {
+	body := fmt.Sprintf(`{"start":"1970-01-01T00:00:00Z","stop":"%s","predicate":"sensor_id=\"%s\""}`,
+		time.Now().UTC().Format(time.RFC3339), key)
+	q := url.Values{"org": {d.org}, "bucket": {d.bucket}}
+	req, err := http.NewRequestWithContext(ctx, "POST", d.url+"/api/v2/delete?"+q.Encode(), strings.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Token "+d.token)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("influx delete returned %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+// ReadPoints returns the last lastX points of key, parsing _time and _value
+// from the CSV response.
+func (d *influxHTTPDriver) ReadPoints(ctx context.Context, key string, lastX int) ([]KeyedPoint, error) {
+	body, err := d.postQuery(ctx, fmt.Sprintf(`from(bucket:"%s")
+	|> range(start: -1h)
+	|> filter(fn: (r) => r["sensor_id"] == "%s" and r["_field"] == "value")
+	|> tail(n:%d)`, d.bucket, key, lastX))
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	cr := csv.NewReader(body)
+	cr.FieldsPerRecord = -1
+	var points []KeyedPoint
+	timeCol, valueCol := -1, -1
+	for {
+		row, err := cr.Read()
+		if err == io.EOF {
+			return points, nil
+		}
+		if err != nil {
+			return points, err
+		}
+		if len(row) < 2 || strings.HasPrefix(row[0], "#") {
+			continue
+		}
+		if row[1] == "result" {
+			timeCol, valueCol = slices.Index(row, "_time"), slices.Index(row, "_value")
+			continue
+		}
+		if timeCol < 0 || valueCol < 0 || timeCol >= len(row) || valueCol >= len(row) {
+			return points, fmt.Errorf("influx query: CSV response has no _time/_value columns")
+		}
+		ts, err := time.Parse(time.RFC3339Nano, row[timeCol])
+		if err != nil {
+			return points, fmt.Errorf("influx query: bad _time %q", row[timeCol])
+		}
+		v, err := strconv.ParseFloat(row[valueCol], 64)
+		if err != nil {
+			return points, fmt.Errorf("influx query: bad _value %q", row[valueCol])
+		}
+		points = append(points, KeyedPoint{Key: key, Value: v, Timestamp: ts.Unix()})
+	}
+}
+
+// query runs a Flux query and counts the data rows of the CSV response.
+func (d *influxHTTPDriver) query(ctx context.Context, flux string) (int, error) {
+	body, err := d.postQuery(ctx, flux)
+	if err != nil {
+		return 0, err
+	}
+	defer body.Close()
+
+	count := 0
+	sc := bufio.NewScanner(body)
+	for sc.Scan() {
+		line := strings.TrimRight(sc.Text(), "\r")
+		// Skip blank table separators, annotations and per-table header rows.
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ",result,") || strings.HasPrefix(line, "result,") {
+			continue
+		}
+		count++
+	}
+	return count, sc.Err()
+}
+
+// postQuery sends a Flux query and returns the CSV response body.
+func (d *influxHTTPDriver) postQuery(ctx context.Context, flux string) (io.ReadCloser, error) {
+	req, err := http.NewRequestWithContext(ctx, "POST", d.url+"/api/v2/query?org="+url.QueryEscape(d.org), strings.NewReader(flux))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Token "+d.token)
+	req.Header.Set("Content-Type", "application/vnd.flux")
+	req.Header.Set("Accept", "application/csv")
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		resp.Body.Close()
+		return nil, fmt.Errorf("influx query returned %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	return resp.Body, nil
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// influxStandIn records the line protocol posted to /api/v2/write and
+// answers every Flux query, the last of which it keeps, with a fixed number
+// of CSV rows. It serves both
+// the raw HTTP driver and influxdb-client-go.
+type influxStandIn struct {
+	mu         sync.Mutex
+	requests   int
+	lines      []string
+	precisions []string
+	gzipped    bool
+	queryRows  int
+	lastQuery  string
+	deletes    []string
+	fail       bool
+}
+
+func newInfluxStandIn(t *testing.T) (*influxStandIn, *httptest.Server) {
+	s := &influxStandIn{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ping", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	mux.HandleFunc("/ready", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"status":"ready"}`)
+	})
+	mux.HandleFunc("/api/v2/write", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Token secret" {
+			http.Error(w, `{"code":"unauthorized"}`, http.StatusUnauthorized)
+			return
+		}
+		if s.fail {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"code":"invalid","message":"partial write"}`)
+			return
+		}
+		var body io.Reader = r.Body
+		if r.Header.Get("Content-Encoding") == "gzip" {
+			zr, err := gzip.NewReader(r.Body)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			body = zr
+		}
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.requests++
+		s.gzipped = r.Header.Get("Content-Encoding") == "gzip"
+		s.precisions = append(s.precisions, r.URL.Query().Get("precision"))
+		sc := bufio.NewScanner(body)
+		for sc.Scan() {
+			s.lines = append(s.lines, sc.Text())
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+	mux.HandleFunc("/api/v2/delete", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		s.mu.Lock()
+		s.deletes = append(s.deletes, string(body))
+		s.mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	})
+	mux.HandleFunc("/api/v2/query", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		s.mu.Lock()
+		s.lastQuery = string(body)
+		s.mu.Unlock()
+		fmt.Fprint(w, ",result,table,_time,_value,sensor_id\r\n")
+		for i := 0; i < s.queryRows; i++ {
+			fmt.Fprintf(w, ",_result,0,2023-11-14T22:13:%02dZ,%d,s1\r\n", 20+i, i)
+		}
+		fmt.Fprint(w, "\r\n")
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return s, srv
+}
+
+func TestInfluxHTTPDriverWriteBatch(t *testing.T) {
+	standIn, srv := newInfluxStandIn(t)
+	d := newInfluxHTTPDriver(srv.URL, "secret", "bench", "bench", "ms", true, 2)
+	ctx := context.Background()
+	if err := d.Connect(ctx); err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer d.Close()
+
+	points := []KeyedPoint{
+		{Key: "s1", Value: 1.5, Timestamp: 1700000000},
+		{Key: "s 2", Value: 2, Timestamp: 1700000001},
+		{Key: "s1", Value: 3, Timestamp: 1700000002},
+	}
+	if err := d.WriteBatch(ctx, points); err != nil {
+		t.Fatalf("write batch: %v", err)
+	}
+
+	if standIn.requests != 2 {
+		t.Errorf("expected 2 requests for batch size 2, got %d", standIn.requests)
+	}
+	if !standIn.gzipped {
+		t.Error("expected gzip-encoded body")
+	}
+	want := []string{
+		"sensor_data,sensor_id=s1 value=1.5 1700000000000",
+		`sensor_data,sensor_id=s\ 2 value=2 1700000001000`,
+		"sensor_data,sensor_id=s1 value=3 1700000002000",
+	}
+	if len(standIn.lines) != len(want) {
+		t.Fatalf("expected %d lines, got %q", len(want), standIn.lines)
+	}
+	for i := range want {
+		if standIn.lines[i] != want[i] {
+			t.Errorf("line %d: expected %q, got %q", i, want[i], standIn.lines[i])
+		}
+	}
+	for _, p := range standIn.precisions {
+		if p != "ms" {
+			t.Errorf("expected precision ms, got %q", p)
+		}
+	}
+}
+
+func TestInfluxHTTPDriverReportsWriteErrors(t *testing.T) {
+	standIn, srv := newInfluxStandIn(t)
+	d := newInfluxHTTPDriver(srv.URL, "secret", "bench", "bench", "s", false, 5000)
+	standIn.fail = true
+
+	if err := d.Write(context.Background(), "s1", 1); err == nil {
+		t.Error("expected Write to return the server's error")
+	}
+	if err := d.WriteBatch(context.Background(), []KeyedPoint{{Key: "s1", Value: 1, Timestamp: 1}}); err == nil {
+		t.Error("expected WriteBatch to return the server's error")
+	}
+}
+
+func TestInfluxHTTPDriverCountsQueryRows(t *testing.T) {
+	standIn, srv := newInfluxStandIn(t)
+	d := newInfluxHTTPDriver(srv.URL, "secret", "bench", "bench", "s", false, 5000)
+	standIn.queryRows = 7
+
+	n, err := d.Read(context.Background(), "s1", 10)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if n != 7 {
+		t.Errorf("expected 7 rows, got %d", n)
+	}
+
+	pts, err := d.ReadPoints(context.Background(), "s1", 10)
+	if err != nil {
+		t.Fatalf("read points: %v", err)
+	}
+	if len(pts) != 7 || pts[3].Value != 3 || pts[3].Timestamp != 1700000003 {
+		t.Errorf("unexpected points %+v", pts)
+	}
+}
+
+func TestInfluxHTTPDriverDownsamplesWithAggregateWindow(t *testing.T) {
+	standIn, srv := newInfluxStandIn(t)
+	d := newInfluxHTTPDriver(srv.URL, "secret", "bench", "bench", "s", false, 5000)
+	standIn.queryRows = 4
+
+	n, err := d.ReadAggregate(context.Background(), "s1", 1700000000, 1700000239, 60, "avg")
+	if err != nil || n != 4 {
+		t.Fatalf("expected 4 buckets, got %d, %v", n, err)
+	}
+	if q := standIn.lastQuery; !strings.Contains(q, "aggregateWindow(every: 60s, fn: mean") || !strings.Contains(q, "stop: 1700000240") {
+		t.Errorf("unexpected query %s", q)
+	}
+}
+
+func TestInfluxDriversDeleteByPredicate(t *testing.T) {
+	standIn, srv := newInfluxStandIn(t)
+	ctx := context.Background()
+	client := newInfluxDriver(srv.URL, "secret", "bench", "bench")
+	if err := client.Connect(ctx); err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer client.Close()
+
+	for _, d := range []KeyDeleter{newInfluxHTTPDriver(srv.URL, "secret", "bench", "bench", "s", false, 5000), client} {
+		if err := d.DeleteKey(ctx, "s1"); err != nil {
+			t.Errorf("%s: delete: %v", d.Name(), err)
+		}
+	}
+	if len(standIn.deletes) != 2 {
+		t.Fatalf("expected 2 delete requests, got %d", len(standIn.deletes))
+	}
+	for _, body := range standIn.deletes {
+		var req struct{ Predicate string }
+		if err := json.Unmarshal([]byte(body), &req); err != nil || req.Predicate != `sensor_id="s1"` {
+			t.Errorf("unexpected delete body %s", body)
+		}
+	}
+}
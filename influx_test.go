@@ -0,0 +1,40 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestInfluxDriverCountsRejectedPoints(t *testing.T) {
+	standIn, srv := newInfluxStandIn(t)
+	d := newInfluxDriver(srv.URL, "secret", "bench", "bench")
+	if err := d.Connect(context.Background()); err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer d.Close()
+
+	s, f, _ := d.multiWrite(10, 3, 0)
+	if s != 30 || f != 0 {
+		t.Errorf("accepting server: expected 30/0, got %d/%d", s, f)
+	}
+	if len(standIn.lines) != 30 {
+		t.Errorf("expected 30 lines at the server, got %d", len(standIn.lines))
+	}
+
+	standIn.fail = true
+	s, f, _ = d.multiWrite(10, 3, 0)
+	if s != 0 || f != 30 {
+		t.Errorf("rejecting server: expected 0/30, got %d/%d", s, f)
+	}
+
+	points := []KeyedPoint{{Key: "s1", Value: 1, Timestamp: 1700000000}, {Key: "s1", Value: 2, Timestamp: 1700000001}}
+	err := d.WriteBatch(context.Background(), points)
+	var pe *partialWriteError
+	if !errors.As(err, &pe) || pe.Failed != 2 {
+		t.Fatalf("expected partialWriteError for 2 points, got %v", err)
+	}
+	if n := writeFailures(err, len(points)); n != 2 {
+		t.Errorf("expected 2 failures, got %d", n)
+	}
+}
@@ -1,9 +1,10 @@
 package main
 
 import (
-	"context"
 	"fmt"
 	"os"
+	"slices"
+	"time"
 )
 
 // readRuns returns the number of iterations for read benchmarks.
@@ -13,156 +14,77 @@ func readRuns(baseRuns int) int {
 	return max(baseRuns*50, 200)
 }
 
-func main() {
-	cfg := ParseConfig()
-	if cfg.InfluxToken == "" {
-		fmt.Fprintln(os.Stderr, "Warning: INFLUX_TOKEN not set. Skipping InfluxDB benchmarks.")
-	}
-
-	var results []*BenchmarkResult
-
-	if cfg.HasDB("gtsdb") {
-		g := newGTSDBDriver(cfg.GTSDBAddr)
-		if err := g.Connect(context.Background()); err != nil {
-			fmt.Fprintf(os.Stderr, "GTSDB: %v\n", err)
-		} else {
-			defer g.Close()
-			runGTSDBBenchmarks(cfg, g, &results)
-		}
-	}
-
-	if cfg.HasDB("influx") {
-		i := newInfluxDriver(cfg.InfluxURL, cfg.InfluxToken, cfg.InfluxOrg, cfg.InfluxBucket)
-		if err := i.Connect(context.Background()); err != nil {
-			fmt.Fprintf(os.Stderr, "InfluxDB: %v\n", err)
-		} else {
-			defer i.Close()
-			runInfluxBenchmarks(cfg, i, &results)
-		}
-	}
-
-	if cfg.HasDB("nsq") {
-		n := newNSQDriver(cfg.NSQAddr)
-		if cfg.HasBench("Pub/Sub") {
-			r := runPubSubBenchmark(n, benchSensorKey, cfg.Count, cfg.Runs)
-			results = append(results, r)
-		}
-	}
-
-	if cfg.HasDB("vm") {
-		v := newVMDriver(cfg.VMURL)
-		if err := v.Connect(context.Background()); err != nil {
-			fmt.Fprintf(os.Stderr, "VictoriaMetrics: %v\n", err)
-		} else {
-			defer v.Close()
-			runVMBenchmarks(cfg, v, &results)
-		}
-	}
-
-	printReport(cfg.Format, results)
-	printComparison(results)
+// subcommands run instead of the benchmarks when named by the first
+// argument; each returns the process exit code.
+var subcommands = map[string]func(args []string) int{
+	"compare":  runCompare,
+	"baseline": runBaseline,
+	"report":   runReport,
+	"memory":   runMemory,
+	"servers":  runServers,
+	"soak":     runSoak,
 }
 
-func runGTSDBBenchmarks(cfg *Config, g *gtsdbDriver, results *[]*BenchmarkResult) {
-	if cfg.HasBench("Write (seq)") {
-		r := runWriteBenchmark(g, benchSensorKey, cfg.Count, cfg.Warmup, cfg.Runs)
-		*results = append(*results, r)
-	}
-
-	if cfg.HasBench("Pipeline Write") {
-		r := runPipelinedWriteGTSDB(cfg.GTSDBAddr, benchSensorKey, cfg.Count, cfg.Runs)
-		*results = append(*results, r)
-	}
-
-	if cfg.HasBench("Batch Write") {
-		r := runBatchWrite(g, benchSensorKey, cfg.Count, cfg.Runs)
-		*results = append(*results, r)
-	}
-
-	if cfg.HasBench("Read (single)") {
-		r := runReadBenchmark(g, benchSensorKey, cfg.Count, readRuns(cfg.Runs))
-		*results = append(*results, r)
-	}
-
-	if cfg.HasBench("Multi-Key Write") {
-		r := runMultiWriteGTSDBBatch(g, cfg.Count/cfg.Sensors, cfg.Sensors, cfg.Runs)
-		*results = append(*results, r)
-	}
-
-	if cfg.HasBench("Pub/Sub") {
-		r := runPubSubBenchmark(g, benchSensorKey, cfg.Count, cfg.Runs)
-		*results = append(*results, r)
-	}
-
-	if cfg.HasBench("Multi-Key Read") {
-		preloadAndInit(cfg, g, nil, nil)
-		r := runMultiReadGTSDB(g, cfg.Sensors, 5000, cfg.Runs)
-		*results = append(*results, r)
+func subcommandNames() []string {
+	names := make([]string, 0, len(subcommands))
+	for name := range subcommands {
+		names = append(names, name)
 	}
+	slices.Sort(names)
+	return names
 }
 
-func runInfluxBenchmarks(cfg *Config, i *influxDriver, results *[]*BenchmarkResult) {
-	if cfg.HasBench("Write (seq)") {
-		r := runWriteBenchmark(i, benchSensorKey, cfg.Count, cfg.Warmup, cfg.Runs)
-		*results = append(*results, r)
-	}
-
-	if cfg.HasBench("Pipeline Write") {
-		r := runPipelinedWrite(i, benchSensorKey, cfg.Count, cfg.Runs)
-		*results = append(*results, r)
-	}
-
-	if cfg.HasBench("Batch Write") {
-		r := runBatchWrite(i, benchSensorKey, cfg.Count, cfg.Runs)
-		*results = append(*results, r)
-	}
-
-	if cfg.HasBench("Read (single)") {
-		r := runReadBenchmark(i, benchSensorKey, cfg.Count, readRuns(cfg.Runs))
-		*results = append(*results, r)
-	}
-
-	if cfg.HasBench("Multi-Key Write") {
-		r := runMultiWriteInflux(i, cfg.Count/cfg.Sensors, cfg.Sensors, cfg.Runs)
-		*results = append(*results, r)
-	}
-
-	if cfg.HasBench("Multi-Key Read") {
-		preloadAndInit(cfg, nil, i, nil)
-		r := runReadManyInflux(i, cfg.Sensors, 5000, cfg.Runs)
-		*results = append(*results, r)
+func main() {
+	if len(os.Args) > 1 {
+		if cmd, ok := subcommands[os.Args[1]]; ok {
+			os.Exit(cmd(os.Args[2:]))
+		}
 	}
-}
 
-func runVMBenchmarks(cfg *Config, v *vmDriver, results *[]*BenchmarkResult) {
-	if cfg.HasBench("Write (seq)") {
-		r := runWriteBenchmark(v, benchSensorKey, cfg.Count, cfg.Warmup, cfg.Runs)
-		*results = append(*results, r)
-	}
+	cfg := ParseConfig()
+	start := time.Now()
 
-	if cfg.HasBench("Pipeline Write") {
-		r := runPipelinedWrite(v, benchSensorKey, cfg.Count, cfg.Runs)
-		*results = append(*results, r)
+	if cfg.Fault != nil {
+		stop, err := startFaultProxies(cfg)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		defer stop()
 	}
 
-	if cfg.HasBench("Batch Write") {
-		r := runBatchWrite(v, benchSensorKey, cfg.Count, cfg.Runs)
-		*results = append(*results, r)
+	var reports []*verifyReport
+	if cfg.Verify {
+		reports = runVerification(cfg)
 	}
 
-	if cfg.HasBench("Read (single)") {
-		r := runReadBenchmark(v, benchSensorKey, cfg.Count, readRuns(cfg.Runs))
-		*results = append(*results, r)
+	var results []*BenchmarkResult
+	if cfg.Scenario != nil {
+		results = runScenarioBenchmarks(cfg)
+	} else if len(cfg.Cardinality) > 0 {
+		results = runCardinalityBenchmarks(cfg)
+	} else {
+		results = runBenchmarks(cfg)
+	}
+
+	applyVerification(results, reports)
+	if cfg.Fault != nil {
+		for _, r := range results {
+			r.FaultProfile = cfg.Fault.Name
+		}
 	}
-
-	if cfg.HasBench("Multi-Key Write") {
-		r := runMultiWriteVM(v, cfg.Count/cfg.Sensors, cfg.Sensors, cfg.Runs)
-		*results = append(*results, r)
+	printReport(cfg.Format, results)
+	if cfg.Verify && cfg.Format != "json" {
+		printVerification(reports)
 	}
+	printComparison(results)
 
-	if cfg.HasBench("Multi-Key Read") {
-		preloadAndInit(cfg, nil, nil, v)
-		r := runReadManyVM(v, cfg.Sensors, 5000, cfg.Runs)
-		*results = append(*results, r)
+	if cfg.ResultsDir != "" {
+		path, err := saveRun(cfg.ResultsDir, newRunRecord(cfg, results, start))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error saving run: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Fprintf(os.Stderr, "Saved run to %s\n", path)
 	}
 }
@@ -1,6 +1,28 @@
 package main
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -50,6 +72,22 @@ func TestBenchmarkResultSingleRun(t *testing.T) {
 	}
 }
 
+func TestBenchmarkResultDurationRunsKeepTheirOwnCounts(t *testing.T) {
+	r := newBenchResult("test", "testdb", 0)
+	r.addRun(time.Second, 900, 100)
+	r.addRun(time.Second, 3000, 0)
+	r.Mode = "duration 1s"
+	r.OperationCount = 2000
+	r.compute()
+
+	if got := r.RunOpsPerSec(); !slices.Equal(got, []float64{1000, 3000}) {
+		t.Errorf("expected each run's own throughput, got %v", got)
+	}
+	if r.TotalOps != 4000 {
+		t.Errorf("expected 4000 total ops, got %d", r.TotalOps)
+	}
+}
+
 func TestBenchmarkResultEmpty(t *testing.T) {
 	r := newBenchResult("test", "testdb", 100)
 	r.compute()
@@ -136,3 +174,1798 @@ func TestReportEntry(t *testing.T) {
 		t.Errorf("expected 100%% success, got %.2f", entry.SuccessRate)
 	}
 }
+
+func TestLatencyHistogramPercentiles(t *testing.T) {
+	h := newLatencyHistogram()
+	for i := 1; i <= 10000; i++ {
+		h.Record(time.Duration(i) * time.Microsecond)
+	}
+
+	if h.Count() != 10000 {
+		t.Fatalf("expected 10000 samples, got %d", h.Count())
+	}
+
+	cases := []struct {
+		p    float64
+		want time.Duration
+	}{
+		{0.50, 5000 * time.Microsecond},
+		{0.99, 9900 * time.Microsecond},
+		{0.9999, 9999 * time.Microsecond},
+	}
+	for _, c := range cases {
+		got := h.Percentile(c.p)
+		diff := float64(got-c.want) / float64(c.want)
+		if diff < -0.01 || diff > 0.01 {
+			t.Errorf("p%v: expected ~%v, got %v", c.p*100, c.want, got)
+		}
+	}
+
+	s := h.Summary()
+	if s.Max != 10000*time.Microsecond {
+		t.Errorf("expected max 10ms, got %v", s.Max)
+	}
+}
+
+func TestHistUpperRelativeError(t *testing.T) {
+	bound := 1.0 / histHalfCount
+	for v := uint64(1); v < 1<<40; v = v*17/16 + 1 {
+		up := histUpper(histIndex(v))
+		if up < v {
+			t.Fatalf("histUpper(histIndex(%d)) = %d, below the value", v, up)
+		}
+		if err := float64(up-v) / float64(v); err >= bound {
+			t.Fatalf("value %d reported as %d: relative error %.4f, want < %.4f", v, up, err, bound)
+		}
+	}
+}
+
+func TestLatencyHistogramMerge(t *testing.T) {
+	a := newLatencyHistogram()
+	b := newLatencyHistogram()
+	a.Record(1 * time.Millisecond)
+	b.Record(3 * time.Millisecond)
+	a.Merge(b)
+
+	if a.Count() != 2 {
+		t.Fatalf("expected 2 samples, got %d", a.Count())
+	}
+	if got := a.Summary().Max; got != 3*time.Millisecond {
+		t.Errorf("expected max 3ms, got %v", got)
+	}
+}
+
+func TestBenchmarkResultOpLatency(t *testing.T) {
+	r := newBenchResult("test", "testdb", 3)
+	r.recordOp(1 * time.Millisecond)
+	r.recordOp(2 * time.Millisecond)
+	r.recordOp(30 * time.Millisecond)
+	r.addRun(33*time.Millisecond, 3, 0)
+	r.compute()
+
+	if r.OpLatency.Count != 3 {
+		t.Errorf("expected 3 recorded ops, got %d", r.OpLatency.Count)
+	}
+	if r.OpLatency.Max != 30*time.Millisecond {
+		t.Errorf("expected op max 30ms, got %v", r.OpLatency.Max)
+	}
+	if r.Max != 33*time.Millisecond {
+		t.Errorf("expected per-run max 33ms, got %v", r.Max)
+	}
+}
+
+func TestParseRate(t *testing.T) {
+	cases := map[string]float64{
+		"":        0,
+		"50000":   50000,
+		"50000/s": 50000,
+		"5/ms":    5000,
+		"600/m":   10,
+	}
+	for in, want := range cases {
+		got, err := parseRate(in)
+		if err != nil {
+			t.Errorf("parseRate(%q): unexpected error %v", in, err)
+			continue
+		}
+		if got != want {
+			t.Errorf("parseRate(%q): expected %v, got %v", in, want, got)
+		}
+	}
+	for _, in := range []string{"fast", "NaN", "Inf", "+Inf/s", "-Inf"} {
+		if _, err := parseRate(in); err == nil {
+			t.Errorf("parseRate(%q): expected error for invalid rate", in)
+		}
+	}
+}
+
+func TestRunOpenLoopCorrectsForStalls(t *testing.T) {
+	hist := newLatencyHistogram()
+	// One worker and a single slow call: every request scheduled behind the
+	// stall must report the time spent waiting for its intended send slot.
+	s, f, elapsed := runOpenLoop(context.Background(), fixedRate{rate: 1000, count: 20}, 1, hist, nil, func(ctx context.Context, seq int) error {
+		if seq == 0 {
+			time.Sleep(30 * time.Millisecond)
+		}
+		return nil
+	})
+
+	if s != 20 || f != 0 {
+		t.Fatalf("expected 20 successes, got %d/%d", s, f)
+	}
+	if elapsed < 30*time.Millisecond {
+		t.Errorf("expected elapsed >= 30ms, got %v", elapsed)
+	}
+	if p50 := hist.Percentile(0.50); p50 < 5*time.Millisecond {
+		t.Errorf("expected corrected p50 to include queueing delay, got %v", p50)
+	}
+}
+
+func TestParseRamp(t *testing.T) {
+	r, err := parseRamp("10s:1000->100000ops")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if r.duration != 10*time.Second || r.from != 1000 || r.to != 100000 {
+		t.Errorf("unexpected ramp: %+v", r)
+	}
+
+	for _, bad := range []string{"10s", "x:1->2", "10s:1-2", "10s:0->0"} {
+		if _, err := parseRamp(bad); err == nil {
+			t.Errorf("expected error for %q", bad)
+		}
+	}
+}
+
+func TestRampScheduleIssuesExpectedOps(t *testing.T) {
+	sched := rampRate{from: 100, to: 300, duration: 2 * time.Second}
+	n := 0
+	prev := time.Duration(-1)
+	for {
+		offset, ok := sched.at(n)
+		if !ok {
+			break
+		}
+		if offset < prev {
+			t.Fatalf("schedule went backwards at op %d", n)
+		}
+		prev = offset
+		n++
+	}
+	// Mean rate 200/s over 2s.
+	if n < 395 || n > 405 {
+		t.Errorf("expected ~400 ops, got %d", n)
+	}
+	if got := sched.rateAt(time.Second); got != 200 {
+		t.Errorf("expected rate 200 at midpoint, got %v", got)
+	}
+}
+
+func TestTimelineBucketsBySecond(t *testing.T) {
+	tl := newTimeline(1)
+	start := time.Now()
+	tl.begin(start, func(time.Duration) float64 { return 10 })
+	tl.record(start, time.Millisecond, nil)
+	tl.record(start.Add(1500*time.Millisecond), 2*time.Millisecond, nil)
+	tl.record(start.Add(1600*time.Millisecond), 4*time.Millisecond, errors.New("boom"))
+
+	points := tl.points()
+	if len(points) != 2 {
+		t.Fatalf("expected 2 seconds, got %d", len(points))
+	}
+	if points[1].Ops != 2 || points[1].Errors != 1 {
+		t.Errorf("unexpected second bucket: %+v", points[1])
+	}
+	if points[1].Max != 4*time.Millisecond || points[0].TargetRate != 10 {
+		t.Errorf("unexpected bucket stats: %+v", points)
+	}
+
+	var nilTimeline *timeline
+	nilTimeline.record(start, time.Millisecond, nil)
+	if nilTimeline.points() != nil {
+		t.Error("expected nil timeline to record nothing")
+	}
+}
+
+// memDriver is an in-memory Writer/Reader/MultiReader/RangeReader/
+// AggregateReader/PointReader
+// used to exercise runners without a live database.
+type memDriver struct {
+	mu     sync.Mutex
+	points map[string][]KeyedPoint
+}
+
+func newMemDriver() *memDriver { return &memDriver{points: make(map[string][]KeyedPoint)} }
+
+func (d *memDriver) Name() string                      { return "mem" }
+func (d *memDriver) Connect(ctx context.Context) error { return nil }
+func (d *memDriver) Close() error                      { return nil }
+
+func (d *memDriver) Write(ctx context.Context, key string, value float64) error {
+	return d.WriteBatch(ctx, []KeyedPoint{{Key: key, Value: value, Timestamp: time.Now().Unix()}})
+}
+
+func (d *memDriver) WriteBatch(ctx context.Context, points []KeyedPoint) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, p := range points {
+		d.points[p.Key] = append(d.points[p.Key], p)
+	}
+	return nil
+}
+
+func (d *memDriver) Read(ctx context.Context, key string, lastX int) (int, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return min(len(d.points[key]), lastX), nil
+}
+
+func (d *memDriver) ReadRange(ctx context.Context, key string, start, end int64) (int, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	n := 0
+	for _, p := range d.points[key] {
+		if p.Timestamp >= start && p.Timestamp <= end {
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (d *memDriver) ReadAggregate(ctx context.Context, key string, start, end, bucket int64, agg string) (int, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	buckets := make(map[int64]bool)
+	for _, p := range d.points[key] {
+		if p.Timestamp >= start && p.Timestamp <= end {
+			buckets[(p.Timestamp-start)/bucket] = true
+		}
+	}
+	return len(buckets), nil
+}
+
+func (d *memDriver) MultiRead(ctx context.Context, keys []string, lastX int) (map[string]int, error) {
+	counts := make(map[string]int, len(keys))
+	for _, k := range keys {
+		counts[k], _ = d.Read(ctx, k, lastX)
+	}
+	return counts, nil
+}
+
+func (d *memDriver) ReadPoints(ctx context.Context, key string, lastX int) ([]KeyedPoint, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	pts := d.points[key]
+	return append([]KeyedPoint(nil), pts[max(0, len(pts)-lastX):]...), nil
+}
+
+func writeTempFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadScenarioYAMLAndJSON(t *testing.T) {
+	yamlPath := writeTempFile(t, "mixed.yaml", `
+keys: 20
+read_ratio: 0.5
+batch_size: 10
+read:
+  lastx: 5
+  multi_keys: 3
+concurrency: 2
+duration: 1s
+`)
+	sc, err := loadScenario(yamlPath)
+	if err != nil {
+		t.Fatalf("yaml: %v", err)
+	}
+	if sc.Name != "mixed" || sc.Keys != 20 || sc.Read.MultiKeys != 3 || sc.Duration != time.Second {
+		t.Errorf("unexpected yaml scenario: %+v", sc)
+	}
+	if sc.KeyPattern != "scenario_sensor_%d" {
+		t.Errorf("expected default key pattern, got %q", sc.KeyPattern)
+	}
+
+	jsonPath := writeTempFile(t, "range.json", `{"name":"r","keys":5,"read_ratio":1,"read":{"range":"10m"},"duration":"2s"}`)
+	sc, err = loadScenario(jsonPath)
+	if err != nil {
+		t.Fatalf("json: %v", err)
+	}
+	if sc.Read.Range != 10*time.Minute || sc.ReadRatio != 1 {
+		t.Errorf("unexpected json scenario: %+v", sc)
+	}
+
+	badPath := writeTempFile(t, "bad.yaml", "keys: 5\nread_ratio: 0.5\nduration: 1s\n")
+	if _, err := loadScenario(badPath); err == nil {
+		t.Error("expected error for reads without lastx or range")
+	}
+}
+
+func TestRunScenarioMixedWorkload(t *testing.T) {
+	sc := &Scenario{
+		Name:        "t",
+		Keys:        10,
+		KeyPattern:  "k%d",
+		Preload:     5,
+		ReadRatio:   0.5,
+		BatchSize:   4,
+		Read:        ScenarioRead{LastX: 3, MultiKeys: 2},
+		Concurrency: 2,
+		Duration:    50 * time.Millisecond,
+		Seed:        7,
+	}
+	d := newMemDriver()
+	results, err := runScenario(sc, d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("expected write and read results, got %d", len(results))
+	}
+	for _, r := range results {
+		if r.OperationCount == 0 || r.SuccessRate() != 100 {
+			t.Errorf("%s: unexpected result ops=%d success=%.1f", r.Name, r.OperationCount, r.SuccessRate())
+		}
+	}
+	if got := len(d.points["k0"]); got < 5 {
+		t.Errorf("expected preload of 5 points, got %d", got)
+	}
+}
+
+func TestScenarioBatchWriteStampsPointsDistinctly(t *testing.T) {
+	sc := &Scenario{Keys: 1, KeyPattern: "k%d", BatchSize: 4}
+	d := newMemDriver()
+	if _, err := sc.write(context.Background(), d, rand.New(rand.NewPCG(1, 2))); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	seen := make(map[int64]bool)
+	for _, p := range d.points["k0"] {
+		seen[p.Timestamp] = true
+	}
+	if len(seen) != 4 {
+		t.Errorf("expected 4 distinct timestamps, got %+v", d.points["k0"])
+	}
+}
+
+func TestScenarioRejectsMissingCapability(t *testing.T) {
+	sc := &Scenario{Keys: 1, KeyPattern: "k%d", ReadRatio: 1, Read: ScenarioRead{Range: time.Minute}, BatchSize: 1, Concurrency: 1, Duration: time.Second}
+	if _, err := runScenario(sc, newNSQDriver("localhost:0")); err == nil {
+		t.Error("expected error for driver without range reads")
+	}
+}
+
+func init() {
+	registerDriver(&driverSpec{
+		Name:     "mem",
+		New:      func(cfg *Config) Driver { return newMemDriver() },
+		MaxBatch: 7,
+	})
+}
+
+func TestRegistryListsBuiltInDrivers(t *testing.T) {
+	names := driverNames()
+	for _, want := range []string{"gtsdb", "gtsdb-http", "influx", "nsq", "vm"} {
+		if !contains(names, want) {
+			t.Errorf("expected driver %q to be registered, got %v", want, names)
+		}
+	}
+}
+
+func TestRunBenchmarksSkipsUnsupported(t *testing.T) {
+	cfg := &Config{
+		Count:      20,
+		Sensors:    2,
+		Runs:       1,
+		Databases:  []string{"mem"},
+		Benchmarks: []string{"Write (seq)", "Multi-Key Write", "Pub/Sub"},
+	}
+	results := runBenchmarks(cfg)
+	if len(results) != 2 {
+		t.Fatalf("expected 2 results (no Pub/Sub support), got %d", len(results))
+	}
+	if results[0].Name != "Write (seq)" || results[1].Name != "Multi-Key Write" {
+		t.Errorf("unexpected order: %s, %s", results[0].Name, results[1].Name)
+	}
+	// 20 points in calls of at most MaxBatch=7.
+	if got := results[1].OpLatency.Count; got != 3 {
+		t.Errorf("expected 3 WriteBatch calls, got %d", got)
+	}
+}
+
+func TestConfigValidateDriverSpecific(t *testing.T) {
+	cfg := &Config{Count: 1, Runs: 1, Databases: []string{"gtsdb"}, Benchmarks: []string{"all"}, GTSDBConns: 1, GTSDBPipeline: 1}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("expected gtsdb without influx token to validate, got %v", err)
+	}
+	cfg.GTSDBConns = 0
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected gtsdb with an empty pool to fail validation")
+	}
+
+	cfg.GTSDBConns = 1
+	cfg.Verify, cfg.Sensors, cfg.VerifyPoints = true, 1, verifyMaxPoints+1
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected -verify-points beyond the read window to fail validation")
+	}
+	cfg.Verify = false
+
+	cfg.Databases = []string{"influx"}
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected influx without token to fail validation")
+	}
+
+	cfg.Databases = []string{"nope"}
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected unknown database to fail validation")
+	}
+}
+
+func TestVMMultiWriteCountsRejectedImport(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "cannot parse json", http.StatusBadRequest)
+	}))
+	defer srv.Close()
+
+	s, f, _ := newVMDriver(srv.URL).multiWrite(10, 3, 0)
+	if s != 0 || f != 30 {
+		t.Errorf("expected 0 successes and 30 failures, got %d/%d", s, f)
+	}
+}
+
+func TestBatchWriteCountsPartialFailures(t *testing.T) {
+	w := &partialWriter{memDriver: newMemDriver(), failed: 3}
+	r := runBatchWrite(w, "k", 10, 1, 1)
+	if r.successCount != 7 || r.failureCount != 3 {
+		t.Errorf("expected 7/3, got %d/%d", r.successCount, r.failureCount)
+	}
+}
+
+// partialWriter rejects a fixed number of points from every batch.
+type partialWriter struct {
+	*memDriver
+	failed int
+}
+
+func (p *partialWriter) WriteBatch(ctx context.Context, points []KeyedPoint) error {
+	return &partialWriteError{Failed: p.failed, Total: len(points), Err: errors.New("rejected")}
+}
+
+// flakyFlusher rejects every other batch write and counts its flushes.
+type flakyFlusher struct {
+	*memDriver
+	writes, flushes int
+}
+
+func (f *flakyFlusher) WriteBatch(ctx context.Context, points []KeyedPoint) error {
+	f.writes++
+	if f.writes%2 == 0 {
+		return errors.New("rejected")
+	}
+	return f.memDriver.WriteBatch(ctx, points)
+}
+
+func (f *flakyFlusher) Flush(ctx context.Context) error {
+	f.flushes++
+	return nil
+}
+
+func TestRunFlushCountsFailedWritesAsFailures(t *testing.T) {
+	f := &flakyFlusher{memDriver: newMemDriver()}
+	r := runFlush(f, f, 0, 1)
+	if f.flushes != flushesPerRun/2 || r.OpLatency.Count != uint64(flushesPerRun/2) {
+		t.Errorf("expected %d timed flushes, got %d flushes and %d timings", flushesPerRun/2, f.flushes, r.OpLatency.Count)
+	}
+	if r.SuccessRate() != 50 {
+		t.Errorf("expected half the flushes to fail, got %.1f%% success", r.SuccessRate())
+	}
+}
+
+func TestCompareKeyClassifiesErrors(t *testing.T) {
+	want := []KeyedPoint{{Timestamp: 1, Value: 1}, {Timestamp: 2, Value: 2}, {Timestamp: 3, Value: 3}, {Timestamp: 4, Value: 4}}
+	got := []KeyedPoint{{Timestamp: 1, Value: 1}, {Timestamp: 3, Value: 3.5}, {Timestamp: 2, Value: 2}, {Timestamp: 2, Value: 2}, {Timestamp: 9, Value: 9}}
+	v := compareKey("k", "read", want, got)
+	if v.Missing != 1 || v.Duplicated != 1 || v.Reordered != 1 || v.Mismatched != 2 {
+		t.Errorf("unexpected classification: %+v", v)
+	}
+	if v.ok() {
+		t.Error("expected key to fail verification")
+	}
+	if !compareKey("k", "read", want, want).ok() {
+		t.Error("expected identical points to verify")
+	}
+}
+
+// lossyDriver drops every fifth point it is asked to store.
+type lossyDriver struct {
+	*memDriver
+}
+
+func (d *lossyDriver) Name() string { return "lossy" }
+
+func (d *lossyDriver) WriteBatch(ctx context.Context, points []KeyedPoint) error {
+	var kept []KeyedPoint
+	for i, p := range points {
+		if i%5 != 0 {
+			kept = append(kept, p)
+		}
+	}
+	return d.memDriver.WriteBatch(ctx, kept)
+}
+
+func TestVerifyFlagsLossyDriver(t *testing.T) {
+	good := runVerify(newMemDriver(), 0, 1, 3, 50, 0)
+	if good.failed() || good.summary() != "ok" || len(good.Keys) != 3 {
+		t.Errorf("expected mem driver to verify, got %s (%d keys)", good.summary(), len(good.Keys))
+	}
+
+	bad := runVerify(&lossyDriver{newMemDriver()}, 0, 1, 3, 50, 0)
+	if !bad.failed() {
+		t.Fatal("expected lossy driver to fail verification")
+	}
+	if got := bad.summary(); got != "failed: 30 missing" {
+		t.Errorf("unexpected summary %q", got)
+	}
+
+	results := []*BenchmarkResult{newBenchResult("Write (seq)", "lossy", 1), newBenchResult("Write (seq)", "mem", 1)}
+	applyVerification(results, []*verifyReport{good, bad})
+	if !results[0].VerifyFailed || results[1].VerifyFailed {
+		t.Errorf("expected only lossy to be flagged: %+v, %+v", results[0].Verification, results[1].Verification)
+	}
+}
+
+func TestVerifyDatasetIsDeterministic(t *testing.T) {
+	a := verifyDataset(7, 2, 10, 1700000000)
+	b := verifyDataset(7, 2, 10, 1700000000)
+	for i := range a {
+		if a[i] != b[i] {
+			t.Fatalf("point %d differs: %+v vs %+v", i, a[i], b[i])
+		}
+	}
+	if a[0].Key == a[10].Key {
+		t.Error("expected distinct keys")
+	}
+}
+
+func TestParseConcurrency(t *testing.T) {
+	levels, err := parseConcurrency("8, 1,2,8,4")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := []int{1, 2, 4, 8}; !slices.Equal(levels, want) {
+		t.Errorf("expected %v, got %v", want, levels)
+	}
+	if levels, err := parseConcurrency(""); err != nil || levels != nil {
+		t.Errorf("expected no sweep, got %v, %v", levels, err)
+	}
+	for _, bad := range []string{"0", "-2", "four", "1,x"} {
+		if _, err := parseConcurrency(bad); err == nil {
+			t.Errorf("%q: expected error", bad)
+		}
+	}
+}
+
+func TestRangeReadBenchmarksSharePreload(t *testing.T) {
+	d := newMemDriver()
+	caps := capsOf(&Config{}, &driverSpec{MaxBatch: 500}, d)
+	cfg := &Config{Runs: 1, Workers: 4}
+	for _, b := range benchmarks {
+		if b.Name != "Range Read (short)" && b.Name != "Range Read (long)" && b.Name != "Downsampled Read" {
+			continue
+		}
+		if !b.Supports(caps) {
+			t.Fatalf("%s: expected memDriver to be supported", b.Name)
+		}
+		r := b.Run(cfg, d, caps)
+		if r.SuccessRate() != 100 || r.OperationCount != 4 {
+			t.Errorf("%s: expected 4 non-empty reads per run, got %d at %.1f%%", r.Name, r.OperationCount, r.SuccessRate())
+		}
+	}
+	data := rangePreloads[d]
+	if len(d.points) != 1 || len(d.points[data.key]) != rangeSpan {
+		t.Errorf("expected one preload of %d points, got %d keys", rangeSpan, len(d.points))
+	}
+	if n, _ := d.ReadAggregate(context.Background(), data.key, data.end-rangeSpan+1, data.end, downsampleBucket, "avg"); n != rangeSpan/downsampleBucket {
+		t.Errorf("expected %d buckets, got %d", rangeSpan/downsampleBucket, n)
+	}
+
+	failing := &partialWriter{memDriver: newMemDriver(), failed: 1}
+	if r := rangeBenchmark("Range Read (short)", shortRangeWindow, 0)(cfg, failing, caps); r != nil {
+		t.Errorf("expected a failed preload to skip the benchmark, got %.1f%% success", r.SuccessRate())
+	}
+}
+
+func TestVMRangeAndAggregateQueries(t *testing.T) {
+	var imported string
+	var query url.Values
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v1/import":
+			body, _ := io.ReadAll(r.Body)
+			imported = string(body)
+		case "/api/v1/query_range":
+			query = r.URL.Query()
+			fmt.Fprint(w, `{"status":"success","data":{"resultType":"matrix","result":[{"metric":{},"values":[[1,"1"],[2,"2"],[3,"3"]]}]}}`)
+		}
+	}))
+	defer srv.Close()
+	d := newVMDriver(srv.URL)
+	ctx := context.Background()
+
+	if err := d.WriteBatch(ctx, []KeyedPoint{{Key: "k", Value: 1, Timestamp: 1700000000}}); err != nil {
+		t.Fatalf("write batch: %v", err)
+	}
+	if !strings.Contains(imported, `"timestamps":[1700000000000]`) {
+		t.Errorf("expected millisecond timestamps, got %s", imported)
+	}
+
+	n, err := d.ReadAggregate(ctx, "k", 1700000000, 1700003599, 60, "max")
+	if err != nil || n != 3 {
+		t.Fatalf("expected 3 buckets, got %d, %v", n, err)
+	}
+	if got := query.Get("query"); got != `max_over_time(benchmark_value{key="k"}[60s])` {
+		t.Errorf("unexpected query %s", got)
+	}
+	if query.Get("step") != "60" || query.Get("start") != "1700000000" {
+		t.Errorf("unexpected range %v", query)
+	}
+}
+
+func TestVMReadReportsFailedQueries(t *testing.T) {
+	status := http.StatusOK
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+		fmt.Fprint(w, `{"status":"error","errorType":"bad_data","error":"query too heavy"}`)
+	}))
+	defer srv.Close()
+	d := newVMDriver(srv.URL)
+
+	for _, code := range []int{http.StatusOK, http.StatusUnprocessableEntity} {
+		status = code
+		_, err := d.ReadRange(context.Background(), "k", 1700000000, 1700000060)
+		if err == nil || !strings.Contains(err.Error(), "query too heavy") {
+			t.Errorf("status %d: expected the query error, got %v", code, err)
+		}
+	}
+}
+
+func TestVMAdminOperations(t *testing.T) {
+	var mu sync.Mutex
+	requests := make(map[string]url.Values)
+	var csv string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests[r.Method+" "+r.URL.Path] = r.URL.Query()
+		mu.Unlock()
+		switch r.URL.Path {
+		case "/api/v1/label/key/values":
+			fmt.Fprint(w, `{"status":"success","data":["a","b","c"]}`)
+		case "/api/v1/export":
+			fmt.Fprint(w, `{"metric":{},"values":[1,2],"timestamps":[1000,2000]}`+"\n"+`{"metric":{},"values":[3],"timestamps":[3000]}`+"\n")
+		case "/api/v1/import/csv":
+			body, _ := io.ReadAll(r.Body)
+			csv = string(body)
+		case "/api/v1/admin/tsdb/delete_series", "/internal/force_flush":
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	d := newVMDriver(srv.URL)
+	ctx := context.Background()
+
+	if n, err := d.ListKeys(ctx); err != nil || n != 3 {
+		t.Errorf("list keys: expected 3, got %d, %v", n, err)
+	}
+	if n, err := d.Export(ctx, "k"); err != nil || n != 3 {
+		t.Errorf("export: expected 3 samples, got %d, %v", n, err)
+	}
+	if err := d.DeleteKey(ctx, "k"); err != nil {
+		t.Errorf("delete: %v", err)
+	}
+	if err := d.Flush(ctx); err != nil {
+		t.Errorf("flush: %v", err)
+	}
+	if err := d.BulkLoad(ctx, "k", []KeyedPoint{{Key: "k", Value: 1.5, Timestamp: 1700000000}}); err != nil {
+		t.Errorf("bulk load: %v", err)
+	}
+
+	if got := requests["POST /api/v1/admin/tsdb/delete_series"].Get("match[]"); got != `benchmark_value{key="k"}` {
+		t.Errorf("delete: unexpected selector %q", got)
+	}
+	if q := requests["POST /api/v1/import/csv"]; q.Get("extra_label") != "key=k" || csv != "1700000000,1.500000\n" {
+		t.Errorf("bulk load: unexpected request %v with body %q", q, csv)
+	}
+	if _, err := d.call(ctx, "GET", "/missing", nil, nil); err == nil {
+		t.Error("expected error for a 404")
+	}
+}
+
+func TestParseCardinality(t *testing.T) {
+	levels, err := parseCardinality("1M, 10k,500,10000")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := []int{500, 10000, 1000000}; !slices.Equal(levels, want) {
+		t.Errorf("expected %v, got %v", want, levels)
+	}
+	for _, bad := range []string{"0", "k", "-1k", "1G", "10,x"} {
+		if _, err := parseCardinality(bad); err == nil {
+			t.Errorf("%q: expected error", bad)
+		}
+	}
+}
+
+func TestParseKeyDist(t *testing.T) {
+	for in, want := range map[string]string{
+		"":             "uniform",
+		"uniform":      "uniform",
+		"zipf":         "zipf:1.1",
+		"zipf:1.5":     "zipf:1.5",
+		"hot":          "hot:0.01:0.9",
+		"hot:0.05:0.8": "hot:0.05:0.8",
+	} {
+		d, err := parseKeyDist(in)
+		if err != nil || d.String() != want {
+			t.Errorf("%q: expected %s, got %s, %v", in, want, d, err)
+		}
+	}
+	for _, bad := range []string{"normal", "zipf:1", "zipf:x", "hot:0.5", "hot:0:0.5", "hot:0.1:2", "uniform:3"} {
+		if _, err := parseKeyDist(bad); err == nil {
+			t.Errorf("%q: expected error", bad)
+		}
+	}
+}
+
+func TestKeyDistSamplers(t *testing.T) {
+	const n, draws = 1000, 20000
+	rng := rand.New(rand.NewPCG(1, 2))
+	share := func(d keyDist, hot int) float64 {
+		sample := d.sampler(n, rng)
+		var hits int
+		for i := 0; i < draws; i++ {
+			k := sample()
+			if k < 0 || k >= n {
+				t.Fatalf("%s: key %d out of range", d, k)
+			}
+			if k < hot {
+				hits++
+			}
+		}
+		return float64(hits) / draws
+	}
+
+	if got := share(keyDist{Kind: "uniform"}, n/10); got < 0.08 || got > 0.12 {
+		t.Errorf("uniform: expected ~10%% of draws in the first 10%% of keys, got %.1f%%", got*100)
+	}
+	if got := share(keyDist{Kind: "hot", HotKeys: 0.01, HotShare: 0.9}, n/100); got < 0.88 || got > 0.92 {
+		t.Errorf("hot: expected ~90%% of draws in the hot set, got %.1f%%", got*100)
+	}
+	if got := share(keyDist{Kind: "zipf", S: 1.5}, n/100); got < 0.6 {
+		t.Errorf("zipf: expected most draws on the hottest keys, got %.1f%%", got*100)
+	}
+}
+
+// memReporter is a memDriver whose server memory grows with its keys.
+type memReporter struct {
+	*memDriver
+}
+
+func (d memReporter) ServerMemory(ctx context.Context) (uint64, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return uint64(len(d.points)) << 10, nil
+}
+
+func TestRunCardinalityGrowsKeyspace(t *testing.T) {
+	d := memReporter{newMemDriver()}
+	cfg := &Config{Count: 300, Runs: 1, Cardinality: []int{50, 200}, KeyDist: keyDist{Kind: "zipf", S: 1.2}}
+	results := runCardinality(cfg, d, 7, "hc_")
+
+	var names []string
+	for _, r := range results {
+		names = append(names, fmt.Sprintf("%s@%d", r.Name, r.Cardinality))
+		if r.SuccessRate() != 100 {
+			t.Errorf("%s at %d keys: success %.1f%%", r.Name, r.Cardinality, r.SuccessRate())
+		}
+	}
+	want := []string{
+		"Key Creation@50", "High-Card Write@50", "High-Card Read@50",
+		"Key Creation@200", "High-Card Write@200", "High-Card Read@200",
+	}
+	if !slices.Equal(names, want) {
+		t.Fatalf("expected %v, got %v", want, names)
+	}
+	if results[3].OperationCount != 150 {
+		t.Errorf("expected the second level to create only its 150 new keys, got %d", results[3].OperationCount)
+	}
+	if got := len(d.points); got != 200 {
+		t.Errorf("expected writes and reads to stay within 200 keys, got %d", got)
+	}
+	if results[0].ServerMemory != 50<<10 || results[5].ServerMemory != 200<<10 {
+		t.Errorf("expected server memory per level, got %d and %d", results[0].ServerMemory, results[5].ServerMemory)
+	}
+	if results[0].KeyDist != "" || results[1].KeyDist != "zipf:1.2" {
+		t.Errorf("expected key distribution on writes and reads only, got %q and %q", results[0].KeyDist, results[1].KeyDist)
+	}
+
+	rows := cardinalityRows(results)
+	if len(rows) != 2 || rows[1].Keys != 200 || len(rows[1].Results) != 3 {
+		t.Errorf("expected 2 rows of 3 results, got %+v", rows)
+	}
+}
+
+func TestScrapeResidentMemory(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/metrics" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, "# TYPE process_resident_memory_bytes gauge\nprocess_resident_memory_bytes_total 1\nprocess_resident_memory_bytes 1.234567e+08\n")
+	}))
+	defer srv.Close()
+
+	mem, err := newVMDriver(srv.URL).ServerMemory(context.Background())
+	if err != nil || mem != 123456700 {
+		t.Errorf("expected 123456700 bytes, got %d, %v", mem, err)
+	}
+	if _, err := scrapeResidentMemory(context.Background(), http.DefaultClient, srv.URL+"/missing"); err == nil {
+		t.Error("expected error for missing metrics endpoint")
+	}
+}
+
+func TestSplitWorkCoversEveryItemOnce(t *testing.T) {
+	for _, tc := range []struct{ workers, n int }{{1, 10}, {3, 10}, {16, 5}, {4, 0}} {
+		var mu sync.Mutex
+		seen := make([]int, tc.n)
+		calls := 0
+		splitWork(tc.workers, tc.n, func(lo, hi int) {
+			mu.Lock()
+			defer mu.Unlock()
+			calls++
+			for i := lo; i < hi; i++ {
+				seen[i]++
+			}
+		})
+		for i, n := range seen {
+			if n != 1 {
+				t.Errorf("workers=%d n=%d: item %d handled %d times", tc.workers, tc.n, i, n)
+			}
+		}
+		if want := max(1, min(tc.workers, tc.n)); calls != want {
+			t.Errorf("workers=%d n=%d: expected %d calls, got %d", tc.workers, tc.n, want, calls)
+		}
+	}
+}
+
+func TestRunBenchmarksConcurrencySweep(t *testing.T) {
+	cfg := &Config{
+		Count:       40,
+		Sensors:     2,
+		Runs:        1,
+		Databases:   []string{"mem"},
+		Benchmarks:  []string{"Write (seq)", "Read (single)", "Multi-Key Write"},
+		Concurrency: []int{1, 4},
+	}
+	results := runBenchmarks(cfg)
+	if len(results) != 6 {
+		t.Fatalf("expected 3 benchmarks at 2 levels, got %d results", len(results))
+	}
+	for i, r := range results {
+		if want := cfg.Concurrency[i%2]; r.Workers != want {
+			t.Errorf("%s: expected %d workers, got %d", r.Name, want, r.Workers)
+		}
+		if r.SuccessRate() != 100 {
+			t.Errorf("%s at %d workers: success %.1f%%", r.Name, r.Workers, r.SuccessRate())
+		}
+	}
+	if got := results[1].OpLatency.Count; got != 40 {
+		t.Errorf("expected 40 writes across 4 workers, got %d", got)
+	}
+	if got := results[3].OperationCount; got != 4 {
+		t.Errorf("expected one read per worker, got %d", got)
+	}
+
+	if curves := scalabilityCurves(results); len(curves) != 3 || len(curves[0].Points) != 2 {
+		t.Errorf("expected 3 curves of 2 points, got %d", len(curves))
+	}
+}
+
+func TestScalabilityCurvesFindKnee(t *testing.T) {
+	var results []*BenchmarkResult
+	for _, p := range []struct {
+		workers int
+		ops     float64
+	}{{1, 1000}, {2, 1900}, {4, 3500}, {8, 3800}, {16, 3900}} {
+		r := newBenchResult("Write (seq)", "db", 1)
+		r.Workers, r.OpsPerSec = p.workers, p.ops
+		results = append(results, r)
+	}
+	results = append(results, newBenchResult("Pub/Sub", "db", 1))
+
+	curves := scalabilityCurves(results)
+	if len(curves) != 1 {
+		t.Fatalf("expected 1 curve, got %d", len(curves))
+	}
+	// 90% of the 3900 peak is 3510: 4 workers fall just short.
+	if curves[0].Knee != 8 {
+		t.Errorf("expected knee at 8 workers, got %d", curves[0].Knee)
+	}
+}
+
+func TestWelchTTest(t *testing.T) {
+	tt, df, p := welchTTest([]float64{10, 11, 12, 13, 14}, []float64{14, 15, 16, 17, 18})
+	if math.Abs(tt+4) > 1e-9 || math.Abs(df-8) > 1e-9 {
+		t.Errorf("expected t=-4 df=8, got t=%g df=%g", tt, df)
+	}
+	if math.Abs(p-0.0039498) > 1e-6 {
+		t.Errorf("expected p=0.0039498, got %g", p)
+	}
+	// Unequal variances give fractional degrees of freedom.
+	if _, df, p := welchTTest([]float64{1, 2, 3}, []float64{1, 5, 9, 13}); math.Abs(df-3.2951) > 1e-4 || math.Abs(p-0.146888) > 1e-5 {
+		t.Errorf("expected df=3.2951 p=0.146888, got df=%g p=%g", df, p)
+	}
+	if _, _, p := welchTTest([]float64{1}, []float64{1, 2}); !math.IsNaN(p) {
+		t.Errorf("expected NaN p for a single-run sample, got %g", p)
+	}
+}
+
+func TestSaveRunRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	r := newBenchResult("Write (seq)", "GTSDB", 1000)
+	r.addRun(100*time.Millisecond, 1000, 0)
+	r.addRun(200*time.Millisecond, 1000, 0)
+	r.compute()
+	cfg := &Config{
+		Databases:   []string{"gtsdb", "influx"},
+		Benchmarks:  []string{"all"},
+		GTSDBCommit: "abc123",
+		Flags:       map[string]string{"runs": "2"},
+	}
+	start := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+
+	first, err := saveRun(dir, newRunRecord(cfg, []*BenchmarkResult{r}, start))
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := saveRun(dir, newRunRecord(cfg, []*BenchmarkResult{r}, start))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filepath.Base(first) != "20261018-120000.json" || filepath.Base(second) != "20261018-120000-2.json" {
+		t.Errorf("unexpected run files %s, %s", first, second)
+	}
+
+	rec, err := resolveRun(dir, "latest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rec.ID != "20261018-120000-2" || rec.Meta.GTSDBCommit != "abc123" || rec.Meta.Flags["runs"] != "2" {
+		t.Errorf("unexpected metadata: %+v", rec)
+	}
+	if rec.Meta.Drivers["gtsdb"] != "in-tree" || !strings.HasPrefix(rec.Meta.Drivers["influx"], "github.com/influxdata/influxdb-client-go/v2") {
+		t.Errorf("unexpected driver versions: %v", rec.Meta.Drivers)
+	}
+	if len(rec.Results) != 1 || !slices.Equal(rec.Results[0].RunOpsPerSec, []float64{10000, 5000}) {
+		t.Errorf("unexpected results: %+v", rec.Results)
+	}
+
+	if prev, err := previousRun(dir, rec.ID); err != nil || prev != "20261018-120000" {
+		t.Errorf("expected the first run before the second, got %q (%v)", prev, err)
+	}
+	if _, err := resolveRun(dir, "baseline"); err == nil {
+		t.Error("expected an error without a baseline")
+	}
+	if err := writeBaseline(dir, "20261018-120000"); err != nil {
+		t.Fatal(err)
+	}
+	if rec, err := resolveRun(dir, "baseline"); err != nil || rec.ID != "20261018-120000" {
+		t.Errorf("expected the baseline run, got %q (%v)", rec.ID, err)
+	}
+}
+
+func TestSetFlagsRedactsTokens(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.String("influx-token", "", "")
+	fs.Int("runs", 3, "")
+	fs.Int("count", 10, "")
+	if err := fs.Parse([]string{"-influx-token=secret", "-runs=5"}); err != nil {
+		t.Fatal(err)
+	}
+	flags := setFlags(fs)
+	if len(flags) != 2 || flags["runs"] != "5" || flags["influx-token"] != "<redacted>" {
+		t.Errorf("unexpected flags: %v", flags)
+	}
+}
+
+func TestCompareRunsFlagsRegressions(t *testing.T) {
+	entry := func(name, driver string, workers int, runs ...float64) reportEntry {
+		mean, _ := meanVar(runs)
+		return reportEntry{Name: name, Driver: driver, Workers: workers, OpsPerSec: mean, RunOpsPerSec: runs}
+	}
+	old := runRecord{ID: "old", Results: []reportEntry{
+		entry("Write (seq)", "GTSDB", 0, 1000, 1010, 990, 1005),
+		entry("Write (seq)", "VM", 0, 1000, 1010, 990, 1005),
+		entry("Read (single)", "GTSDB", 0, 500, 800, 300, 600),
+		entry("Write (seq)", "GTSDB", 4, 3000, 3010, 2990),
+		entry("Pub/Sub", "NSQ", 0, 10),
+	}}
+	cur := runRecord{ID: "new", Results: []reportEntry{
+		entry("Write (seq)", "GTSDB", 0, 800, 810, 790, 805),
+		entry("Write (seq)", "VM", 0, 1200, 1210, 1190, 1205),
+		entry("Read (single)", "GTSDB", 0, 400, 900, 250, 500),
+		entry("Write (seq)", "GTSDB", 4, 3000, 3020, 2980),
+		entry("Pub/Sub", "NSQ", 0, 9),
+		entry("Flush", "GTSDB", 0, 50, 51),
+	}}
+
+	want := map[string]string{
+		"Write (seq)/GTSDB":             verdictRegression,
+		"Write (seq)/VM":                verdictImprovement,
+		"Read (single)/GTSDB":           verdictNoChange,
+		"Write (seq) [4 workers]/GTSDB": verdictNoChange,
+		"Pub/Sub/NSQ":                   verdictUntested,
+		"Flush/GTSDB":                   verdictAdded,
+	}
+	diffs := compareRuns(old, cur, 0.05, 0.05)
+	if len(diffs) != len(want) {
+		t.Fatalf("expected %d diffs, got %d", len(want), len(diffs))
+	}
+	for _, d := range diffs {
+		if got := want[d.Label+"/"+d.Driver]; d.Verdict != got {
+			t.Errorf("%s/%s: expected %q, got %q (change %+.2f, p %.3g)", d.Label, d.Driver, got, d.Verdict, d.Change, d.P)
+		}
+	}
+
+	var buf strings.Builder
+	printDiffs(&buf, old, cur, diffs)
+	if !strings.Contains(buf.String(), "-20.0%") {
+		t.Errorf("expected the regression's change in the output:\n%s", buf.String())
+	}
+
+	// The ops/s columns show the per-run mean the change is computed from,
+	// not the pooled aggregate.
+	cur.Results[0].OpsPerSec = 700
+	buf.Reset()
+	printDiffs(&buf, old, cur, compareRuns(old, cur, 0.05, 0.05))
+	if !regexp.MustCompile(`1001\s+801\s+-20\.0%`).MatchString(buf.String()) {
+		t.Errorf("expected per-run mean throughputs in the output:\n%s", buf.String())
+	}
+}
+
+func TestStudentTQuantile(t *testing.T) {
+	for _, tc := range []struct{ level, df, want float64 }{
+		{0.95, 2, 4.302653},
+		{0.95, 8, 2.306004},
+		{0.99, 30, 2.749996},
+	} {
+		if got := studentTQuantile(tc.level, tc.df); math.Abs(got-tc.want) > 1e-5 {
+			t.Errorf("t(%g, df=%g): expected %g, got %g", tc.level, tc.df, tc.want, got)
+		}
+	}
+}
+
+func TestComputeConfidenceIntervals(t *testing.T) {
+	r := newBenchResult("test", "testdb", 100)
+	for run, d := range []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 150 * time.Millisecond} {
+		for i := range 100 {
+			r.recordOp(time.Duration(run+1)*time.Millisecond + time.Duration(i)*10*time.Microsecond)
+		}
+		r.addRun(d, 100, 0)
+	}
+	r.compute()
+
+	// 150ms ± 4.3027 * 50ms/sqrt(3).
+	if lo, hi := r.MeanCI[0], r.MeanCI[1]; (lo-25789*time.Microsecond).Abs() > 10*time.Microsecond || (hi-274211*time.Microsecond).Abs() > 10*time.Microsecond {
+		t.Errorf("unexpected mean CI %v-%v", lo, hi)
+	}
+	if lo, hi := r.OpsPerSecCI[0], r.OpsPerSecCI[1]; lo <= 0 || lo >= r.OpsPerSec || hi <= r.OpsPerSec {
+		t.Errorf("expected the throughput CI around %g, got %g-%g", r.OpsPerSec, lo, hi)
+	}
+	for name, ci := range map[string][2]time.Duration{"P50": r.OpP50CI, "P99": r.OpP99CI} {
+		want := map[string]time.Duration{"P50": r.OpLatency.P50, "P99": r.OpLatency.P99}[name]
+		if ci[0] < time.Millisecond || ci[0] > want || ci[1] < want || ci[1] > 4*time.Millisecond {
+			t.Errorf("expected the op %s CI around %v, got %v-%v", name, want, ci[0], ci[1])
+		}
+	}
+	if e := newReportEntry(r); len(e.OpsPerSecCI) != 2 || len(e.MeanCI) != 2 || len(e.OpLatency.P99CI) != 2 {
+		t.Errorf("expected CIs in the report entry, got %v %v %v", e.OpsPerSecCI, e.MeanCI, e.OpLatency.P99CI)
+	}
+
+	single := newBenchResult("test", "testdb", 100)
+	single.addRun(time.Second, 100, 0)
+	single.compute()
+	if single.MeanCI != [2]time.Duration{} || single.OpP99CI != [2]time.Duration{} || newReportEntry(single).OpsPerSecCI != nil {
+		t.Error("expected no CI from a single run")
+	}
+}
+
+func TestSpeedupReportsSignificance(t *testing.T) {
+	result := func(driver string, runs ...time.Duration) *BenchmarkResult {
+		r := newBenchResult("Write (seq)", driver, 1000)
+		for _, d := range runs {
+			r.addRun(d, 1000, 0)
+		}
+		r.compute()
+		return r
+	}
+	ms := time.Millisecond
+	fast := result("GTSDB", 100*ms, 101*ms, 99*ms, 100*ms)
+	slow := result("VM", 170*ms, 172*ms, 168*ms, 170*ms)
+	noisy := result("Influx", 60*ms, 180*ms, 95*ms, 130*ms)
+
+	if got := speedup(slow, fast); !strings.HasPrefix(got, "GTSDB faster by 1.70x ±0.0") || !strings.HasSuffix(got, "(p<0.001)") {
+		t.Errorf("unexpected verdict %q", got)
+	}
+	if got := speedup(fast, noisy); !strings.HasPrefix(got, "no significant difference (") {
+		t.Errorf("expected noise to be reported as such, got %q", got)
+	}
+	if got := speedup(fast, result("NSQ", 200*ms)); got != "2.00x (too few runs to test significance)" {
+		t.Errorf("unexpected single-run verdict %q", got)
+	}
+}
+
+func TestParseReportInput(t *testing.T) {
+	entries := `[
+  {"name": "Write (seq)", "driver": "GTSDB", "ops_per_sec": 1000}
+]`
+	for name, input := range map[string]string{
+		"array":    entries,
+		"captured": "go: downloading\n" + entries + "\n\n=== COMPARISON ===\n",
+		"run":      `{"id": "20261018-120000", "results": ` + entries + `}`,
+	} {
+		rec, err := parseReportInput([]byte(input))
+		if err != nil || len(rec.Results) != 1 || rec.Results[0].OpsPerSec != 1000 {
+			t.Errorf("%s: unexpected %+v (%v)", name, rec, err)
+		}
+	}
+	if _, err := parseReportInput([]byte("no results here")); err == nil {
+		t.Error("expected an error without results")
+	}
+}
+
+func TestWriteReport(t *testing.T) {
+	entry := func(name, driver string, ops float64, mean string) reportEntry {
+		return reportEntry{
+			Name: name, Driver: driver, Runs: 3, OpsPerSec: ops, Mean: mean,
+			RunOpsPerSec: []float64{ops * 0.99, ops, ops * 1.01},
+			OpLatency:    opLatencyEntry{Count: 10, P50: "15µs", P90: "30µs", P99: "90µs", P999: "1ms", P9999: "4ms"},
+		}
+	}
+	var entries []reportEntry
+	for _, name := range []string{"Write (seq)", "Read (single)", "Batch Write"} {
+		entries = append(entries, entry(name, "GTSDB", 2000, "50ms"), entry(name, "VM", 1000, "100ms"))
+	}
+	for _, w := range []int{1, 2, 4} {
+		e := entry("Pipeline Write", "GTSDB", float64(1000*w), "10ms")
+		e.Workers, e.Knee = w, w == 4
+		entries = append(entries, e)
+	}
+	hc := entry("Key Creation", "GTSDB", 5000, "2s")
+	hc.Cardinality, hc.ServerMemory = 10000, 30<<20
+	entries = append(entries, hc)
+
+	dir := t.TempDir()
+	rec := runRecord{ID: "20261018-120000", Meta: runMeta{GTSDBCommit: "abc123"}, Results: entries}
+	if err := writeReport(dir, rec, time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatal(err)
+	}
+
+	md, err := os.ReadFile(filepath.Join(dir, "BENCHMARK_REPORT.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"| GTSDB commit | `abc123` |",
+		"![charts/ops_per_sec.svg](charts/ops_per_sec.svg)",
+		"![charts/radar.svg](charts/radar.svg)",
+		"### Pipeline Write",
+		"| GTSDB | 4 | 4,000.0 |",
+		"## Cardinality",
+		"| Write (seq) | GTSDB vs VM | GTSDB faster by 2.00x",
+	} {
+		if !strings.Contains(string(md), want) {
+			t.Errorf("report is missing %q", want)
+		}
+	}
+	if strings.Contains(string(md), "MISSING") {
+		t.Error("report has a formatting error")
+	}
+
+	charts, _ := filepath.Glob(filepath.Join(dir, "charts", "*.svg"))
+	// Throughput, radar, four latency charts and one scalability curve.
+	if len(charts) != 7 {
+		t.Errorf("expected 7 charts, got %v", charts)
+	}
+	for _, path := range charts {
+		data, _ := os.ReadFile(path)
+		dec := xml.NewDecoder(bytes.NewReader(data))
+		for {
+			if _, err := dec.Token(); err == io.EOF {
+				break
+			} else if err != nil {
+				t.Errorf("%s: %v", filepath.Base(path), err)
+				break
+			}
+		}
+	}
+
+	var data homepageData
+	raw, _ := os.ReadFile(filepath.Join(dir, "benchmark-data.json"))
+	if err := json.Unmarshal(raw, &data); err != nil {
+		t.Fatal(err)
+	}
+	if data.Write["gtsdb"] != 50 || data.Ratios.WriteVsVM != 2 || data.Pipeline["gtsdb"] != 10 {
+		t.Errorf("unexpected homepage data %+v", data)
+	}
+}
+
+func TestFmtCount(t *testing.T) {
+	for n, want := range map[float64]string{0: "0", 999: "999", 1234567: "1,234,567", -1234.5: "-1,234.5"} {
+		decimals := 0
+		if n != math.Trunc(n) {
+			decimals = 1
+		}
+		if got := fmtCount(n, decimals); got != want {
+			t.Errorf("fmtCount(%g): expected %q, got %q", n, want, got)
+		}
+	}
+}
+
+// TestServerStub is not a test: "benchmark servers" tests run the test
+// binary as a stub server that listens on $SERVER_STUB_ADDR until
+// interrupted, or exits at once when the address is "exit".
+func TestServerStub(t *testing.T) {
+	addr := os.Getenv("SERVER_STUB_ADDR")
+	switch addr {
+	case "":
+		return
+	case "exit":
+		fmt.Println("stub: giving up")
+		os.Exit(3)
+	}
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		fmt.Println("stub:", err)
+		os.Exit(1)
+	}
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			os.Exit(1)
+		}
+		conn.Close()
+	}
+}
+
+// writeStubServers writes a servers file running one stub server and
+// returns its path and health address.
+func writeStubServers(t *testing.T, stubAddr string) (string, string) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+	if stubAddr == "" {
+		stubAddr = addr
+	}
+	dir := t.TempDir()
+	cfg := fmt.Sprintf(`state: state
+servers:
+  - name: stub
+    binary: %q
+    args: ["-test.run=^TestServerStub$"]
+    env: {SERVER_STUB_ADDR: %q}
+    data: [data]
+    health: tcp://%s
+    timeout: 5s
+`, os.Args[0], stubAddr, addr)
+	path := filepath.Join(dir, "servers.yaml")
+	if err := os.WriteFile(path, []byte(cfg), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path, "tcp://" + addr
+}
+
+func TestServersStartStop(t *testing.T) {
+	path, health := writeStubServers(t, "")
+	cfg, err := loadServerConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if p, err := os.FindProcess(cfg.serverPid(cfg.Servers[0])); err == nil && p.Pid > 0 {
+			p.Kill()
+		}
+	})
+	dataFile := filepath.Join(filepath.Dir(path), "data", "points")
+	os.MkdirAll(filepath.Dir(dataFile), 0o755)
+	os.WriteFile(dataFile, []byte("old"), 0o644)
+
+	if code := runServers([]string{"-config", path, "start"}); code != 0 {
+		t.Fatalf("start exited %d", code)
+	}
+	if _, err := os.Stat(dataFile); !os.IsNotExist(err) {
+		t.Errorf("start left old data behind: %v", err)
+	}
+	if err := probeServer(context.Background(), health); err != nil {
+		t.Fatalf("stub not up after start: %v", err)
+	}
+	if cfg.serverPid(cfg.Servers[0]) == 0 {
+		t.Error("start recorded no pid")
+	}
+	if code := runServers([]string{"-config", path, "start"}); code != 1 {
+		t.Errorf("second start exited %d, want 1", code)
+	}
+	if code := runServers([]string{"-config", path, "wipe"}); code != 1 {
+		t.Errorf("wipe of a running server exited %d, want 1", code)
+	}
+	if code := runServers([]string{"-config", path, "status"}); code != 0 {
+		t.Errorf("status exited %d", code)
+	}
+
+	if code := runServers([]string{"-config", path, "stop", "stub"}); code != 0 {
+		t.Fatalf("stop exited %d", code)
+	}
+	if err := probeServer(context.Background(), health); err == nil {
+		t.Error("stub still up after stop")
+	}
+	if cfg.serverPid(cfg.Servers[0]) != 0 {
+		t.Error("stop left the pid file behind")
+	}
+	if code := runServers([]string{"-config", path, "status"}); code != 1 {
+		t.Errorf("status of a stopped server exited %d, want 1", code)
+	}
+}
+
+func TestServersStartReportsEarlyExit(t *testing.T) {
+	path, _ := writeStubServers(t, "exit")
+	cfg, err := loadServerConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	err = cfg.startServer(cfg.Servers[0], false)
+	if err == nil || !strings.Contains(err.Error(), "exited during startup") || !strings.Contains(err.Error(), "stub: giving up") {
+		t.Fatalf("startServer = %v, want the early exit and the log tail", err)
+	}
+	if time.Since(start) > 4*time.Second {
+		t.Error("startServer waited out the timeout after the process exited")
+	}
+}
+
+func TestLoadServerConfig(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("STUB_BIN", "bin/stub")
+	write := func(body string) string {
+		path := filepath.Join(dir, "servers.yaml")
+		os.WriteFile(path, []byte(body), 0o644)
+		return path
+	}
+	cfg, err := loadServerConfig(write(`servers:
+  - name: a
+    binary: $STUB_BIN
+    data: [data, /abs/data]
+    health: http://127.0.0.1:1/health
+  - name: b
+    binary: sh
+    dir: sub
+    health: tcp://127.0.0.1:2
+    timeout: 1s
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, b := cfg.Servers[0], cfg.Servers[1]
+	if cfg.State != filepath.Join(dir, ".servers") {
+		t.Errorf("State = %q", cfg.State)
+	}
+	if a.Binary != filepath.Join(dir, "bin/stub") || b.Binary != "sh" {
+		t.Errorf("binaries = %q, %q", a.Binary, b.Binary)
+	}
+	if a.Dir != dir || b.Dir != filepath.Join(dir, "sub") {
+		t.Errorf("dirs = %q, %q", a.Dir, b.Dir)
+	}
+	if !slices.Equal(a.Data, []string{filepath.Join(dir, "data"), "/abs/data"}) {
+		t.Errorf("data = %q", a.Data)
+	}
+	if a.Timeout != defaultServerTimeout || b.Timeout != time.Second {
+		t.Errorf("timeouts = %v, %v", a.Timeout, b.Timeout)
+	}
+	if got, err := cfg.selectServers([]string{"b"}); err != nil || len(got) != 1 || got[0] != b {
+		t.Errorf("selectServers(b) = %v, %v", got, err)
+	}
+	if _, err := cfg.selectServers([]string{"c"}); err == nil {
+		t.Error("selectServers accepted an unknown server")
+	}
+
+	for _, bad := range []string{
+		"servers: [{name: a, binary: x, health: 'localhost:1'}]",
+		"servers: [{name: a, binary: x, health: 'ftp://localhost:1'}]",
+		"servers: [{name: a, health: 'tcp://localhost:1'}]",
+		"servers: [{name: a, binary: x, health: 'tcp://h:1'}, {name: a, binary: y, health: 'tcp://h:2'}]",
+	} {
+		if _, err := loadServerConfig(write(bad)); err == nil {
+			t.Errorf("loadServerConfig accepted %s", bad)
+		}
+	}
+	if err := wipeData(&serverEntry{Name: "x", Data: []string{"/"}}); err == nil {
+		t.Error("wipeData accepted /")
+	}
+}
+
+// writeFakeProc writes a /proc/<pid> entry with the given CPU ticks.
+func writeFakeProc(t *testing.T, root string, pid int, ticks int) {
+	t.Helper()
+	dir := filepath.Join(root, fmt.Sprint(pid))
+	os.MkdirAll(filepath.Join(dir, "fd"), 0o755)
+	stat := fmt.Sprintf("%d (my (odd) server) S 1 1 1 0 -1 0 10 0 0 0 %d %d 0 0 20 0 7 0 100 1000 300\n", pid, ticks, ticks/4)
+	if err := os.WriteFile(filepath.Join(dir, "stat"), []byte(stat), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(filepath.Join(dir, "statm"), []byte("1000 300 50 1 0 200 0\n"), 0o644)
+	for i := range 3 {
+		os.WriteFile(filepath.Join(dir, "fd", fmt.Sprint(i)), nil, 0o644)
+	}
+}
+
+func TestReadProc(t *testing.T) {
+	root := t.TempDir()
+	writeFakeProc(t, root, 42, 200)
+	s, err := readProc(root, 42)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := procStats{RSS: 300 * uint64(os.Getpagesize()), CPU: 2500 * time.Millisecond, FDs: 3, Threads: 7}
+	if s != want {
+		t.Errorf("readProc = %+v, want %+v", s, want)
+	}
+	if _, err := readProc(root, 43); err == nil {
+		t.Error("readProc of a missing process succeeded")
+	}
+	if _, err := os.Stat("/proc/self/stat"); err == nil {
+		if s, err := readProc("/proc", os.Getpid()); err != nil || s.RSS == 0 || s.Threads == 0 {
+			t.Errorf("readProc(self) = %+v, %v", s, err)
+		}
+	}
+}
+
+func TestResourceSampler(t *testing.T) {
+	root, data := t.TempDir(), t.TempDir()
+	writeFakeProc(t, root, 7, 100)
+	os.WriteFile(filepath.Join(data, "old"), make([]byte, 1000), 0o644)
+
+	s := newResourceSampler(resourceTarget{Proc: root, Pid: 7, Data: []string{data}}, 5*time.Millisecond)
+	s.start()
+	time.Sleep(20 * time.Millisecond)
+	writeFakeProc(t, root, 7, 500)
+	os.MkdirAll(filepath.Join(data, "sub"), 0o755)
+	os.WriteFile(filepath.Join(data, "sub", "new"), make([]byte, 4000), 0o644)
+	u := s.stop()
+
+	if len(u.Samples) < 3 {
+		t.Fatalf("%d samples, want at least 3", len(u.Samples))
+	}
+	if u.CPU != 5*time.Second {
+		t.Errorf("CPU = %v, want 5s consumed since start", u.CPU)
+	}
+	if u.DiskGrowth != 4000 || u.PeakDisk != 5000 || u.MeanDisk <= 1000 || u.MeanDisk >= 5000 {
+		t.Errorf("disk growth %d, peak %d, mean %d", u.DiskGrowth, u.PeakDisk, u.MeanDisk)
+	}
+	if u.PeakRSS != 300*uint64(os.Getpagesize()) || u.PeakFDs != 3 || u.PeakThreads != 7 {
+		t.Errorf("usage = %+v", u)
+	}
+
+	r := &BenchmarkResult{TotalOps: 1000, Resources: u}
+	if got := r.OpsPerCPUSecond(); got != 200 {
+		t.Errorf("OpsPerCPUSecond = %v, want 200", got)
+	}
+	if got := r.BytesPerPoint(); got != 4 {
+		t.Errorf("BytesPerPoint = %v, want 4", got)
+	}
+	e := newReportEntry(r)
+	if e.Resources == nil || e.Resources.OpsPerCPUSecond != 200 || len(e.Resources.Samples) != len(u.Samples) {
+		t.Errorf("report entry resources = %+v", e.Resources)
+	}
+	e.Driver = "GTSDB"
+	home := newHomepageData([]reportEntry{e})
+	if got := home.Resources["GTSDB"]; got.CPUSec != 5 || got.DiskKB != 4.9 {
+		t.Errorf("homepage resources = %+v", got)
+	}
+}
+
+func TestResourceUsageOverRuns(t *testing.T) {
+	began := time.Unix(1700000000, 0)
+	at := func(d time.Duration) time.Time { return began.Add(d) }
+	// A preload burns 8s of CPU and writes 1000 bytes before two runs
+	// with a setup between them.
+	s := &resourceSampler{began: began, samples: []resourceSample{
+		{At: 0, CPU: 0, Disk: 0, RSS: 100},
+		{At: 4 * time.Second, CPU: 8 * time.Second, Disk: 1000, RSS: 900},
+		{At: 5 * time.Second, CPU: 9 * time.Second, Disk: 1100, RSS: 200},
+		{At: 6 * time.Second, CPU: 10 * time.Second, Disk: 1200, RSS: 300},
+		{At: 8 * time.Second, CPU: 14 * time.Second, Disk: 1200, RSS: 300},
+		{At: 10 * time.Second, CPU: 16 * time.Second, Disk: 1400, RSS: 400},
+	}}
+	u := s.usageOver([][2]time.Time{{at(4 * time.Second), at(6 * time.Second)}, {at(8 * time.Second), at(9 * time.Second)}})
+	if u.CPU != 3*time.Second || u.DiskGrowth != 300 {
+		t.Errorf("CPU %v and disk growth %d, want 3s and 300 from the runs alone", u.CPU, u.DiskGrowth)
+	}
+	if u.PeakRSS != 900 || u.MeanRSS != (900+200+300+300+350)/5 {
+		t.Errorf("peak RSS %d and mean %d", u.PeakRSS, u.MeanRSS)
+	}
+	if all := s.usageOver(nil); all.CPU != 16*time.Second {
+		t.Errorf("CPU %v without windows, want all 16s", all.CPU)
+	}
+
+	cfg := &Config{Count: 1, Runs: 1, Databases: []string{"gtsdb"}, Benchmarks: []string{"all"}, GTSDBConns: 1, GTSDBPipeline: 1, Servers: &serverConfig{}}
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected -servers without a positive -sample-interval to fail validation")
+	}
+}
+
+// burstWriter records how many writes are in flight at once, the size of
+// every batch and how many batches repeated a timestamp.
+type burstWriter struct {
+	memDriver
+	inFlight, peak atomic.Int64
+	mu             sync.Mutex
+	batches        []int
+	repeats        int
+}
+
+func (w *burstWriter) WriteBatch(ctx context.Context, points []KeyedPoint) error {
+	n := w.inFlight.Add(1)
+	defer w.inFlight.Add(-1)
+	for {
+		p := w.peak.Load()
+		if n <= p || w.peak.CompareAndSwap(p, n) {
+			break
+		}
+	}
+	time.Sleep(time.Millisecond)
+	stamps := make(map[int64]bool)
+	for _, p := range points {
+		stamps[p.Timestamp] = true
+	}
+	w.mu.Lock()
+	w.batches = append(w.batches, len(points))
+	if len(stamps) != len(points) {
+		w.repeats++
+	}
+	w.mu.Unlock()
+	return nil
+}
+
+func TestPipelinedWorkerHonoursPipelineAndBatchSize(t *testing.T) {
+	w := &burstWriter{memDriver: memDriver{points: make(map[string][]KeyedPoint)}}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Millisecond)
+	defer cancel()
+	var c loadCounters
+	pipelinedWorker(ctx, w, writeLoad{Keys: []string{"a", "b"}, Pipeline: 6, BatchSize: 4, ReadEvery: 1, ReadLastX: 10}, &c)
+
+	if got := w.peak.Load(); got != 6 {
+		t.Errorf("peak in-flight writes = %d, want the pipeline depth 6", got)
+	}
+	for _, n := range w.batches {
+		if n != 4 {
+			t.Fatalf("batch of %d points, want 4", n)
+		}
+	}
+	if w.repeats != 0 {
+		t.Errorf("%d batches stamped several points with one timestamp", w.repeats)
+	}
+	if c.Writes.Load() == 0 || c.Writes.Load()%4 != 0 || c.Reads.Load() == 0 || c.Errors.Load() != 0 {
+		t.Errorf("writes %d, reads %d, errors %d", c.Writes.Load(), c.Reads.Load(), c.Errors.Load())
+	}
+}
+
+// fakeMemoryReporter reports a fixed server memory.
+type fakeMemoryReporter struct {
+	memDriver
+	rss uint64
+	err error
+}
+
+func (f *fakeMemoryReporter) ServerMemory(ctx context.Context) (uint64, error) { return f.rss, f.err }
+
+func TestMemoryProbe(t *testing.T) {
+	root := t.TempDir()
+	writeFakeProc(t, root, 9, 0)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/debug/vars" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, `{"cmdline":["gtsdb"],"memstats":{"HeapAlloc":2097152,"Sys":8388608,"NumGC":12,"PauseNs":[1,2]}}`)
+	}))
+	defer srv.Close()
+
+	p := &memoryProbe{proc: resourceTarget{Proc: root, Pid: 9}, expvarURL: srv.URL + "/debug/vars", client: srv.Client()}
+	s, err := p.sample(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantRSS := float64(300*os.Getpagesize()) / (1 << 20)
+	if s.RSSMB != wantRSS || s.HeapMB != 2 || s.SysMB != 8 || s.GCDone != 12 {
+		t.Errorf("sample = %+v", s)
+	}
+
+	// Without a pid the driver's metrics give RSS; expvar failing alone is
+	// not fatal.
+	p = &memoryProbe{reporter: &fakeMemoryReporter{rss: 3 << 20}, expvarURL: srv.URL + "/nope", client: srv.Client()}
+	if s, err := p.sample(context.Background()); err != nil || s.RSSMB != 3 {
+		t.Errorf("sample = %+v, %v", s, err)
+	}
+	p.reporter = &fakeMemoryReporter{err: errors.New("down")}
+	if _, err := p.sample(context.Background()); err == nil {
+		t.Error("sample succeeded with every source failing")
+	}
+
+	if _, err := newMemoryProbe(&driverSpec{Name: "mem"}, newMemDriver(), 0, "", ""); err == nil {
+		t.Error("newMemoryProbe accepted a driver with no memory source")
+	}
+}
+
+func TestRunMemoryBench(t *testing.T) {
+	root := t.TempDir()
+	writeFakeProc(t, root, 5, 0)
+	probe := &memoryProbe{proc: resourceTarget{Proc: root, Pid: 5}}
+	cfg := MemoryBenchConfig{Duration: 40 * time.Millisecond, Interval: 10 * time.Millisecond, Keys: 3, Concurrency: 2, Pipeline: 4}
+	out, err := runMemoryBench(cfg, newMemDriver(), probe)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(out.Samples) != 5 || out.TotalWrites == 0 || out.Errors != 0 {
+		t.Errorf("%d samples, %d writes, %d errors", len(out.Samples), out.TotalWrites, out.Errors)
+	}
+	if last := out.Samples[len(out.Samples)-1]; last.Writes == 0 || last.RSSMB == 0 || last.T <= 0 {
+		t.Errorf("last sample = %+v", last)
+	}
+	if out.RSSSlope != 0 {
+		t.Errorf("RSS slope = %v for constant memory", out.RSSSlope)
+	}
+}
+
+func TestLinearSlope(t *testing.T) {
+	if got := linearSlope([]float64{0, 1, 2, 3}, []float64{1, 3, 5, 7}); math.Abs(got-2) > 1e-12 {
+		t.Errorf("slope = %v, want 2", got)
+	}
+	if got := linearSlope([]float64{1, 1}, []float64{1, 5}); got != 0 {
+		t.Errorf("slope with no spread in x = %v, want 0", got)
+	}
+	if slope, p := slopeTest([]float64{0, 1, 2, 3, 4}, []float64{1, 3, 5, 7, 9}); slope != 2 || p != 0 {
+		t.Errorf("slopeTest of an exact line = %v, %v", slope, p)
+	}
+	if _, p := slopeTest([]float64{0, 1, 2, 3, 4, 5}, []float64{1, -1, 1, -1, 1, -1}); p < 0.05 {
+		t.Errorf("slopeTest of noise p = %v, want no significance", p)
+	}
+}
+
+func TestSoakLoopCheckpointsAndResumes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "soak.jsonl")
+	data := t.TempDir()
+	os.WriteFile(filepath.Join(data, "seg"), make([]byte, 100), 0o644)
+	cfg := soakConfig{Duration: 60 * time.Millisecond, Checkpoint: 20 * time.Millisecond, Keys: 5, Concurrency: 2, Pipeline: 3, ReadEvery: 1, ReadLastX: 10}
+	h := soakHeader{DB: "mem", Driver: "mem", Config: cfg, Started: time.Now()}
+	log, err := createSoakLog(path, h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := createSoakLog(path, h); err == nil {
+		t.Error("createSoakLog overwrote an existing soak")
+	}
+	d := newMemDriver()
+	cps, err := soakLoop(context.Background(), cfg, d, nil, []string{data}, log, nil, io.Discard)
+	log.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cps) < 3 {
+		t.Fatalf("%d checkpoints, want at least 3", len(cps))
+	}
+	first := cps[len(cps)-1]
+	if first.Segment != 1 || first.Elapsed < 0.05 || first.TotalWrites == 0 || first.Disk != 100 {
+		t.Errorf("last checkpoint = %+v", first)
+	}
+
+	// A crash mid-write leaves a torn line, which resuming drops.
+	f, _ := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	f.WriteString(`{"time":"2026-`)
+	f.Close()
+	log, h2, prior, err := resumeSoakLog(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h2.DB != "mem" || h2.Config != cfg || len(prior) != len(cps) {
+		t.Fatalf("resumed header %+v with %d checkpoints, want %d", h2, len(prior), len(cps))
+	}
+	cfg.Duration = 120 * time.Millisecond
+	cps, err = soakLoop(context.Background(), cfg, d, nil, []string{data}, log, prior, io.Discard)
+	log.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	last := cps[len(cps)-1]
+	if last.Segment != 2 || last.Elapsed < 0.11 || last.TotalWrites <= first.TotalWrites {
+		t.Errorf("resumed soak ended at %+v after %+v", last, first)
+	}
+	_, logged, _, err := readSoakLog(path)
+	if err != nil || len(logged) != len(cps) {
+		t.Errorf("log holds %d checkpoints (%v), want %d", len(logged), err, len(cps))
+	}
+
+	// A soak already run to its duration does nothing more.
+	if again, err := soakLoop(context.Background(), cfg, d, nil, nil, nil, cps, io.Discard); err != nil || len(again) != len(cps) {
+		t.Errorf("finished soak ran again: %d checkpoints, %v", len(again), err)
+	}
+}
+
+func TestAnalyzeSoak(t *testing.T) {
+	var cps []soakCheckpoint
+	for i := range 12 {
+		cps = append(cps, soakCheckpoint{
+			Elapsed:      float64(i) * 3600,
+			TotalWrites:  int64(i+1) * 1000,
+			PointsPerSec: 1000 + float64(i%3),
+			P99:          2 + float64(i%2)*0.01,
+			RSS:          uint64(100+10*i) << 20,
+			Disk:         int64(i+1) * 50000,
+		})
+	}
+	// The first hour is warm-up, with a spike trend detection must ignore.
+	cps[0].PointsPerSec = 5000
+	trends := analyzeSoak(cps, time.Hour, 5, 0.05)
+	verdicts := make(map[string]string)
+	for _, tr := range trends {
+		verdicts[tr.Metric] = tr.Verdict
+	}
+	want := map[string]string{
+		"Throughput":     verdictNoTrend,
+		"Op p99":         verdictNoTrend,
+		"Server RSS":     verdictLeak,
+		"Disk per point": verdictNoTrend,
+	}
+	if !reflect.DeepEqual(verdicts, want) {
+		t.Errorf("verdicts = %v, want %v", verdicts, want)
+	}
+
+	for i := range cps {
+		cps[i].PointsPerSec = 1000 - 40*float64(i)
+		cps[i].Disk = int64(i+1) * int64(i+1) * 50000
+	}
+	for _, tr := range analyzeSoak(cps, time.Hour, 5, 0.05) {
+		switch tr.Metric {
+		case "Throughput":
+			if tr.Verdict != verdictDecay {
+				t.Errorf("falling throughput: %+v", tr)
+			}
+		case "Disk per point":
+			if tr.Verdict != verdictCompactionDebt {
+				t.Errorf("growing disk per point: %+v", tr)
+			}
+		}
+	}
+	if tr := analyzeSoak(cps[:3], time.Hour, 5, 0.05)[0]; tr.Verdict != verdictTooFew || tr.flagged() {
+		t.Errorf("two steady checkpoints gave %+v", tr)
+	}
+
+	path := filepath.Join(t.TempDir(), "soak.jsonl")
+	log, err := createSoakLog(path, soakHeader{Driver: "mem", Config: soakConfig{Warmup: time.Hour}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, cp := range cps {
+		log.append(cp)
+	}
+	log.Close()
+	if code := runSoak([]string{"-out", path, "-analyze"}); code != 1 {
+		t.Errorf("soak -analyze of a decaying soak exited %d, want 1", code)
+	}
+}
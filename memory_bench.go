@@ -1,186 +1,394 @@
-package main
-
-import (
-	"bufio"
-	"encoding/json"
-	"fmt"
-	"math/rand/v2"
-	"net"
-	"os"
-	"runtime"
-	"sync/atomic"
-	"time"
-)
-
-// MemorySample represents a single memory measurement at a point in time.
-type MemorySample struct {
-	T       float64 `json:"t"`        // Elapsed time in seconds
-	AllocMB float64 `json:"alloc_mb"` // Go runtime Alloc (heap in use)
-	SysMB   float64 `json:"sys_mb"`   // Go runtime Sys (OS memory retained)
-	GCDone  int32   `json:"gc_done"`  // Number of completed GC cycles
-}
-
-type MemBenchOutput struct {
-	Samples     []MemorySample `json:"samples"`
-	TotalWrites int64          `json:"total_writes"`
-	TotalReads  int64          `json:"total_reads"`
-}
-
-func sampleMemory() (AllocMB, SysMB float64, GCDone int32) {
-	var m runtime.MemStats
-	runtime.ReadMemStats(&m)
-	return float64(m.Alloc) / (1024 * 1024),
-		float64(m.Sys) / (1024 * 1024),
-		int32(m.NumGC)
-}
-
-// MemoryBenchConfig configures the memory-over-time benchmark.
-type MemoryBenchConfig struct {
-	GTSDBAddr   string
-	Duration    time.Duration
-	Interval    time.Duration
-	Keys        int
-	Concurrency int // number of parallel pipelined connections
-	Pipeline    int // number of writes to pipeline per batch
-	BatchSize   int // number of keys per batch-write (0 = single writes)
-}
-
-func pipelinedWorker(tcpAddr string, keys []string, done <-chan struct{}, writeCount, readCount *atomic.Int64) {
-	conn, err := net.Dial("tcp", tcpAddr)
-	if err != nil {
-		return
-	}
-	defer conn.Close()
-	reader := bufio.NewReader(conn)
-	buf := make([]byte, 0, 4096)
-
-	for {
-		select {
-		case <-done:
-			return
-		default:
-		}
-
-		// Pipeline: write N payloads without waiting
-		n := 100
-		buf = buf[:0]
-		for i := 0; i < n; i++ {
-			key := keys[rand.IntN(len(keys))]
-			buf = append(buf, []byte(fmt.Sprintf(
-				`{"operation":"write","key":"%s","write":{"value":%f}}`+"\n",
-				key, rand.Float64()*100,
-			))...)
-		}
-		if _, err := conn.Write(buf); err != nil {
-			return
-		}
-		// Drain responses
-		for i := 0; i < n; i++ {
-			if _, err := reader.ReadBytes('\n'); err != nil {
-				return
-			}
-		}
-		writeCount.Add(int64(n))
-
-		// Occasional batch read
-		if rand.IntN(20) == 0 {
-			key := keys[rand.IntN(len(keys))]
-			rp := fmt.Sprintf(
-				`{"operation":"read","key":"%s","read":{"lastx":1000}}`+"\n",
-				key,
-			)
-			if _, err := conn.Write([]byte(rp)); err != nil {
-				return
-			}
-			if _, err := reader.ReadBytes('\n'); err != nil {
-				return
-			}
-			readCount.Add(1)
-		}
-	}
-}
-
-func runMemoryBench(cfg MemoryBenchConfig) ([]MemorySample, int64, int64, error) {
-	fmt.Fprintf(os.Stderr, "Connecting to GTSDB at %s...\n", cfg.GTSDBAddr)
-	fmt.Fprintf(os.Stderr, "Workload: %d concurrent pipelines × %d pipeline depth, %d keys, %d batch size\n",
-		cfg.Concurrency, cfg.Pipeline, cfg.Keys, cfg.BatchSize)
-	fmt.Fprintf(os.Stderr, "Sampling memory every %v for %v...\n", cfg.Interval, cfg.Duration)
-
-	keys := make([]string, cfg.Keys)
-	for i := range keys {
-		keys[i] = fmt.Sprintf("mem_stress_%d", i)
-	}
-
-	var samples []MemorySample
-	startTime := time.Now()
-
-	alloc, sys, gc := sampleMemory()
-	samples = append(samples, MemorySample{T: 0, AllocMB: alloc, SysMB: sys, GCDone: gc})
-
-	var writeCount, readCount atomic.Int64
-	done := make(chan struct{})
-
-	// Start concurrent pipelined workers
-	for i := 0; i < cfg.Concurrency; i++ {
-		go pipelinedWorker(cfg.GTSDBAddr, keys, done, &writeCount, &readCount)
-	}
-
-	sampleTicker := time.NewTicker(cfg.Interval)
-	defer sampleTicker.Stop()
-
-	sampleCount := int(cfg.Duration / cfg.Interval)
-	for i := 0; i < sampleCount; i++ {
-		<-sampleTicker.C
-		alloc, sys, gc := sampleMemory()
-		elapsed := time.Since(startTime).Seconds()
-		samples = append(samples, MemorySample{T: elapsed, AllocMB: alloc, SysMB: sys, GCDone: gc})
-	}
-
-	close(done)
-
-	return samples, writeCount.Load(), readCount.Load(), nil
-}
-
-func main() {
-	cfg := MemoryBenchConfig{
-		GTSDBAddr:   "localhost:5555",
-		Duration:    30 * time.Second,
-		Interval:    1 * time.Second,
-		Keys:        50,  // 10× more keys for wider load
-		Concurrency: 8,   // 8 parallel pipelined connections
-		Pipeline:    100, // 100 writes per pipeline burst
-		BatchSize:   0,   // use single writes in pipeline
-	}
-	if len(os.Args) > 1 {
-		cfg.GTSDBAddr = os.Args[1]
-	}
-	if len(os.Args) > 2 {
-		d, err := time.ParseDuration(os.Args[2])
-		if err == nil {
-			cfg.Duration = d
-		}
-	}
-
-	samples, totalWrites, totalReads, err := runMemoryBench(cfg)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
-	}
-
-	output := struct {
-		Samples     []MemorySample `json:"samples"`
-		TotalWrites int64          `json:"total_writes"`
-		TotalReads  int64          `json:"total_reads"`
-	}{
-		Samples:     samples,
-		TotalWrites: totalWrites,
-		TotalReads:  totalReads,
-	}
-
-	enc := json.NewEncoder(os.Stdout)
-	enc.SetIndent("", "  ")
-	if err := enc.Encode(output); err != nil {
-		fmt.Fprintf(os.Stderr, "Output error: %v\n", err)
-		os.Exit(1)
-	}
-}
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	json "github.com/bytedance/sonic"
+)
+
+// MemorySample represents a single measurement of the server's memory.
+type MemorySample struct {
+	T      float64 `json:"t"`                 // Elapsed time in seconds
+	RSSMB  float64 `json:"rss_mb,omitempty"`  // Server resident memory, from /proc or its metrics
+	HeapMB float64 `json:"heap_mb,omitempty"` // expvar memstats.HeapAlloc
+	SysMB  float64 `json:"sys_mb,omitempty"`  // expvar memstats.Sys (OS memory retained)
+	GCDone uint32  `json:"gc_done,omitempty"` // expvar memstats.NumGC
+	Writes int64   `json:"writes"`            // Points written so far
+}
+
+type MemBenchOutput struct {
+	Driver      string            `json:"driver"`
+	Config      MemoryBenchConfig `json:"config"`
+	Samples     []MemorySample    `json:"samples"`
+	TotalWrites int64             `json:"total_writes"`
+	TotalReads  int64             `json:"total_reads"`
+	Errors      int64             `json:"errors"`
+	// RSSSlope and HeapSlope are the least-squares growth of RSSMB and
+	// HeapMB in MB per minute.
+	RSSSlope  float64 `json:"rss_mb_per_min"`
+	HeapSlope float64 `json:"heap_mb_per_min,omitempty"`
+}
+
+// MemoryBenchConfig configures the memory-over-time benchmark.
+type MemoryBenchConfig struct {
+	Duration    time.Duration `json:"duration"`
+	Interval    time.Duration `json:"interval"`
+	Keys        int           `json:"keys"`
+	Concurrency int           `json:"concurrency"` // number of parallel pipelined workers
+	Pipeline    int           `json:"pipeline"`    // number of writes in flight per worker burst
+	BatchSize   int           `json:"batch_size"`  // number of points per batch-write (0 = single writes)
+}
+
+func (c MemoryBenchConfig) Validate() error {
+	if c.Duration <= 0 || c.Interval <= 0 {
+		return fmt.Errorf("duration and interval must be positive")
+	}
+	if c.Keys <= 0 || c.Concurrency <= 0 || c.Pipeline <= 0 {
+		return fmt.Errorf("keys, concurrency and pipeline must be positive")
+	}
+	if c.BatchSize < 0 {
+		return fmt.Errorf("batch-size must not be negative")
+	}
+	return nil
+}
+
+// writeLoad is the write-heavy workload pipelinedWorker drives.
+type writeLoad struct {
+	Keys      []string
+	Pipeline  int
+	BatchSize int
+	// ReadEvery issues a read of ReadLastX points after every ReadEvery
+	// bursts on average, when the driver can read; 0 never reads.
+	ReadEvery int
+	ReadLastX int
+}
+
+// loadCounters accumulates what pipelinedWorkers did. Latency, when set,
+// records every operation; swapping it starts a new interval.
+type loadCounters struct {
+	Writes  atomic.Int64
+	Reads   atomic.Int64
+	Errors  atomic.Int64
+	Latency atomic.Pointer[latencyHistogram]
+}
+
+// pipelinedWorker writes bursts of load.Pipeline concurrent operations, each
+// a single Write or a WriteBatch of load.BatchSize points, until ctx is done.
+// Drivers that multiplex requests, like gtsdb's pool, pipeline the burst
+// over their connections.
+func pipelinedWorker(ctx context.Context, w Writer, load writeLoad, c *loadCounters) {
+	r, canRead := w.(Reader)
+	key := func() string { return load.Keys[rand.IntN(len(load.Keys))] }
+	// record counts an operation unless it failed only because ctx ended.
+	record := func(start time.Time, err error, n *atomic.Int64, points int) {
+		switch {
+		case err == nil:
+			n.Add(int64(points))
+			if h := c.Latency.Load(); h != nil {
+				h.Record(time.Since(start))
+			}
+		case ctx.Err() == nil:
+			c.Errors.Add(1)
+		}
+	}
+
+	for ctx.Err() == nil {
+		var wg sync.WaitGroup
+		wg.Add(load.Pipeline)
+		for range load.Pipeline {
+			go func() {
+				defer wg.Done()
+				start := time.Now()
+				if load.BatchSize == 0 {
+					record(start, w.Write(ctx, key(), rand.Float64()*100), &c.Writes, 1)
+					return
+				}
+				ts := time.Now().Unix()
+				points := make([]KeyedPoint, load.BatchSize)
+				for i := range points {
+					points[i] = KeyedPoint{Key: key(), Value: rand.Float64() * 100, Timestamp: ts + int64(i)}
+				}
+				record(start, w.WriteBatch(ctx, points), &c.Writes, len(points))
+			}()
+		}
+		wg.Wait()
+
+		// Occasional batch read
+		if canRead && load.ReadEvery > 0 && rand.IntN(load.ReadEvery) == 0 {
+			start := time.Now()
+			_, err := r.Read(ctx, key(), load.ReadLastX)
+			record(start, err, &c.Reads, 1)
+		}
+	}
+}
+
+// memoryProbe reads a database server's memory from every source it has:
+// the process's RSS under /proc, the driver's own metrics, and a Go
+// expvar endpoint for heap statistics.
+type memoryProbe struct {
+	proc      resourceTarget
+	reporter  MemoryReporter
+	expvarURL string
+	client    *http.Client
+}
+
+// sample takes one reading. It fails only when every source failed.
+func (p *memoryProbe) sample(ctx context.Context) (MemorySample, error) {
+	var s MemorySample
+	var errs []error
+	const mb = 1 << 20
+	switch {
+	case p.proc.Pid != 0:
+		st, err := readProc(p.proc.Proc, p.proc.Pid)
+		if err != nil {
+			errs = append(errs, err)
+			break
+		}
+		s.RSSMB = float64(st.RSS) / mb
+	case p.reporter != nil:
+		rss, err := p.reporter.ServerMemory(ctx)
+		if err != nil {
+			errs = append(errs, err)
+			break
+		}
+		s.RSSMB = float64(rss) / mb
+	}
+	if p.expvarURL != "" {
+		m, err := readExpvarMemStats(ctx, p.client, p.expvarURL)
+		if err != nil {
+			errs = append(errs, err)
+		} else {
+			s.HeapMB, s.SysMB, s.GCDone = float64(m.HeapAlloc)/mb, float64(m.Sys)/mb, m.NumGC
+		}
+	}
+	if len(errs) > 0 && s.RSSMB == 0 && s.HeapMB == 0 {
+		return s, errors.Join(errs...)
+	}
+	return s, nil
+}
+
+// expvarMemStats is the part of runtime.MemStats /debug/vars publishes
+// that the memory benchmark reports.
+type expvarMemStats struct {
+	HeapAlloc uint64
+	Sys       uint64
+	NumGC     uint32
+}
+
+func readExpvarMemStats(ctx context.Context, client *http.Client, url string) (expvarMemStats, error) {
+	var vars struct {
+		MemStats *expvarMemStats `json:"memstats"`
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return expvarMemStats{}, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return expvarMemStats{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return expvarMemStats{}, fmt.Errorf("expvar: HTTP %d", resp.StatusCode)
+	}
+	if err := json.ConfigDefault.NewDecoder(resp.Body).Decode(&vars); err != nil {
+		return expvarMemStats{}, fmt.Errorf("expvar: %w", err)
+	}
+	if vars.MemStats == nil {
+		return expvarMemStats{}, fmt.Errorf("expvar: no memstats at %s", url)
+	}
+	return *vars.MemStats, nil
+}
+
+// runMemoryBench drives cfg's workload against w while sampling the
+// server's memory through probe every cfg.Interval.
+func runMemoryBench(cfg MemoryBenchConfig, w Writer, probe *memoryProbe) (MemBenchOutput, error) {
+	out := MemBenchOutput{Driver: w.Name(), Config: cfg}
+	keys := make([]string, cfg.Keys)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("mem_stress_%d", i)
+	}
+
+	startTime := time.Now()
+	first, err := probe.sample(context.Background())
+	if err != nil {
+		return out, fmt.Errorf("cannot measure server memory: %w", err)
+	}
+	out.Samples = append(out.Samples, first)
+
+	var counters loadCounters
+	load := writeLoad{Keys: keys, Pipeline: cfg.Pipeline, BatchSize: cfg.BatchSize, ReadEvery: 20, ReadLastX: 1000}
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	wg.Add(cfg.Concurrency)
+	for range cfg.Concurrency {
+		go func() {
+			defer wg.Done()
+			pipelinedWorker(ctx, w, load, &counters)
+		}()
+	}
+
+	sampleTicker := time.NewTicker(cfg.Interval)
+	defer sampleTicker.Stop()
+	sampleCount := int(cfg.Duration / cfg.Interval)
+	for range sampleCount {
+		<-sampleTicker.C
+		s, err := probe.sample(context.Background())
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "  sample failed: %v\n", err)
+			continue
+		}
+		s.T = time.Since(startTime).Seconds()
+		s.Writes = counters.Writes.Load()
+		out.Samples = append(out.Samples, s)
+		heap := ""
+		if probe.expvarURL != "" {
+			heap = fmt.Sprintf("  heap %8.1f MB", s.HeapMB)
+		}
+		fmt.Fprintf(os.Stderr, "  %6.0fs  rss %8.1f MB%s  writes %d\n", s.T, s.RSSMB, heap, s.Writes)
+	}
+	cancel()
+	wg.Wait()
+
+	out.TotalWrites = counters.Writes.Load()
+	out.TotalReads = counters.Reads.Load()
+	out.Errors = counters.Errors.Load()
+	ts, rss, heap := make([]float64, len(out.Samples)), make([]float64, len(out.Samples)), make([]float64, len(out.Samples))
+	for i, s := range out.Samples {
+		ts[i], rss[i], heap[i] = s.T/60, s.RSSMB, s.HeapMB
+	}
+	out.RSSSlope = linearSlope(ts, rss)
+	out.HeapSlope = linearSlope(ts, heap)
+	return out, nil
+}
+
+// runMemory implements "benchmark memory", which measures how a database
+// server's memory grows under a sustained write load.
+func runMemory(args []string) int {
+	fs := flag.NewFlagSet("memory", flag.ContinueOnError)
+	mcfg := MemoryBenchConfig{}
+	cfg := &Config{}
+	registerDriverFlags(fs, cfg)
+	db := fs.String("db", "gtsdb", "Database to load: any driver that can write")
+	fs.DurationVar(&mcfg.Duration, "duration", 30*time.Second, "How long to load the server")
+	fs.DurationVar(&mcfg.Interval, "interval", time.Second, "How often to sample its memory")
+	fs.IntVar(&mcfg.Keys, "keys", 50, "Keys written")
+	fs.IntVar(&mcfg.Concurrency, "concurrency", 8, "Parallel pipelined workers")
+	fs.IntVar(&mcfg.Pipeline, "pipeline", 100, "Writes in flight per worker burst")
+	fs.IntVar(&mcfg.BatchSize, "batch-size", 0, "Points per batch write (0 = single writes)")
+	pid := fs.Int("pid", 0, "Server process to read RSS from under /proc")
+	serversPath := fs.String("servers", "", "Servers file (see \"benchmark servers\") to find the server process in")
+	expvarURL := fs.String("expvar", "", "Go expvar endpoint of the server for heap statistics, e.g. http://localhost:5556/debug/vars")
+	outPath := fs.String("out", "", "Write the JSON result here instead of stdout")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: benchmark memory [flags]\n\n")
+		fmt.Fprintf(os.Stderr, "Loads one database with pipelined writes and samples the server's memory: its\n")
+		fmt.Fprintf(os.Stderr, "RSS from -pid or -servers, else from the driver's metrics (vm, influx), and its\n")
+		fmt.Fprintf(os.Stderr, "Go heap from -expvar. Progress goes to stderr, the JSON result to stdout.\n\nFlags:\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() > 0 {
+		fs.Usage()
+		return 2
+	}
+	cfg.Databases = []string{*db}
+	fail := func(code int, err error) int {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return code
+	}
+	if err := mcfg.Validate(); err != nil {
+		return fail(2, err)
+	}
+	if err := cfg.validateDrivers(); err != nil {
+		return fail(2, err)
+	}
+
+	spec, d, err := openDriver(cfg, *db)
+	if err != nil {
+		return fail(1, err)
+	}
+	defer d.Close()
+	w, ok := d.(Writer)
+	if !ok {
+		return fail(2, fmt.Errorf("%s cannot write", *db))
+	}
+	probe, err := newMemoryProbe(spec, d, *pid, *serversPath, *expvarURL)
+	if err != nil {
+		return fail(2, err)
+	}
+
+	fmt.Fprintf(os.Stderr, "Loading %s: %d workers × %d in flight, %d keys, batch size %d\n",
+		d.Name(), mcfg.Concurrency, mcfg.Pipeline, mcfg.Keys, mcfg.BatchSize)
+	fmt.Fprintf(os.Stderr, "Sampling memory every %v for %v...\n", mcfg.Interval, mcfg.Duration)
+	out, err := runMemoryBench(mcfg, w, probe)
+	if err != nil {
+		return fail(1, err)
+	}
+	fmt.Fprintf(os.Stderr, "%d writes, %d reads, %d errors; RSS grew %.2f MB/min\n",
+		out.TotalWrites, out.TotalReads, out.Errors, out.RSSSlope)
+
+	data, err := json.ConfigDefault.MarshalIndent(out, "", "  ")
+	if err != nil {
+		return fail(1, err)
+	}
+	data = append(data, '\n')
+	if *outPath != "" {
+		err = os.WriteFile(*outPath, data, 0o644)
+	} else {
+		_, err = os.Stdout.Write(data)
+	}
+	if err != nil {
+		return fail(1, err)
+	}
+	return 0
+}
+
+// newMemoryProbe picks the memory sources for driver d: /proc for the
+// server serverTarget finds, else the driver's own metrics, plus expvarURL
+// when set.
+func newMemoryProbe(spec *driverSpec, d Driver, pid int, serversPath, expvarURL string) (*memoryProbe, error) {
+	target, err := serverTarget(spec, pid, serversPath)
+	if err != nil {
+		return nil, err
+	}
+	p := &memoryProbe{proc: target, expvarURL: expvarURL, client: &http.Client{Timeout: 5 * time.Second}}
+	if m, ok := d.(MemoryReporter); ok {
+		p.reporter = m
+	}
+	if p.proc.Pid == 0 && p.reporter == nil && expvarURL == "" {
+		return nil, fmt.Errorf("no way to measure %s's memory: pass -pid, -servers or -expvar", spec.Name)
+	}
+	return p, nil
+}
+
+// serverTarget locates a driver's server process and data: pid when set,
+// else the process and data of its entry in the servers file.
+func serverTarget(spec *driverSpec, pid int, serversPath string) (resourceTarget, error) {
+	target := resourceTarget{Proc: "/proc", Pid: pid}
+	if serversPath == "" {
+		return target, nil
+	}
+	servers, err := loadServerConfig(serversPath)
+	if err != nil {
+		return target, err
+	}
+	if t, ok := resourceTargetFor(&Config{Servers: servers}, spec); ok {
+		target.Data = t.Data
+		if pid == 0 {
+			target.Pid = t.Pid
+		}
+	}
+	return target, nil
+}
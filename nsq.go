@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"flag"
 	"sync/atomic"
 	"time"
 
@@ -18,6 +19,19 @@ func newNSQDriver(addr string) *nsqDriver {
 	return &nsqDriver{addr: addr}
 }
 
+func init() {
+	registerDriver(&driverSpec{
+		Name: "nsq",
+		Flags: func(fs *flag.FlagSet, cfg *Config) {
+			fs.StringVar(&cfg.NSQAddr, "nsq-addr", "localhost:4150", "NSQ TCP address")
+		},
+		New:       func(cfg *Config) Driver { return newNSQDriver(cfg.NSQAddr) },
+		Endpoints: func(cfg *Config) []endpoint { return []endpoint{tcpEndpoint(&cfg.NSQAddr)} },
+		Module:    "github.com/nsqio/go-nsq",
+		Server:    "nsqd",
+	})
+}
+
 func (d *nsqDriver) Name() string { return "NSQ" }
 
 func (d *nsqDriver) Connect(ctx context.Context) error {
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// openLoopJob is one scheduled operation and the time it should have been sent.
+type openLoopJob struct {
+	seq      int
+	intended time.Time
+}
+
+// openLoopSchedule decides when each operation of an open-loop run is due.
+type openLoopSchedule interface {
+	// at returns the offset from the start of the run at which operation seq
+	// should be sent, or false once the run is complete.
+	at(seq int) (time.Duration, bool)
+	// rateAt returns the target rate in ops/sec at the given offset.
+	rateAt(offset time.Duration) float64
+	describe() string
+}
+
+// fixedRate sends at a constant rate until count operations have been issued
+// or, if count is zero, until duration has elapsed.
+type fixedRate struct {
+	rate     float64
+	count    int
+	duration time.Duration
+}
+
+func (s fixedRate) at(seq int) (time.Duration, bool) {
+	if s.count > 0 && seq >= s.count {
+		return 0, false
+	}
+	t := time.Duration(float64(seq) / s.rate * float64(time.Second))
+	if s.count == 0 && t >= s.duration {
+		return 0, false
+	}
+	return t, true
+}
+
+func (s fixedRate) rateAt(time.Duration) float64 { return s.rate }
+
+func (s fixedRate) describe() string { return fmt.Sprintf("open-loop @%.0f/s", s.rate) }
+
+// rampRate increases (or decreases) the rate linearly from `from` to `to`
+// ops/sec over duration.
+type rampRate struct {
+	from     float64
+	to       float64
+	duration time.Duration
+}
+
+func (s rampRate) at(seq int) (time.Duration, bool) {
+	// Operations issued by time t: from*t + a*t^2 with a = (to-from)/(2T).
+	// Solve for t in the numerically stable form 2n / (b + sqrt(b^2 + 4an)).
+	a := (s.to - s.from) / (2 * s.duration.Seconds())
+	n := float64(seq)
+	disc := s.from*s.from + 4*a*n
+	if disc < 0 {
+		return 0, false
+	}
+	denom := s.from + math.Sqrt(disc)
+	if denom <= 0 {
+		return 0, seq == 0
+	}
+	t := time.Duration(2 * n / denom * float64(time.Second))
+	if t >= s.duration {
+		return 0, false
+	}
+	return t, true
+}
+
+func (s rampRate) rateAt(offset time.Duration) float64 {
+	return s.from + (s.to-s.from)*offset.Seconds()/s.duration.Seconds()
+}
+
+func (s rampRate) describe() string {
+	return fmt.Sprintf("ramp %.0f->%.0f/s over %s", s.from, s.to, s.duration)
+}
+
+// runOpenLoop issues operations on sched regardless of how fast earlier ones
+// complete. Latency is measured from each operation's intended send time, so a
+// server stall inflates the latency of every request queued behind it instead
+// of silently lowering the request rate (coordinated omission).
+// At most workers operations are in flight at once. tl may be nil.
+func runOpenLoop(ctx context.Context, sched openLoopSchedule, workers int, hist *latencyHistogram, tl *timeline, op func(ctx context.Context, seq int) error) (success, failure uint64, elapsed time.Duration) {
+	jobs := make(chan openLoopJob, workers)
+	var acc atomicAccumulator
+	var wg sync.WaitGroup
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			local := newLatencyHistogram()
+			for j := range jobs {
+				err := op(ctx, j.seq)
+				lat := time.Since(j.intended)
+				local.Record(lat)
+				tl.record(j.intended, lat, err)
+				if err == nil {
+					acc.addSuccess(1)
+				} else {
+					acc.addFailure(1)
+				}
+			}
+			hist.Merge(local)
+		}()
+	}
+
+	start := time.Now()
+	tl.begin(start, sched.rateAt)
+	for i := 0; ; i++ {
+		offset, ok := sched.at(i)
+		if !ok {
+			break
+		}
+		intended := start.Add(offset)
+		if d := time.Until(intended); d > 0 {
+			time.Sleep(d)
+		}
+		jobs <- openLoopJob{seq: i, intended: intended}
+	}
+	close(jobs)
+	wg.Wait()
+
+	return acc.successCount(), acc.failureCount(), time.Since(start)
+}
+
+// runOpenLoopWrite performs single-point writes on the given schedule.
+func runOpenLoopWrite(w Writer, key string, warmup, runs int, sched openLoopSchedule, workers int) *BenchmarkResult {
+	result := newBenchResult("Write (seq)", w.Name(), 0)
+	ctx := context.Background()
+
+	for i := 0; i < warmup; i++ {
+		w.Write(ctx, key, float64(i))
+	}
+
+	var totalOps uint64
+	var totalElapsed time.Duration
+	for run := 0; run < runs; run++ {
+		tl := newTimeline(run + 1)
+		s, f, elapsed := runOpenLoop(ctx, sched, workers, result.Latency, tl, func(ctx context.Context, seq int) error {
+			return w.Write(ctx, key, float64(seq))
+		})
+		result.addRun(elapsed, s, f)
+		result.Timeline = append(result.Timeline, tl.points()...)
+		totalOps += s + f
+		totalElapsed += elapsed
+	}
+
+	result.setOpenLoop(sched, totalOps, totalElapsed)
+	result.OperationCount = int(totalOps) / runs
+	result.compute()
+	return result
+}
+
+// runOpenLoopRead performs single-key reads on the given schedule.
+func runOpenLoopRead(r Reader, key string, lastX, runs int, sched openLoopSchedule, workers int) *BenchmarkResult {
+	result := newBenchResult("Read (single)", r.Name(), 0)
+	ctx := context.Background()
+
+	r.Read(ctx, key, lastX)
+
+	var totalOps uint64
+	var totalElapsed time.Duration
+	for run := 0; run < runs; run++ {
+		tl := newTimeline(run + 1)
+		s, f, elapsed := runOpenLoop(ctx, sched, workers, result.Latency, tl, func(ctx context.Context, _ int) error {
+			_, err := r.Read(ctx, key, lastX)
+			return err
+		})
+		result.addRun(elapsed, s, f)
+		result.Timeline = append(result.Timeline, tl.points()...)
+		totalOps += s + f
+		totalElapsed += elapsed
+	}
+
+	result.setOpenLoop(sched, totalOps, totalElapsed)
+	result.OperationCount = int(totalOps) / runs
+	result.compute()
+	return result
+}
+
+// parseRate parses a target rate such as "50000", "50000/s" or "3000/ms"
+// into operations per second.
+func parseRate(s string) (float64, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, nil
+	}
+	unit := time.Second
+	if i := strings.IndexByte(s, '/'); i >= 0 {
+		switch s[i+1:] {
+		case "s", "sec":
+			unit = time.Second
+		case "ms":
+			unit = time.Millisecond
+		case "m", "min":
+			unit = time.Minute
+		default:
+			return 0, fmt.Errorf("invalid rate unit %q", s[i+1:])
+		}
+		s = s[:i]
+	}
+	n, err := strconv.ParseFloat(s, 64)
+	if err != nil || n < 0 || math.IsNaN(n) || math.IsInf(n, 0) {
+		return 0, fmt.Errorf("invalid rate %q", s)
+	}
+	return n * float64(time.Second) / float64(unit), nil
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/golang/snappy"
+)
+
+// promRWDriver writes through the Prometheus remote-write protocol
+// (snappy-compressed protobuf WriteRequest) and reads with PromQL
+// query_range. It works against VictoriaMetrics' /api/v1/write and any
+// other remote-write receiver.
+type promRWDriver struct {
+	writeURL string
+	queryURL string
+	client   *http.Client
+}
+
+func newPromRWDriver(writeURL, queryURL string) *promRWDriver {
+	return &promRWDriver{
+		writeURL: writeURL,
+		queryURL: strings.TrimRight(queryURL, "/"),
+		client: &http.Client{
+			Timeout: 30 * time.Second,
+			Transport: &http.Transport{
+				MaxIdleConnsPerHost: 100,
+				MaxConnsPerHost:     100,
+			},
+		},
+	}
+}
+
+func init() {
+	registerDriver(&driverSpec{
+		Name: "promrw",
+		Flags: func(fs *flag.FlagSet, cfg *Config) {
+			fs.StringVar(&cfg.PromRWURL, "promrw-url", "http://localhost:8428/api/v1/write", "Prometheus remote-write endpoint")
+			fs.StringVar(&cfg.PromQueryURL, "promrw-query-url", "http://localhost:8428", "PromQL query API base URL for the remote-write driver")
+		},
+		New: func(cfg *Config) Driver { return newPromRWDriver(cfg.PromRWURL, cfg.PromQueryURL) },
+		Endpoints: func(cfg *Config) []endpoint {
+			return []endpoint{urlEndpoint(&cfg.PromRWURL), urlEndpoint(&cfg.PromQueryURL)}
+		},
+		Module: "github.com/golang/snappy",
+		Server: "vm",
+	})
+}
+
+func (d *promRWDriver) Name() string { return "PromRW" }
+
+// Connect sends an empty WriteRequest, which every receiver must accept.
+func (d *promRWDriver) Connect(ctx context.Context) error {
+	if err := d.send(ctx, nil); err != nil {
+		return fmt.Errorf("remote-write not reachable: %w", err)
+	}
+	return nil
+}
+
+func (d *promRWDriver) Close() error {
+	d.client.CloseIdleConnections()
+	return nil
+}
+
+// Write stamps the point in milliseconds, so writes to a key within one
+// second are distinct samples rather than duplicates the receiver drops.
+func (d *promRWDriver) Write(ctx context.Context, key string, value float64) error {
+	return d.send(ctx, []KeyedPoint{{Key: key, Value: value, Timestamp: time.Now().UnixMilli()}})
+}
+
+func (d *promRWDriver) WriteBatch(ctx context.Context, points []KeyedPoint) error {
+	millis := make([]KeyedPoint, len(points))
+	for i, p := range points {
+		p.Timestamp *= 1000
+		millis[i] = p
+	}
+	return d.send(ctx, millis)
+}
+
+// send posts points, whose timestamps are in milliseconds.
+func (d *promRWDriver) send(ctx context.Context, points []KeyedPoint) error {
+	body := snappy.Encode(nil, encodeWriteRequest(points))
+	req, err := http.NewRequestWithContext(ctx, "POST", d.writeURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	req.Header.Set("Content-Encoding", "snappy")
+	req.Header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("remote-write returned %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+// Read counts the points returned for the last lastX seconds of key.
+func (d *promRWDriver) Read(ctx context.Context, key string, lastX int) (int, error) {
+	end := time.Now().Unix()
+	return d.ReadRange(ctx, key, end-int64(lastX), end)
+}
+
+func (d *promRWDriver) ReadRange(ctx context.Context, key string, start, end int64) (int, error) {
+	return promQueryRangeCount(ctx, d.client, d.queryURL, fmt.Sprintf(`benchmark_value{key="%s"}`, key), start, end, 1)
+}
+
+func (d *promRWDriver) ReadAggregate(ctx context.Context, key string, start, end, bucket int64, agg string) (int, error) {
+	return promQueryRangeCount(ctx, d.client, d.queryURL, promAggregateQuery(key, bucket, agg), start, end, bucket)
+}
+
+func (d *promRWDriver) ReadPoints(ctx context.Context, key string, lastX int) ([]KeyedPoint, error) {
+	return promQueryRawPoints(ctx, d.client, d.queryURL, key, time.Hour, lastX)
+}
+
+// encodeWriteRequest encodes points, with timestamps in milliseconds, as a
+// prometheus.WriteRequest, one TimeSeries per key with samples in timestamp
+// order:
+//
+//	WriteRequest { repeated TimeSeries timeseries = 1; }
+//	TimeSeries   { repeated Label labels = 1; repeated Sample samples = 2; }
+//	Label        { string name = 1; string value = 2; }
+//	Sample       { double value = 1; int64 timestamp = 2; } // milliseconds
+func encodeWriteRequest(points []KeyedPoint) []byte {
+	groups := make(map[string][]KeyedPoint)
+	var keys []string
+	for _, p := range points {
+		if _, ok := groups[p.Key]; !ok {
+			keys = append(keys, p.Key)
+		}
+		groups[p.Key] = append(groups[p.Key], p)
+	}
+
+	var buf, series, msg []byte
+	for _, key := range keys {
+		pts := groups[key]
+		sort.SliceStable(pts, func(i, j int) bool { return pts[i].Timestamp < pts[j].Timestamp })
+
+		series = series[:0]
+		// Labels must be sorted by name.
+		for _, l := range [][2]string{{"__name__", "benchmark_value"}, {"key", key}} {
+			msg = msg[:0]
+			msg = appendProtoBytes(msg, 1, []byte(l[0]))
+			msg = appendProtoBytes(msg, 2, []byte(l[1]))
+			series = appendProtoBytes(series, 1, msg)
+		}
+		for _, p := range pts {
+			msg = msg[:0]
+			msg = binary.AppendUvarint(msg, 1<<3|1) // field 1, fixed64
+			msg = binary.LittleEndian.AppendUint64(msg, math.Float64bits(p.Value))
+			msg = binary.AppendUvarint(msg, 2<<3|0) // field 2, varint
+			msg = binary.AppendUvarint(msg, uint64(p.Timestamp))
+			series = appendProtoBytes(series, 2, msg)
+		}
+		buf = appendProtoBytes(buf, 1, series)
+	}
+	return buf
+}
+
+// appendProtoBytes appends a length-delimited protobuf field.
+func appendProtoBytes(b []byte, field int, v []byte) []byte {
+	b = binary.AppendUvarint(b, uint64(field)<<3|2)
+	b = binary.AppendUvarint(b, uint64(len(v)))
+	return append(b, v...)
+}
@@ -0,0 +1,306 @@
+package main
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/snappy"
+)
+
+// remoteWriteStandIn is an in-process remote-write receiver that decodes
+// every WriteRequest and answers query_range and range-selector queries from
+// what it stored.
+type remoteWriteStandIn struct {
+	mu      sync.Mutex
+	series  map[string][]KeyedPoint // keyed by the "key" label
+	headers http.Header
+	fail    bool
+}
+
+func newRemoteWriteStandIn(t *testing.T) (*remoteWriteStandIn, *httptest.Server) {
+	s := &remoteWriteStandIn{series: make(map[string][]KeyedPoint)}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/write", func(w http.ResponseWriter, r *http.Request) {
+		if s.fail {
+			http.Error(w, "ingestion disabled", http.StatusServiceUnavailable)
+			return
+		}
+		compressed, _ := io.ReadAll(r.Body)
+		raw, err := snappy.Decode(nil, compressed)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		series, err := decodeWriteRequest(raw)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		s.mu.Lock()
+		s.headers = r.Header.Clone()
+		for key, pts := range series {
+			s.series[key] = append(s.series[key], pts...)
+		}
+		s.mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	})
+	mux.HandleFunc("/api/v1/query_range", func(w http.ResponseWriter, r *http.Request) {
+		start, _ := strconv.ParseInt(r.URL.Query().Get("start"), 10, 64)
+		end, _ := strconv.ParseInt(r.URL.Query().Get("end"), 10, 64)
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		fmt.Fprint(w, `{"status":"success","data":{"resultType":"matrix","result":[`)
+		first := true
+		for key, pts := range s.series {
+			if r.URL.Query().Get("query") != fmt.Sprintf(`benchmark_value{key="%s"}`, key) {
+				continue
+			}
+			if !first {
+				fmt.Fprint(w, ",")
+			}
+			first = false
+			fmt.Fprintf(w, `{"metric":{"key":"%s"},"values":[`, key)
+			n := 0
+			for _, p := range pts {
+				if p.Timestamp < start || p.Timestamp > end {
+					continue
+				}
+				if n > 0 {
+					fmt.Fprint(w, ",")
+				}
+				fmt.Fprintf(w, `[%d,"%g"]`, p.Timestamp, p.Value)
+				n++
+			}
+			fmt.Fprint(w, "]}")
+		}
+		fmt.Fprint(w, "]}}")
+	})
+	mux.HandleFunc("/api/v1/query", func(w http.ResponseWriter, r *http.Request) {
+		// Only range selectors are supported: return every stored sample.
+		m := regexp.MustCompile(`key="([^"]*)"`).FindStringSubmatch(r.URL.Query().Get("query"))
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		fmt.Fprint(w, `{"status":"success","data":{"resultType":"matrix","result":[{"metric":{},"values":[`)
+		if m != nil {
+			for i, p := range s.series[m[1]] {
+				if i > 0 {
+					fmt.Fprint(w, ",")
+				}
+				fmt.Fprintf(w, `[%d,"%s"]`, p.Timestamp, strconv.FormatFloat(p.Value, 'g', -1, 64))
+			}
+		}
+		fmt.Fprint(w, "]}]}}")
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return s, srv
+}
+
+// decodeWriteRequest decodes a prometheus.WriteRequest into points grouped
+// by their "key" label. It checks that __name__ is benchmark_value.
+func decodeWriteRequest(b []byte) (map[string][]KeyedPoint, error) {
+	out := make(map[string][]KeyedPoint)
+	err := walkProto(b, func(field int, v []byte, _ uint64) error {
+		if field != 1 {
+			return fmt.Errorf("unexpected WriteRequest field %d", field)
+		}
+		labels := make(map[string]string)
+		var samples []KeyedPoint
+		err := walkProto(v, func(field int, v []byte, _ uint64) error {
+			switch field {
+			case 1:
+				var name, value string
+				err := walkProto(v, func(field int, v []byte, _ uint64) error {
+					if field == 1 {
+						name = string(v)
+					} else {
+						value = string(v)
+					}
+					return nil
+				})
+				labels[name] = value
+				return err
+			case 2:
+				var p KeyedPoint
+				err := walkProto(v, func(field int, v []byte, n uint64) error {
+					if field == 1 {
+						p.Value = math.Float64frombits(n)
+					} else {
+						p.Timestamp = int64(n) / 1000
+					}
+					return nil
+				})
+				samples = append(samples, p)
+				return err
+			}
+			return fmt.Errorf("unexpected TimeSeries field %d", field)
+		})
+		if err != nil {
+			return err
+		}
+		if labels["__name__"] != "benchmark_value" {
+			return fmt.Errorf("unexpected metric name %q", labels["__name__"])
+		}
+		for i := range samples {
+			samples[i].Key = labels["key"]
+		}
+		out[labels["key"]] = append(out[labels["key"]], samples...)
+		return nil
+	})
+	return out, err
+}
+
+// walkProto calls fn for each field of a protobuf message. Length-delimited
+// fields pass their bytes; varint and fixed64 fields pass their value as n.
+func walkProto(b []byte, fn func(field int, v []byte, n uint64) error) error {
+	for len(b) > 0 {
+		tag, k := binary.Uvarint(b)
+		if k <= 0 {
+			return fmt.Errorf("bad tag")
+		}
+		b = b[k:]
+		field := int(tag >> 3)
+		switch tag & 7 {
+		case 0:
+			n, k := binary.Uvarint(b)
+			if k <= 0 {
+				return fmt.Errorf("bad varint")
+			}
+			b = b[k:]
+			if err := fn(field, nil, n); err != nil {
+				return err
+			}
+		case 1:
+			if len(b) < 8 {
+				return fmt.Errorf("short fixed64")
+			}
+			if err := fn(field, nil, binary.LittleEndian.Uint64(b)); err != nil {
+				return err
+			}
+			b = b[8:]
+		case 2:
+			l, k := binary.Uvarint(b)
+			if k <= 0 || uint64(len(b)-k) < l {
+				return fmt.Errorf("bad length")
+			}
+			if err := fn(field, b[k:k+int(l)], 0); err != nil {
+				return err
+			}
+			b = b[k+int(l):]
+		default:
+			return fmt.Errorf("unsupported wire type %d", tag&7)
+		}
+	}
+	return nil
+}
+
+func TestPromRWDriverWriteAndRead(t *testing.T) {
+	standIn, srv := newRemoteWriteStandIn(t)
+	d := newPromRWDriver(srv.URL+"/api/v1/write", srv.URL)
+	ctx := context.Background()
+
+	if err := d.Connect(ctx); err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer d.Close()
+
+	if err := d.Write(ctx, "s1", 42.5); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	now := time.Now().Unix()
+	batch := []KeyedPoint{
+		{Key: "s2", Value: 2, Timestamp: now - 1},
+		{Key: "s2", Value: 1, Timestamp: now - 2},
+		{Key: "s1", Value: 3, Timestamp: now - 3},
+	}
+	if err := d.WriteBatch(ctx, batch); err != nil {
+		t.Fatalf("write batch: %v", err)
+	}
+
+	if got := standIn.headers.Get("Content-Encoding"); got != "snappy" {
+		t.Errorf("expected snappy encoding, got %q", got)
+	}
+	if got := standIn.series["s2"]; len(got) != 2 || got[0].Value != 1 || got[1].Value != 2 {
+		t.Errorf("expected s2 samples sorted by timestamp, got %+v", got)
+	}
+
+	n, err := d.Read(ctx, "s1", 60)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if n != 2 {
+		t.Errorf("expected 2 points for s1, got %d", n)
+	}
+
+	pts, err := d.ReadPoints(ctx, "s2", 10)
+	if err != nil {
+		t.Fatalf("read points: %v", err)
+	}
+	if len(pts) != 2 || pts[0] != batch[1] || pts[1] != batch[0] {
+		t.Errorf("expected s2 points in timestamp order, got %+v", pts)
+	}
+}
+
+func TestPromRWDriverReportsServerErrors(t *testing.T) {
+	standIn, srv := newRemoteWriteStandIn(t)
+	d := newPromRWDriver(srv.URL+"/api/v1/write", srv.URL)
+	standIn.fail = true
+
+	err := d.Write(context.Background(), "s1", 1)
+	if err == nil {
+		t.Fatal("expected error from failing receiver")
+	}
+}
+
+func TestPromRWWriteStampsMilliseconds(t *testing.T) {
+	var mu sync.Mutex
+	var stamps []uint64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		compressed, _ := io.ReadAll(r.Body)
+		raw, _ := snappy.Decode(nil, compressed)
+		mu.Lock()
+		defer mu.Unlock()
+		// WriteRequest.timeseries -> TimeSeries.samples -> Sample.timestamp
+		walkProto(raw, func(_ int, series []byte, _ uint64) error {
+			return walkProto(series, func(field int, sample []byte, _ uint64) error {
+				if field != 2 {
+					return nil
+				}
+				return walkProto(sample, func(field int, _ []byte, n uint64) error {
+					if field == 2 {
+						stamps = append(stamps, n)
+					}
+					return nil
+				})
+			})
+		})
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+	d := newPromRWDriver(srv.URL+"/api/v1/write", srv.URL)
+	ctx := context.Background()
+
+	before := uint64(time.Now().UnixMilli())
+	for i := range 2 {
+		if err := d.Write(ctx, "k", float64(i)); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+		time.Sleep(2 * time.Millisecond)
+	}
+	if err := d.WriteBatch(ctx, []KeyedPoint{{Key: "k", Value: 1, Timestamp: 1700000000}}); err != nil {
+		t.Fatalf("write batch: %v", err)
+	}
+	if len(stamps) != 3 || stamps[0] < before || stamps[1] <= stamps[0] || stamps[2] != 1700000000000 {
+		t.Errorf("expected two increasing millisecond stamps and a batch point in ms, got %v", stamps)
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"time"
+)
+
+// rangeSpan is the number of seconds of 1 Hz data preloaded for the range
+// read benchmarks, ending at the time of the preload.
+const rangeSpan = 6 * 3600
+
+// Range read windows in seconds. Downsampled reads cover the whole span in
+// buckets of downsampleBucket seconds.
+const (
+	shortRangeWindow = 60
+	longRangeWindow  = 3600
+	downsampleBucket = 60
+)
+
+// rangeData is a driver's preloaded range read key.
+type rangeData struct {
+	key string
+	end int64
+}
+
+// rangePreloads lets the range benchmarks of one driver share a preload.
+var rangePreloads = make(map[Driver]rangeData)
+
+// preloadRange writes rangeSpan seconds of 1 Hz points for w, once per
+// driver. The key is named after its end so windows never see points
+// preloaded by an earlier invocation.
+func preloadRange(w Writer, maxBatch int) (rangeData, error) {
+	if data, ok := rangePreloads[w]; ok {
+		return data, nil
+	}
+	fmt.Printf("Pre-loading %s range data...\n", w.Name())
+	end := time.Now().Unix()
+	data := rangeData{key: fmt.Sprintf("bench_range_%d", end), end: end}
+	batch := cardinalityBatch
+	if maxBatch > 0 {
+		batch = min(batch, maxBatch)
+	}
+	first := end - rangeSpan + 1
+	for lo := 0; lo < rangeSpan; lo += batch {
+		points := make([]KeyedPoint, min(batch, rangeSpan-lo))
+		for i := range points {
+			points[i] = KeyedPoint{Key: data.key, Value: rand.Float64() * 100, Timestamp: first + int64(lo+i)}
+		}
+		if err := w.WriteBatch(context.Background(), points); err != nil {
+			return data, err
+		}
+	}
+	time.Sleep(200 * time.Millisecond) // wait for ingestion
+	fmt.Println("Pre-load done.")
+	rangePreloads[w] = data
+	return data, nil
+}
+
+// runRangeRead reads window-second windows at random offsets of the
+// preloaded span, downsampled into bucket-second buckets reduced with agg
+// when bucket is set. Each run issues one read per worker. An empty window
+// counts as a failure, since every window was preloaded.
+func runRangeRead(name string, d Driver, data rangeData, window, bucket int64, agg string, runs, workers int) *BenchmarkResult {
+	workers = max(workers, 1)
+	result := newBenchResult(name, d.Name(), workers)
+	ctx := context.Background()
+
+	read := func(start, end int64) (int, error) {
+		return d.(RangeReader).ReadRange(ctx, data.key, start, end)
+	}
+	if bucket > 0 {
+		read = func(start, end int64) (int, error) {
+			return d.(AggregateReader).ReadAggregate(ctx, data.key, start, end, bucket, agg)
+		}
+	}
+	first := data.end - rangeSpan + 1
+	randomWindow := func() (int64, int64) {
+		start := first + rand.Int64N(rangeSpan-window+1)
+		return start, start + window - 1
+	}
+
+	read(randomWindow())
+
+	for run := 0; run < runs; run++ {
+		var acc atomicAccumulator
+		start := time.Now()
+		splitWork(workers, workers, func(_, _ int) {
+			from, to := randomWindow()
+			opStart := time.Now()
+			n, err := read(from, to)
+			result.recordOp(time.Since(opStart))
+			if err == nil && n > 0 {
+				acc.addSuccess(1)
+			} else {
+				acc.addFailure(1)
+			}
+		})
+		result.addRun(time.Since(start), acc.successCount(), acc.failureCount())
+	}
+
+	result.compute()
+	return result
+}
+
+// rangeBenchmark returns the runner of a range read benchmark. It skips
+// the benchmark when the preload fails, since windows over a partial
+// dataset would count as failed reads.
+func rangeBenchmark(name string, window, bucket int64) benchRunner {
+	return func(cfg *Config, d Driver, c driverCaps) *BenchmarkResult {
+		data, err := preloadRange(d.(Writer), c.MaxBatch)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s range preload: %v; skipping %s\n", d.Name(), err, name)
+			return nil
+		}
+		return runRangeRead(name, d, data, window, bucket, cmp.Or(cfg.Agg, "avg"), readRuns(cfg.Runs), cfg.Workers)
+	}
+}
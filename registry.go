@@ -0,0 +1,232 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+)
+
+// benchRunner runs one benchmark against a connected driver. It returns nil
+// when the benchmark had to be skipped.
+type benchRunner func(cfg *Config, d Driver, caps driverCaps) *BenchmarkResult
+
+// driverSpec describes a database driver to the orchestrator. Adding a
+// database means registering a spec from the driver's own file.
+type driverSpec struct {
+	// Name is the identifier used with -db.
+	Name string
+	// Flags registers driver-specific flags into cfg. Optional.
+	Flags func(fs *flag.FlagSet, cfg *Config)
+	// Validate checks driver-specific settings; it only runs when the
+	// driver is selected. Optional.
+	Validate func(cfg *Config) error
+	// New constructs an unconnected driver.
+	New func(cfg *Config) Driver
+	// MaxBatch is the largest number of points one WriteBatch call may
+	// carry; 0 means unlimited.
+	MaxBatch int
+	// Runners replace the generic runner of the named benchmarks.
+	Runners map[string]benchRunner
+	// Endpoints lists the server addresses in cfg that -fault routes
+	// through a fault-injection proxy. Optional.
+	Endpoints func(cfg *Config) []endpoint
+	// Module is the Go client module the driver is built on, whose
+	// version is recorded with saved runs. Empty for in-tree protocols.
+	Module string
+	// Server is the -servers entry running the driver's database, whose
+	// resources are sampled during benchmarks; default Name.
+	Server string
+	// Aggregations lists the per-bucket reductions the driver's
+	// ReadAggregate supports; nil means all of aggregations.
+	Aggregations []string
+}
+
+// driverCaps lists the interfaces a connected driver implements.
+type driverCaps struct {
+	Writer      bool
+	Reader      bool
+	MultiReader bool
+	RangeReader bool
+	// AggregateReader is set when the driver can downsample range reads
+	// with the configured -agg.
+	AggregateReader bool
+	PubSuber        bool
+	// Administrative operations.
+	KeyLister  bool
+	KeyRenamer bool
+	KeyDeleter bool
+	BulkLoader bool
+	Flusher    bool
+	Exporter   bool
+	MaxBatch   int
+}
+
+func capsOf(cfg *Config, spec *driverSpec, d Driver) driverCaps {
+	_, w := d.(Writer)
+	_, r := d.(Reader)
+	_, mr := d.(MultiReader)
+	_, rr := d.(RangeReader)
+	_, ar := d.(AggregateReader)
+	_, ps := d.(PubSuber)
+	_, kl := d.(KeyLister)
+	_, kr := d.(KeyRenamer)
+	_, kd := d.(KeyDeleter)
+	_, bl := d.(BulkLoader)
+	_, fl := d.(Flusher)
+	_, ex := d.(Exporter)
+	if spec.Aggregations != nil && !contains(spec.Aggregations, cmp.Or(cfg.Agg, "avg")) {
+		ar = false
+	}
+	return driverCaps{
+		Writer: w, Reader: r, MultiReader: mr, RangeReader: rr, AggregateReader: ar, PubSuber: ps,
+		KeyLister: kl, KeyRenamer: kr, KeyDeleter: kd, BulkLoader: bl, Flusher: fl, Exporter: ex,
+		MaxBatch: spec.MaxBatch,
+	}
+}
+
+var driverRegistry = make(map[string]*driverSpec)
+
+// registerDriver makes a driver available to -db. It is called from init.
+func registerDriver(spec *driverSpec) {
+	if _, dup := driverRegistry[spec.Name]; dup {
+		panic("benchmark: driver registered twice: " + spec.Name)
+	}
+	driverRegistry[spec.Name] = spec
+}
+
+func lookupDriver(name string) (*driverSpec, bool) {
+	spec, ok := driverRegistry[name]
+	return spec, ok
+}
+
+// driverNames returns the registered -db names in sorted order.
+func driverNames() []string {
+	names := make([]string, 0, len(driverRegistry))
+	for name := range driverRegistry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// registerDriverFlags registers every driver's flags into fs.
+func registerDriverFlags(fs *flag.FlagSet, cfg *Config) {
+	for _, name := range driverNames() {
+		if spec, _ := lookupDriver(name); spec.Flags != nil {
+			spec.Flags(fs, cfg)
+		}
+	}
+}
+
+// openDriver constructs and connects the driver for a -db name.
+func openDriver(cfg *Config, name string) (*driverSpec, Driver, error) {
+	spec, ok := lookupDriver(name)
+	if !ok {
+		return nil, nil, fmt.Errorf("unknown database: %s", name)
+	}
+	d := spec.New(cfg)
+	if err := d.Connect(context.Background()); err != nil {
+		return nil, nil, err
+	}
+	return spec, d, nil
+}
+
+// runBenchmarks runs every selected benchmark against every selected driver
+// that can run it, either through the driver's specialised runner or the
+// generic one. With a -concurrency sweep, concurrent benchmarks run once per
+// level.
+func runBenchmarks(cfg *Config) []*BenchmarkResult {
+	var results []*BenchmarkResult
+	for _, name := range cfg.Databases {
+		spec, d, err := openDriver(cfg, name)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", name, err)
+			continue
+		}
+		caps := capsOf(cfg, spec, d)
+		for _, b := range benchmarks {
+			if !cfg.HasBench(b.Name) {
+				continue
+			}
+			run := spec.Runners[b.Name]
+			if run == nil {
+				if !b.Supports(caps) {
+					continue
+				}
+				run = b.Run
+			}
+			if len(cfg.Concurrency) == 0 || !b.Concurrent {
+				results = append(results, sampleResources(cfg, spec, func() []*BenchmarkResult { return runOne(run, cfg, d, caps) })...)
+				continue
+			}
+			for _, n := range cfg.Concurrency {
+				level := *cfg
+				level.Workers = n
+				for _, r := range sampleResources(cfg, spec, func() []*BenchmarkResult { return runOne(run, &level, d, caps) }) {
+					r.Workers = n
+					results = append(results, r)
+				}
+			}
+		}
+		d.Close()
+	}
+	return results
+}
+
+// runOne runs one benchmark, returning its result unless it was skipped.
+func runOne(run benchRunner, cfg *Config, d Driver, caps driverCaps) []*BenchmarkResult {
+	if r := run(cfg, d, caps); r != nil {
+		return []*BenchmarkResult{r}
+	}
+	return nil
+}
+
+// runCardinalityBenchmarks runs the high-cardinality suite against every
+// selected database that can write. Each invocation uses a fresh keyspace.
+func runCardinalityBenchmarks(cfg *Config) []*BenchmarkResult {
+	prefix := fmt.Sprintf("hc%d_", time.Now().Unix())
+	var results []*BenchmarkResult
+	for _, name := range cfg.Databases {
+		spec, d, err := openDriver(cfg, name)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", name, err)
+			continue
+		}
+		if w, ok := d.(Writer); ok {
+			results = append(results, sampleResources(cfg, spec, func() []*BenchmarkResult {
+				return runCardinality(cfg, w, spec.MaxBatch, prefix)
+			})...)
+		} else {
+			fmt.Fprintf(os.Stderr, "%s: cannot write, skipping high-cardinality benchmarks\n", d.Name())
+		}
+		d.Close()
+	}
+	return results
+}
+
+// runScenarioBenchmarks runs the -scenario workload against every selected database.
+func runScenarioBenchmarks(cfg *Config) []*BenchmarkResult {
+	var results []*BenchmarkResult
+	for _, name := range cfg.Databases {
+		spec, d, err := openDriver(cfg, name)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", name, err)
+			continue
+		}
+		rs := sampleResources(cfg, spec, func() (rs []*BenchmarkResult) {
+			rs, err = runScenario(cfg.Scenario, d)
+			return rs
+		})
+		d.Close()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Scenario %s: %v\n", cfg.Scenario.Name, err)
+			continue
+		}
+		results = append(results, rs...)
+	}
+	return results
+}
@@ -2,9 +2,12 @@ package main
 
 import (
 	"fmt"
+	"math"
 	"os"
+	"slices"
 	"strings"
 	"text/tabwriter"
+	"time"
 
 	json "github.com/bytedance/sonic"
 )
@@ -23,10 +26,166 @@ type reportEntry struct {
 	P99         string  `json:"p99"`
 	OpsPerSec   float64 `json:"ops_per_sec"`
 	SuccessRate float64 `json:"success_rate"`
+
+	// RunOpsPerSec is the throughput of each run; compare tests it for
+	// significance. OpsPerSecCI and MeanCI are the confidenceLevel
+	// intervals of the mean throughput and run time.
+	RunOpsPerSec []float64 `json:"run_ops_per_sec,omitempty"`
+	OpsPerSecCI  []float64 `json:"ops_per_sec_ci,omitempty"`
+	MeanCI       []string  `json:"mean_ci,omitempty"`
+
+	OpLatency opLatencyEntry `json:"op_latency"`
+
+	Mode         string  `json:"mode,omitempty"`
+	TargetRate   float64 `json:"target_rate,omitempty"`
+	AchievedRate float64 `json:"achieved_rate,omitempty"`
+
+	Timeline []timelineEntry `json:"timeline,omitempty"`
+
+	Verification string `json:"verification,omitempty"`
+	VerifyFailed bool   `json:"verify_failed,omitempty"`
+
+	FaultProfile string `json:"fault_profile,omitempty"`
+
+	// Workers is the -concurrency level; Knee marks the level at the knee
+	// of this benchmark/driver's scalability curve.
+	Workers int  `json:"workers,omitempty"`
+	Knee    bool `json:"knee,omitempty"`
+
+	Cardinality  int    `json:"cardinality,omitempty"`
+	KeyDist      string `json:"key_dist,omitempty"`
+	ServerMemory uint64 `json:"server_memory_bytes,omitempty"`
+
+	Resources *resourceEntry `json:"resources,omitempty"`
+}
+
+// resourceEntry is the server resource usage sampled during a benchmark.
+type resourceEntry struct {
+	PeakRSS     uint64  `json:"peak_rss_bytes"`
+	MeanRSS     uint64  `json:"mean_rss_bytes"`
+	CPUSeconds  float64 `json:"cpu_seconds"`
+	PeakFDs     int     `json:"peak_fds"`
+	MeanFDs     float64 `json:"mean_fds"`
+	PeakThreads int     `json:"peak_threads"`
+	MeanThreads float64 `json:"mean_threads"`
+	PeakDisk    int64   `json:"peak_disk_bytes"`
+	MeanDisk    int64   `json:"mean_disk_bytes"`
+	DiskGrowth  int64   `json:"disk_growth_bytes"`
+
+	OpsPerCPUSecond float64 `json:"ops_per_cpu_second,omitempty"`
+	BytesPerPoint   float64 `json:"bytes_per_point,omitempty"`
+
+	Samples []resourceSampleEntry `json:"samples"`
+}
+
+// resourceSampleEntry is one resource sample, Millis after the benchmark
+// began.
+type resourceSampleEntry struct {
+	Millis     int64   `json:"ms"`
+	RSS        uint64  `json:"rss_bytes"`
+	CPUSeconds float64 `json:"cpu_seconds"`
+	FDs        int     `json:"fds"`
+	Threads    int     `json:"threads"`
+	Disk       int64   `json:"disk_bytes"`
+}
+
+func newResourceEntry(r *BenchmarkResult) *resourceEntry {
+	u := r.Resources
+	if u == nil {
+		return nil
+	}
+	e := &resourceEntry{
+		PeakRSS:     u.PeakRSS,
+		MeanRSS:     u.MeanRSS,
+		CPUSeconds:  u.CPU.Seconds(),
+		PeakFDs:     u.PeakFDs,
+		MeanFDs:     u.MeanFDs,
+		PeakThreads: u.PeakThreads,
+		MeanThreads: u.MeanThreads,
+		PeakDisk:    u.PeakDisk,
+		MeanDisk:    u.MeanDisk,
+		DiskGrowth:  u.DiskGrowth,
+
+		OpsPerCPUSecond: r.OpsPerCPUSecond(),
+		BytesPerPoint:   r.BytesPerPoint(),
+
+		Samples: make([]resourceSampleEntry, len(u.Samples)),
+	}
+	for i, s := range u.Samples {
+		e.Samples[i] = resourceSampleEntry{
+			Millis:     s.At.Milliseconds(),
+			RSS:        s.RSS,
+			CPUSeconds: s.CPU.Seconds(),
+			FDs:        s.FDs,
+			Threads:    s.Threads,
+			Disk:       s.Disk,
+		}
+	}
+	return e
+}
+
+// timelineEntry is one second of a time-based run.
+type timelineEntry struct {
+	Run        int     `json:"run"`
+	Second     int     `json:"second"`
+	OpsPerSec  uint64  `json:"ops_per_sec"`
+	Errors     uint64  `json:"errors"`
+	TargetRate float64 `json:"target_rate,omitempty"`
+	P50        string  `json:"p50"`
+	P99        string  `json:"p99"`
+	Max        string  `json:"max"`
+}
+
+func newTimelineEntries(points []timelinePoint) []timelineEntry {
+	if len(points) == 0 {
+		return nil
+	}
+	entries := make([]timelineEntry, len(points))
+	for i, p := range points {
+		entries[i] = timelineEntry{
+			Run:        p.Run,
+			Second:     p.Second,
+			OpsPerSec:  p.Ops,
+			Errors:     p.Errors,
+			TargetRate: p.TargetRate,
+			P50:        p.P50.String(),
+			P99:        p.P99.String(),
+			Max:        p.Max.String(),
+		}
+	}
+	return entries
+}
+
+// opLatencyEntry reports per-operation latency percentiles.
+type opLatencyEntry struct {
+	Count uint64 `json:"count"`
+	Mean  string `json:"mean"`
+	P50   string `json:"p50"`
+	P90   string `json:"p90"`
+	P99   string `json:"p99"`
+	P999  string `json:"p99_9"`
+	P9999 string `json:"p99_99"`
+	Max   string `json:"max"`
+	// P50CI and P99CI are the confidenceLevel intervals of P50 and P99.
+	P50CI []string `json:"p50_ci,omitempty"`
+	P99CI []string `json:"p99_ci,omitempty"`
+}
+
+func newOpLatencyEntry(s latencySummary) opLatencyEntry {
+	return opLatencyEntry{
+		Count: s.Count,
+		Mean:  s.Mean.String(),
+		P50:   s.P50.String(),
+		P90:   s.P90.String(),
+		P99:   s.P99.String(),
+		P999:  s.P999.String(),
+		P9999: s.P9999.String(),
+		Max:   s.Max.String(),
+	}
 }
 
 func newReportEntry(r *BenchmarkResult) reportEntry {
-	return reportEntry{
+	e := reportEntry{
 		Name:        r.Name,
 		Driver:      r.DriverName,
 		Runs:        len(r.Durations),
@@ -40,7 +199,38 @@ func newReportEntry(r *BenchmarkResult) reportEntry {
 		P99:         r.P99.String(),
 		OpsPerSec:   r.OpsPerSec,
 		SuccessRate: r.SuccessRate(),
+		OpLatency:   newOpLatencyEntry(r.OpLatency),
+
+		RunOpsPerSec: r.RunOpsPerSec(),
+
+		Mode:         r.Mode,
+		TargetRate:   r.TargetRate,
+		AchievedRate: r.AchievedRate,
+
+		Timeline: newTimelineEntries(r.Timeline),
+
+		Verification: r.Verification,
+		VerifyFailed: r.VerifyFailed,
+
+		FaultProfile: r.FaultProfile,
+
+		Workers: r.Workers,
+
+		Cardinality:  r.Cardinality,
+		KeyDist:      r.KeyDist,
+		ServerMemory: r.ServerMemory,
+
+		Resources: newResourceEntry(r),
+	}
+	if r.MeanCI[1] > 0 {
+		e.OpsPerSecCI = r.OpsPerSecCI[:]
+		e.MeanCI = []string{r.MeanCI[0].String(), r.MeanCI[1].String()}
+	}
+	if r.OpP99CI[1] > 0 {
+		e.OpLatency.P50CI = []string{r.OpP50CI[0].String(), r.OpP50CI[1].String()}
+		e.OpLatency.P99CI = []string{r.OpP99CI[0].String(), r.OpP99CI[1].String()}
 	}
+	return e
 }
 
 func printReport(format string, results []*BenchmarkResult) {
@@ -54,13 +244,34 @@ func printReport(format string, results []*BenchmarkResult) {
 
 func printTable(results []*BenchmarkResult) {
 	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
-	fmt.Fprintf(w, "Benchmark\tDriver\tRuns\tOps/Run\tMean\tStdDev\tP50\tP95\tP99\tOps/sec\tSuccess%%\n")
-	fmt.Fprintf(w, "---------\t------\t----\t-------\t----\t------\t---\t---\t---\t-------\t--------\n")
+	fmt.Fprintf(w, "Benchmark\tDriver\tRuns\tOps/Run\tMean\tStdDev\tP50\tP95\tP99\tOps/sec\t%.0f%% CI\tSuccess%%\tOp P50\tOp P99\tOp P99 CI\tOp P99.9\tOp P99.99\tOp Max\n", confidenceLevel*100)
+	fmt.Fprintf(w, "---------\t------\t----\t-------\t----\t------\t---\t---\t---\t-------\t------\t--------\t------\t------\t---------\t--------\t---------\t------\n")
 
 	for _, r := range results {
-		fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%s\t%s\t%s\t%s\t%s\t%.0f\t%.2f%%\n",
-			r.Name,
-			r.DriverName,
+		name := r.Name
+		if r.Mode != "" {
+			name += " [" + r.Mode + "]"
+		}
+		if r.Workers > 0 {
+			name += fmt.Sprintf(" [%d workers]", r.Workers)
+		}
+		if r.Cardinality > 0 {
+			name += " [" + cardinalityTag(r) + "]"
+		}
+		if r.FaultProfile != "" {
+			name += " {fault: " + r.FaultProfile + "}"
+		}
+		ci := "-"
+		if r.MeanCI[1] > 0 {
+			ci = fmt.Sprintf("%.0f-%.0f", r.OpsPerSecCI[0], r.OpsPerSecCI[1])
+		}
+		latCI := "-"
+		if r.OpP99CI[1] > 0 {
+			latCI = fmt.Sprintf("%v-%v", r.OpP99CI[0], r.OpP99CI[1])
+		}
+		fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%s\t%s\t%s\t%s\t%s\t%.0f\t%s\t%.2f%%\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			name,
+			driverLabel(r),
 			len(r.Durations),
 			r.OperationCount,
 			r.Mean,
@@ -69,26 +280,172 @@ func printTable(results []*BenchmarkResult) {
 			r.P95,
 			r.P99,
 			r.OpsPerSec,
+			ci,
 			r.SuccessRate(),
+			r.OpLatency.P50,
+			r.OpLatency.P99,
+			latCI,
+			r.OpLatency.P999,
+			r.OpLatency.P9999,
+			r.OpLatency.Max,
 		)
 	}
 	w.Flush()
+
+	flagged := make(map[string]bool)
+	for _, r := range results {
+		if r.VerifyFailed && !flagged[r.DriverName] {
+			flagged[r.DriverName] = true
+			fmt.Printf("(!) %s failed read-after-write verification (%s); its numbers are not comparable.\n", r.DriverName, r.Verification)
+		}
+	}
+
+	for _, r := range results {
+		if r.TargetRate > 0 {
+			fmt.Printf("%s / %s: requested %.0f ops/s, achieved %.0f ops/s (%.1f%%)\n",
+				r.Name, r.DriverName, r.TargetRate, r.AchievedRate, r.AchievedRate/r.TargetRate*100)
+		}
+	}
+
+	for _, r := range results {
+		if len(r.Timeline) > 0 {
+			printTimeline(r)
+		}
+	}
+
+	printResources(results)
+	printScalability(results)
+	printCardinality(results)
+}
+
+// printResources prints the server resource usage sampled with -servers.
+func printResources(results []*BenchmarkResult) {
+	if !slices.ContainsFunc(results, func(r *BenchmarkResult) bool { return r.Resources != nil }) {
+		return
+	}
+	fmt.Println("\nServer resources:")
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "Benchmark\tDriver\tPeak RSS\tMean RSS\tCPU\tOps/CPU-s\tPeak FDs\tPeak Threads\tDisk\tBytes/Point\n")
+	for _, r := range results {
+		u := r.Resources
+		if u == nil {
+			continue
+		}
+		opsPerCPU, perPoint := "-", "-"
+		if v := r.OpsPerCPUSecond(); v > 0 {
+			opsPerCPU = fmt.Sprintf("%.0f", v)
+		}
+		if v := r.BytesPerPoint(); v > 0 {
+			perPoint = fmt.Sprintf("%.1f", v)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%d\t%d\t%s\t%s\n",
+			resultLabel(r), r.DriverName, fmtBytes(float64(u.PeakRSS)), fmtBytes(float64(u.MeanRSS)),
+			u.CPU.Round(time.Millisecond), opsPerCPU, u.PeakFDs, u.PeakThreads, fmtBytes(float64(u.PeakDisk)), perPoint)
+	}
+	w.Flush()
+}
+
+// resultLabel is entryLabel for a result.
+func resultLabel(r *BenchmarkResult) string {
+	return entryLabel(reportEntry{
+		Name: r.Name, Mode: r.Mode, Workers: r.Workers,
+		Cardinality: r.Cardinality, KeyDist: r.KeyDist, FaultProfile: r.FaultProfile,
+	})
+}
+
+// driverLabel marks drivers that failed -verify.
+func driverLabel(r *BenchmarkResult) string {
+	if r.VerifyFailed {
+		return r.DriverName + " (!)"
+	}
+	return r.DriverName
+}
+
+// printTimeline prints the per-second time series of a time-based run.
+func printTimeline(r *BenchmarkResult) {
+	fmt.Printf("\n%s / %s [%s] timeline:\n", r.Name, r.DriverName, r.Mode)
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "Run\tSecond\tOps/sec\tTarget\tErrors\tP50\tP99\tMax\n")
+	for _, p := range r.Timeline {
+		target := "-"
+		if p.TargetRate > 0 {
+			target = fmt.Sprintf("%.0f", p.TargetRate)
+		}
+		fmt.Fprintf(w, "%d\t%d\t%d\t%s\t%d\t%s\t%s\t%s\n",
+			p.Run, p.Second, p.Ops, target, p.Errors, p.P50, p.P99, p.Max)
+	}
+	w.Flush()
 }
 
 func printJSON(results []*BenchmarkResult) {
+	enc := json.ConfigDefault.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	enc.Encode(reportEntries(results))
+}
+
+// reportEntries converts results to their JSON form, marking scalability
+// knees.
+func reportEntries(results []*BenchmarkResult) []reportEntry {
+	knees := make(map[*BenchmarkResult]bool)
+	for _, c := range scalabilityCurves(results) {
+		for _, p := range c.Points {
+			knees[p] = p.Workers == c.Knee
+		}
+	}
 	entries := make([]reportEntry, len(results))
 	for i, r := range results {
 		entries[i] = newReportEntry(r)
+		entries[i].Knee = knees[r]
 	}
-	enc := json.ConfigDefault.NewEncoder(os.Stdout)
-	enc.SetIndent("", "  ")
-	enc.Encode(entries)
+	return entries
+}
+
+// speedup says which of a and b has the higher throughput and by how much,
+// with a confidence interval, or that the difference is not significant.
+func speedup(a, b *BenchmarkResult) string {
+	return speedupOf(a.DriverName, b.DriverName, a.OpsPerSec, b.OpsPerSec, a.RunOpsPerSec(), b.RunOpsPerSec())
+}
+
+// speedupOf is speedup for drivers aName and bName with mean throughputs
+// aOps and bOps measured over runs aRuns and bRuns.
+func speedupOf(aName, bName string, aOps, bOps float64, aRuns, bRuns []float64) string {
+	ratio := meanThroughput(aOps, aRuns) / meanThroughput(bOps, bRuns)
+	rel, p := ratioTest(aRuns, bRuns, confidenceLevel)
+	switch {
+	case math.IsNaN(rel) || ratio == 0:
+		return fmt.Sprintf("%.2fx (too few runs to test significance)", ratio)
+	case p >= significanceLevel:
+		return fmt.Sprintf("no significant difference (%.2fx ±%.2f, %s)", ratio, ratio*rel, formatP(p))
+	}
+	faster := aName
+	if ratio < 1 {
+		faster, ratio = bName, 1/ratio
+	}
+	return fmt.Sprintf("%s faster by %.2fx ±%.2f (%s)", faster, ratio, ratio*rel, formatP(p))
+}
+
+// meanThroughput is the mean of the per-run throughputs runs, the estimator
+// the significance tests compare, or ops when there are no runs.
+func meanThroughput(ops float64, runs []float64) float64 {
+	if len(runs) == 0 {
+		return ops
+	}
+	mean, _ := meanVar(runs)
+	return mean
 }
 
 func printComparison(results []*BenchmarkResult) {
+	// Results are only compared at the same -concurrency level and keyspace.
 	groups := make(map[string][]*BenchmarkResult)
 	for _, r := range results {
-		groups[r.Name] = append(groups[r.Name], r)
+		name := r.Name
+		if r.Workers > 0 {
+			name += fmt.Sprintf(" @ %d workers", r.Workers)
+		}
+		if r.Cardinality > 0 {
+			name += " @ " + cardinalityTag(r)
+		}
+		groups[name] = append(groups[name], r)
 	}
 
 	fmt.Println("\n=== COMPARISON ===")
@@ -101,12 +458,23 @@ func printComparison(results []*BenchmarkResult) {
 			for j := i + 1; j < len(group); j++ {
 				a, b := group[i], group[j]
 				if b.OpsPerSec > 0 {
-					ratio := a.OpsPerSec / b.OpsPerSec
-					fmt.Printf("  %s vs %s: %.2fx (%s: %.0f ops/s, %s: %.0f ops/s)\n",
-						a.DriverName, b.DriverName, ratio,
+					fmt.Printf("  %s vs %s: %s (%s: %.0f ops/s, %s: %.0f ops/s)\n",
+						a.DriverName, b.DriverName, speedup(a, b),
 						a.DriverName, a.OpsPerSec,
 						b.DriverName, b.OpsPerSec,
 					)
+					for _, r := range []*BenchmarkResult{a, b} {
+						if r.VerifyFailed {
+							fmt.Printf("    %s failed verification (%s): ratio not meaningful\n", r.DriverName, r.Verification)
+						}
+					}
+				}
+				if a.OpLatency.Count > 0 && b.OpLatency.Count > 0 {
+					fmt.Printf("    per-op p50: %s %s / %s %s, p99: %s %s / %s %s, p99.99: %s %s / %s %s\n",
+						a.DriverName, a.OpLatency.P50, b.DriverName, b.OpLatency.P50,
+						a.DriverName, a.OpLatency.P99, b.DriverName, b.OpLatency.P99,
+						a.DriverName, a.OpLatency.P9999, b.DriverName, b.OpLatency.P9999,
+					)
 				}
 			}
 		}
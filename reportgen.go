@@ -0,0 +1,811 @@
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	json "github.com/bytedance/sonic"
+)
+
+// opPercentiles are the per-op latency percentiles the latency charts plot.
+var opPercentiles = []string{"p50", "p90", "p99", "p99.9", "p99.99"}
+
+// reportSections splits a run's entries the way the report presents them:
+// -cardinality entries apart, and -concurrency sweeps kept for the
+// scalability section while other sections see each variant's best level.
+type reportSections struct {
+	Main        []reportEntry
+	Sweep       []reportEntry
+	Cardinality []reportEntry
+}
+
+func splitReportEntries(entries []reportEntry) reportSections {
+	var s reportSections
+	best := make(map[string]int)
+	for _, e := range entries {
+		switch {
+		case e.Cardinality > 0:
+			s.Cardinality = append(s.Cardinality, e)
+			continue
+		case e.Workers > 0:
+			s.Sweep = append(s.Sweep, e)
+		}
+		key := variantLabel(e) + "\x00" + e.Driver
+		if i, ok := best[key]; ok {
+			if e.OpsPerSec > s.Main[i].OpsPerSec {
+				s.Main[i] = e
+			}
+			continue
+		}
+		best[key] = len(s.Main)
+		s.Main = append(s.Main, e)
+	}
+	return s
+}
+
+// variantLabel is entryLabel without the worker count, which groups the
+// levels of a -concurrency sweep.
+func variantLabel(e reportEntry) string {
+	e.Workers = 0
+	return entryLabel(e)
+}
+
+// groupByLabel groups entries by variantLabel, in order of first appearance.
+func groupByLabel(entries []reportEntry) ([]string, map[string][]reportEntry) {
+	var labels []string
+	groups := make(map[string][]reportEntry)
+	for _, e := range entries {
+		label := variantLabel(e)
+		if _, ok := groups[label]; !ok {
+			labels = append(labels, label)
+		}
+		groups[label] = append(groups[label], e)
+	}
+	return labels, groups
+}
+
+// entryDrivers returns the drivers of entries in order of first appearance.
+func entryDrivers(entries []reportEntry) []string {
+	var drivers []string
+	for _, e := range entries {
+		if !slices.Contains(drivers, e.Driver) {
+			drivers = append(drivers, e.Driver)
+		}
+	}
+	return drivers
+}
+
+// parseEntryDuration parses a duration of a report entry; malformed ones
+// read as zero.
+func parseEntryDuration(s string) time.Duration {
+	d, _ := time.ParseDuration(s)
+	return d
+}
+
+// fmtDuration renders a report entry duration for the markdown tables.
+func fmtDuration(s string) string {
+	d := parseEntryDuration(s)
+	switch {
+	case d == 0:
+		return "0 s"
+	case d >= time.Second:
+		return fmt.Sprintf("%.2f s", d.Seconds())
+	case d >= time.Millisecond:
+		return fmt.Sprintf("%.3f ms", float64(d)/float64(time.Millisecond))
+	case d >= time.Microsecond:
+		return fmt.Sprintf("%.1f us", float64(d)/float64(time.Microsecond))
+	}
+	return fmt.Sprintf("%d ns", d.Nanoseconds())
+}
+
+// fmtCount renders n with thousands separators and decimals digits after
+// the point.
+func fmtCount(n float64, decimals int) string {
+	s := fmt.Sprintf("%.*f", decimals, n)
+	intPart, frac, _ := strings.Cut(s, ".")
+	neg := strings.HasPrefix(intPart, "-")
+	intPart = strings.TrimPrefix(intPart, "-")
+	var b strings.Builder
+	for i, ch := range intPart {
+		if i > 0 && (len(intPart)-i)%3 == 0 {
+			b.WriteByte(',')
+		}
+		b.WriteRune(ch)
+	}
+	out := b.String()
+	if neg {
+		out = "-" + out
+	}
+	if frac != "" {
+		out += "." + frac
+	}
+	return out
+}
+
+// chartSlug turns a benchmark label into a file name component.
+func chartSlug(label string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(label) {
+		if ('a' <= r && r <= 'z') || ('0' <= r && r <= '9') {
+			b.WriteRune(r)
+		} else {
+			b.WriteByte('_')
+		}
+	}
+	return strings.Trim(b.String(), "_")
+}
+
+// opLatencyValues returns e's per-op latency percentiles in milliseconds.
+func opLatencyValues(e reportEntry) []float64 {
+	ms := func(s string) float64 { return float64(parseEntryDuration(s)) / float64(time.Millisecond) }
+	l := e.OpLatency
+	return []float64{ms(l.P50), ms(l.P90), ms(l.P99), ms(l.P999), ms(l.P9999)}
+}
+
+// reportCharts renders the report's SVG charts. files maps each chart's
+// path relative to the report directory to its contents, and paths maps its
+// role ("ops", "radar", "latency:<label>", "scalability:<label>") to the path.
+func reportCharts(s reportSections) (files, paths map[string]string) {
+	files = make(map[string]string)
+	paths = make(map[string]string)
+	add := func(role, name, svg string) {
+		path := "charts/" + name
+		files[path] = svg
+		paths[role] = path
+	}
+
+	labels, groups := groupByLabel(s.Main)
+	drivers := entryDrivers(s.Main)
+	if len(labels) > 0 {
+		series := make([]chartSeries, len(drivers))
+		for i, d := range drivers {
+			series[i] = chartSeries{Name: d, Values: make([]float64, len(labels))}
+			for j, label := range labels {
+				for _, e := range groups[label] {
+					if e.Driver == d {
+						series[i].Values[j] = e.OpsPerSec
+					}
+				}
+			}
+		}
+		add("ops", "ops_per_sec.svg", barChartSVG("Throughput Comparison (Higher is Better)", "Operations / Second", labels, series, true))
+	}
+
+	if axes, series := radarScores(labels, groups, drivers); len(axes) >= 3 {
+		add("radar", "radar.svg", radarChartSVG("Performance Profile (Log-Normalized)", axes, series))
+	}
+
+	for _, label := range labels {
+		var series []chartSeries
+		for _, e := range groups[label] {
+			if e.OpLatency.Count > 0 {
+				series = append(series, chartSeries{Name: e.Driver, Values: opLatencyValues(e)})
+			}
+		}
+		if len(series) == 0 {
+			continue
+		}
+		add("latency:"+label, "latency_"+chartSlug(label)+".svg", lineChartSVG(lineChart{
+			Title:  label + ": Per-Op Latency Percentiles (Lower is Better)",
+			XLabel: "Percentile",
+			YLabel: "Latency (ms)",
+			X:      []float64{0, 1, 2, 3, 4},
+			XTicks: opPercentiles,
+			LogY:   true,
+			Series: series,
+		}))
+	}
+
+	sweepLabels, sweepGroups := groupByLabel(s.Sweep)
+	for _, label := range sweepLabels {
+		var levels []int
+		for _, e := range sweepGroups[label] {
+			if !slices.Contains(levels, e.Workers) {
+				levels = append(levels, e.Workers)
+			}
+		}
+		slices.Sort(levels)
+		x := make([]float64, len(levels))
+		ticks := make([]string, len(levels))
+		for i, l := range levels {
+			x[i], ticks[i] = float64(l), fmt.Sprint(l)
+		}
+		var series []chartSeries
+		for _, d := range entryDrivers(sweepGroups[label]) {
+			cs := chartSeries{Name: d, Values: make([]float64, len(levels)), Marks: make([]bool, len(levels))}
+			for i := range cs.Values {
+				cs.Values[i] = math.NaN()
+			}
+			for _, e := range sweepGroups[label] {
+				if e.Driver == d {
+					i := slices.Index(levels, e.Workers)
+					cs.Values[i], cs.Marks[i] = e.OpsPerSec, e.Knee
+				}
+			}
+			series = append(series, cs)
+		}
+		add("scalability:"+label, "scalability_"+chartSlug(label)+".svg", lineChartSVG(lineChart{
+			Title:  label + ": Throughput vs Workers (knee starred)",
+			XLabel: "Workers",
+			YLabel: "Ops/sec",
+			X:      x,
+			XTicks: ticks,
+			LogX:   true,
+			Series: series,
+		}))
+	}
+	return files, paths
+}
+
+// radarScores scores each driver on every throughput benchmark at least two
+// drivers ran: log10 of its ops/sec as a percentage of the best driver's.
+func radarScores(labels []string, groups map[string][]reportEntry, drivers []string) ([]string, []chartSeries) {
+	var axes []string
+	for _, label := range labels {
+		if len(groups[label]) >= 2 && groups[label][0].Name != "Pub/Sub" {
+			axes = append(axes, label)
+		}
+	}
+	series := make([]chartSeries, len(drivers))
+	for i, d := range drivers {
+		series[i] = chartSeries{Name: d, Values: make([]float64, len(axes))}
+	}
+	for j, label := range axes {
+		var best float64
+		for _, e := range groups[label] {
+			best = max(best, math.Log10(max(e.OpsPerSec, 1)))
+		}
+		for _, e := range groups[label] {
+			if best > 0 {
+				series[slices.Index(drivers, e.Driver)].Values[j] = math.Log10(max(e.OpsPerSec, 1)) / best * 100
+			}
+		}
+	}
+	return axes, series
+}
+
+// buildMarkdown renders BENCHMARK_REPORT.md for rec, linking the charts in
+// paths.
+func buildMarkdown(rec runRecord, s reportSections, paths map[string]string, now time.Time) string {
+	var b strings.Builder
+	p := func(format string, args ...any) { fmt.Fprintf(&b, format+"\n", args...) }
+	img := func(role string) {
+		if path, ok := paths[role]; ok {
+			p("![%s](%s)", path, path)
+			p("")
+		}
+	}
+	table := func(header ...string) {
+		p("| %s |", strings.Join(header, " | "))
+		seps := make([]string, len(header))
+		for i, h := range header {
+			seps[i] = strings.Repeat("-", len(h))
+		}
+		p("|%s|", "-"+strings.Join(seps, "-|-")+"-")
+	}
+
+	p("# Time Series Database Benchmark Report")
+	p("")
+	p("**Generated:** %s", now.Format("2006-01-02 15:04:05"))
+	p("")
+
+	p("## Overview")
+	p("")
+	p("### Benchmark Configuration")
+	p("")
+	table("Parameter", "Value")
+	if rec.ID != "" {
+		p("| Run | `%s` |", rec.ID)
+	}
+	if !rec.Meta.Timestamp.IsZero() {
+		p("| Started | %s |", rec.Meta.Timestamp.Format("2006-01-02 15:04:05 MST"))
+	}
+	if rec.Meta.GTSDBCommit != "" {
+		p("| GTSDB commit | `%s` |", rec.Meta.GTSDBCommit)
+	}
+	if h := rec.Meta.Host; h.OS != "" {
+		p("| Host | %s (%s/%s, %d CPUs, %s) |", h.Hostname, h.OS, h.Arch, h.CPUs, h.GoVersion)
+	}
+	for _, name := range sortedKeys(rec.Meta.Flags) {
+		p("| `-%s` | `%s` |", name, rec.Meta.Flags[name])
+	}
+	p("| Results | %d |", len(rec.Results))
+	p("")
+	if len(rec.Meta.Drivers) > 0 {
+		table("Database", "Client")
+		for _, db := range sortedKeys(rec.Meta.Drivers) {
+			p("| `%s` | %s |", db, rec.Meta.Drivers[db])
+		}
+		p("")
+	}
+
+	if _, ok := paths["ops"]; ok {
+		p("## Overall Throughput Comparison")
+		p("")
+		img("ops")
+		p("*Log scale. Higher bars indicate better throughput (ops/sec).*")
+		p("")
+	}
+	if _, ok := paths["radar"]; ok {
+		p("## Performance Profile (Radar)")
+		p("")
+		img("radar")
+		p("*Log-normalized throughput on every benchmark two or more drivers ran. 100 = best in class.*")
+		p("")
+	}
+
+	labels, groups := groupByLabel(s.Main)
+	if len(labels) > 0 {
+		p("## Benchmarks")
+		p("")
+	}
+	for _, label := range labels {
+		p("### %s", label)
+		p("")
+		img("latency:" + label)
+		table("Driver", "Runs", "Mean", "StdDev", "Min", "Max", "P50", "P95", "P99", "Ops/sec", fmt.Sprintf("%.0f%% CI", confidenceLevel*100), "Op P99", "Success")
+		for _, e := range groups[label] {
+			ci := "-"
+			if len(e.OpsPerSecCI) == 2 {
+				ci = fmtCount(e.OpsPerSecCI[0], 0) + " - " + fmtCount(e.OpsPerSecCI[1], 0)
+			}
+			opP99 := "-"
+			if e.OpLatency.Count > 0 {
+				opP99 = fmtDuration(e.OpLatency.P99)
+			}
+			if ci := e.OpLatency.P99CI; len(ci) == 2 {
+				opP99 += " (" + fmtDuration(ci[0]) + " - " + fmtDuration(ci[1]) + ")"
+			}
+			driver := e.Driver
+			if e.VerifyFailed {
+				driver += " (!)"
+			}
+			p("| %s | %d | %s | %s | %s | %s | %s | %s | %s | %s | %s | %s | %.2f%% |",
+				driver, e.Runs, fmtDuration(e.Mean), fmtDuration(e.StdDev), fmtDuration(e.Min), fmtDuration(e.Max),
+				fmtDuration(e.P50), fmtDuration(e.P95), fmtDuration(e.P99), fmtCount(e.OpsPerSec, 1), ci, opP99, e.SuccessRate)
+		}
+		p("")
+	}
+
+	if totals := resourceTotals(s.Main); len(totals) > 0 {
+		p("## Server Resources")
+		p("")
+		table("Database", "Peak RSS", "CPU", "Peak Disk")
+		for _, driver := range sortedKeys(totals) {
+			t := totals[driver]
+			p("| %s | %s | %.1fs | %s |", driver, fmtBytes(float64(t.PeakRSS)), t.CPUSeconds, fmtBytes(float64(t.PeakDisk)))
+		}
+		p("")
+		table("Benchmark", "Driver", "Peak RSS", "Mean RSS", "CPU", "Ops/CPU-s", "Peak FDs", "Peak Threads", "Disk", "Bytes/Point")
+		for _, e := range s.Main {
+			r := e.Resources
+			if r == nil {
+				continue
+			}
+			opsPerCPU, perPoint := "-", "-"
+			if r.OpsPerCPUSecond > 0 {
+				opsPerCPU = fmtCount(r.OpsPerCPUSecond, 0)
+			}
+			if r.BytesPerPoint > 0 {
+				perPoint = fmtCount(r.BytesPerPoint, 1)
+			}
+			p("| %s | %s | %s | %s | %.2fs | %s | %d | %d | %s | %s |",
+				entryLabel(e), e.Driver, fmtBytes(float64(r.PeakRSS)), fmtBytes(float64(r.MeanRSS)), r.CPUSeconds,
+				opsPerCPU, r.PeakFDs, r.PeakThreads, fmtBytes(float64(r.PeakDisk)), perPoint)
+		}
+		p("")
+		p("*Sampled from each server's process and data directories while the benchmark ran. Bytes/Point is the data directories' growth per operation.*")
+		p("")
+	}
+
+	if len(labels) > 0 {
+		p("## Key Findings")
+		p("")
+	}
+	for _, label := range labels {
+		ranked := slices.Clone(groups[label])
+		p("### %s", label)
+		p("")
+		if ranked[0].Name == "Pub/Sub" {
+			slices.SortStableFunc(ranked, func(a, b reportEntry) int {
+				return cmp.Compare(parseEntryDuration(a.Mean), parseEntryDuration(b.Mean))
+			})
+			for i, e := range ranked {
+				p("- #%d **%s**: **%s** delivery latency", i+1, e.Driver, fmtDuration(e.Mean))
+			}
+		} else {
+			slices.SortStableFunc(ranked, func(a, b reportEntry) int { return cmp.Compare(b.OpsPerSec, a.OpsPerSec) })
+			for i, e := range ranked {
+				p("- #%d **%s**: **%s ops/sec** (%s)", i+1, e.Driver, fmtCount(e.OpsPerSec, 0), fmtDuration(e.Mean))
+			}
+		}
+		p("")
+	}
+
+	sweepLabels, sweepGroups := groupByLabel(s.Sweep)
+	if len(sweepLabels) > 0 {
+		p("## Scalability")
+		p("")
+		p("*Each benchmark was run at every `-concurrency` level; other sections report the best level. " +
+			"The knee is the fewest workers reaching 90%% of peak throughput (a star on the charts).*")
+		p("")
+	}
+	for _, label := range sweepLabels {
+		p("### %s", label)
+		p("")
+		img("scalability:" + label)
+		table("Driver", "Workers", "Ops/sec", "Op P50", "Op P99", "Knee")
+		entries := slices.Clone(sweepGroups[label])
+		drivers := entryDrivers(entries)
+		slices.SortStableFunc(entries, func(a, b reportEntry) int {
+			return cmp.Or(cmp.Compare(slices.Index(drivers, a.Driver), slices.Index(drivers, b.Driver)), cmp.Compare(a.Workers, b.Workers))
+		})
+		for _, e := range entries {
+			knee := ""
+			if e.Knee {
+				knee = "**knee**"
+			}
+			p("| %s | %d | %s | %s | %s | %s |", e.Driver, e.Workers, fmtCount(e.OpsPerSec, 1),
+				fmtDuration(e.OpLatency.P50), fmtDuration(e.OpLatency.P99), knee)
+		}
+		p("")
+	}
+
+	if len(s.Cardinality) > 0 {
+		writeCardinalityMarkdown(&b, s.Cardinality)
+	}
+
+	var pairs []string
+	for _, label := range labels {
+		g := groups[label]
+		for i := 0; i < len(g); i++ {
+			for j := i + 1; j < len(g); j++ {
+				a, c := g[i], g[j]
+				if a.OpsPerSec == 0 || c.OpsPerSec == 0 {
+					continue
+				}
+				pairs = append(pairs, fmt.Sprintf("| %s | %s vs %s | %s |", label, a.Driver, c.Driver,
+					speedupOf(a.Driver, c.Driver, a.OpsPerSec, c.OpsPerSec, a.RunOpsPerSec, c.RunOpsPerSec)))
+			}
+		}
+	}
+	if len(pairs) > 0 {
+		p("## Head-to-Head Comparison")
+		p("")
+		table("Benchmark", "Comparison", "Result")
+		for _, row := range pairs {
+			p("%s", row)
+		}
+		p("")
+	}
+	return b.String()
+}
+
+// writeCardinalityMarkdown writes the cardinality section: one row per
+// driver and keyspace size.
+func writeCardinalityMarkdown(b *strings.Builder, entries []reportEntry) {
+	fmt.Fprintf(b, "## Cardinality\n\n")
+	fmt.Fprintf(b, "*Each keyspace size extends the previous one; creation covers only the new keys. "+
+		"RSS/new key is the server memory growth since the previous size divided by the keys added.*\n\n")
+	fmt.Fprintf(b, "| Driver | Keys | Create keys/s | Write pts/s | Read ops/s | Read P99 | Server RSS | RSS/new key |\n")
+	fmt.Fprintf(b, "|--------|------|---------------|-------------|------------|----------|------------|-------------|\n")
+	type level struct {
+		driver string
+		keys   int
+	}
+	var order []level
+	byLevel := make(map[level]map[string]reportEntry)
+	for _, e := range entries {
+		l := level{e.Driver, e.Cardinality}
+		if byLevel[l] == nil {
+			byLevel[l] = make(map[string]reportEntry)
+			order = append(order, l)
+		}
+		byLevel[l][e.Name] = e
+	}
+	var prev level
+	var prevMem uint64
+	for _, l := range order {
+		byName := byLevel[l]
+		rate := func(name string) string {
+			if e, ok := byName[name]; ok {
+				return fmtCount(e.OpsPerSec, 0)
+			}
+			return "-"
+		}
+		readP99 := "-"
+		if e, ok := byName["High-Card Read"]; ok {
+			readP99 = fmtDuration(e.OpLatency.P99)
+		}
+		var mem uint64
+		for _, e := range byName {
+			mem = max(mem, e.ServerMemory)
+		}
+		rss, perKey := "-", "-"
+		if mem > 0 {
+			rss = fmt.Sprintf("%s MB", fmtCount(float64(mem)/(1<<20), 1))
+			if prev.driver == l.driver && prevMem > 0 && l.keys > prev.keys {
+				perKey = fmt.Sprintf("%s B", fmtCount((float64(mem)-float64(prevMem))/float64(l.keys-prev.keys), 0))
+			}
+		}
+		fmt.Fprintf(b, "| %s | %s | %s | %s | %s | %s | %s | %s |\n", l.driver, fmtCount(float64(l.keys), 0),
+			rate("Key Creation"), rate("High-Card Write"), rate("High-Card Read"), readP99, rss, perKey)
+		prev, prevMem = l, mem
+	}
+	fmt.Fprintln(b)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// homepageData is benchmark-data.json, read by the GTSDB homepage: mean run
+// times in milliseconds per benchmark and GTSDB's speed-up ratios.
+type homepageData struct {
+	Write      map[string]float64 `json:"write"`
+	BatchWrite map[string]float64 `json:"batchWrite"`
+	Pipeline   map[string]float64 `json:"pipeline"`
+	MultiWrite map[string]float64 `json:"multiWrite"`
+	Read       map[string]float64 `json:"read"`
+	ReadMany   map[string]float64 `json:"readMany"`
+	// PubSub is in seconds.
+	PubSub map[string]float64 `json:"pubsub"`
+	Ratios homepageRatios     `json:"ratios"`
+	// Resources is each server's peak memory, total CPU time and peak disk
+	// usage over the run, when it was sampled.
+	Resources map[string]homepageResources `json:"resources,omitempty"`
+}
+
+type homepageResources struct {
+	MemoryMB float64 `json:"memory_mb"`
+	CPUSec   float64 `json:"cpu_sec"`
+	DiskKB   float64 `json:"disk_kb"`
+}
+
+// resourceTotal is a driver's resource usage over a whole run.
+type resourceTotal struct {
+	PeakRSS    uint64
+	CPUSeconds float64
+	PeakDisk   int64
+}
+
+// resourceTotals sums the sampled resource usage of entries per driver.
+func resourceTotals(entries []reportEntry) map[string]resourceTotal {
+	totals := make(map[string]resourceTotal)
+	for _, e := range entries {
+		if e.Resources == nil {
+			continue
+		}
+		t := totals[e.Driver]
+		t.PeakRSS = max(t.PeakRSS, e.Resources.PeakRSS)
+		t.CPUSeconds += e.Resources.CPUSeconds
+		t.PeakDisk = max(t.PeakDisk, e.Resources.PeakDisk)
+		totals[e.Driver] = t
+	}
+	return totals
+}
+
+type homepageRatios struct {
+	WriteVsInflux      float64 `json:"writeVsInflux"`
+	WriteVsVM          float64 `json:"writeVsVM"`
+	PipelineVsInflux   float64 `json:"pipelineVsInflux"`
+	PipelineVsVM       float64 `json:"pipelineVsVM"`
+	BatchVsInflux      float64 `json:"batchVsInflux"`
+	BatchVsVM          float64 `json:"batchVsVM"`
+	MultiWriteVsInflux float64 `json:"multiWriteVsInflux"`
+	MultiWriteVsVM     float64 `json:"multiWriteVsVM"`
+	ReadVsInflux       float64 `json:"readVsInflux"`
+	ReadVsVM           float64 `json:"readVsVM"`
+	ReadManyVsInflux   float64 `json:"readManyVsInflux"`
+	ReadManyVsVM       float64 `json:"readManyVsVM"`
+}
+
+// subMillisecondFloor stands in for read times too short to have measured.
+const subMillisecondFloor = 0.05
+
+func newHomepageData(entries []reportEntry) homepageData {
+	find := func(name, driver string) (reportEntry, bool) {
+		for _, e := range entries {
+			if e.Name == name && e.Driver == driver {
+				return e, true
+			}
+		}
+		return reportEntry{}, false
+	}
+	meanMS := func(name, driver string) float64 {
+		e, _ := find(name, driver)
+		return float64(parseEntryDuration(e.Mean)) / float64(time.Millisecond)
+	}
+	means := func(name string, floor float64) map[string]float64 {
+		return map[string]float64{
+			"gtsdb":    max(meanMS(name, "GTSDB"), floor),
+			"vm":       max(meanMS(name, "VM"), floor),
+			"influxdb": max(meanMS(name, "InfluxDB"), floor),
+		}
+	}
+	round := func(v float64, places int) float64 {
+		p := math.Pow(10, float64(places))
+		return math.Round(v*p) / p
+	}
+	// ratio is the faster driver's speed-up over the slower one, from mean
+	// run times when either throughput rounded to zero.
+	ratio := func(name, d1, d2 string) float64 {
+		e1, _ := find(name, d1)
+		e2, _ := find(name, d2)
+		if e1.OpsPerSec == 0 || e2.OpsPerSec == 0 {
+			m1, m2 := meanMS(name, d1), meanMS(name, d2)
+			switch {
+			case m1 > 0 && m2 > 0:
+				return round(max(m1, m2)/min(m1, m2), 1)
+			case m1 > 0:
+				return round(m1/subMillisecondFloor, 1)
+			case m2 > 0:
+				return round(m2/subMillisecondFloor, 1)
+			}
+			return 1
+		}
+		return round(max(e1.OpsPerSec, e2.OpsPerSec)/min(e1.OpsPerSec, e2.OpsPerSec), 2)
+	}
+	var resources map[string]homepageResources
+	if totals := resourceTotals(entries); len(totals) > 0 {
+		resources = make(map[string]homepageResources, len(totals))
+		for driver, t := range totals {
+			resources[driver] = homepageResources{
+				MemoryMB: round(float64(t.PeakRSS)/(1<<20), 1),
+				CPUSec:   round(t.CPUSeconds, 1),
+				DiskKB:   round(float64(t.PeakDisk)/(1<<10), 1),
+			}
+		}
+	}
+	return homepageData{
+		Write:      means("Write (seq)", 0),
+		BatchWrite: means("Batch Write", 0),
+		Pipeline:   means("Pipeline Write", 0),
+		MultiWrite: means("Multi-Key Write", 0),
+		Read:       means("Read (single)", subMillisecondFloor),
+		ReadMany:   means("Multi-Key Read", subMillisecondFloor),
+		PubSub: map[string]float64{
+			"gtsdb": meanMS("Pub/Sub", "GTSDB") / 1000,
+			"nsq":   meanMS("Pub/Sub", "NSQ") / 1000,
+		},
+		Ratios: homepageRatios{
+			WriteVsInflux:      ratio("Write (seq)", "GTSDB", "InfluxDB"),
+			WriteVsVM:          ratio("Write (seq)", "GTSDB", "VM"),
+			PipelineVsInflux:   ratio("Pipeline Write", "GTSDB", "InfluxDB"),
+			PipelineVsVM:       ratio("Pipeline Write", "GTSDB", "VM"),
+			BatchVsInflux:      ratio("Batch Write", "GTSDB", "InfluxDB"),
+			BatchVsVM:          ratio("Batch Write", "VM", "GTSDB"),
+			MultiWriteVsInflux: ratio("Multi-Key Write", "GTSDB", "InfluxDB"),
+			MultiWriteVsVM:     ratio("Multi-Key Write", "VM", "GTSDB"),
+			ReadVsInflux:       ratio("Read (single)", "GTSDB", "InfluxDB"),
+			ReadVsVM:           ratio("Read (single)", "GTSDB", "VM"),
+			ReadManyVsInflux:   ratio("Multi-Key Read", "GTSDB", "InfluxDB"),
+			ReadManyVsVM:       ratio("Multi-Key Read", "GTSDB", "VM"),
+		},
+		Resources: resources,
+	}
+}
+
+// writeReport writes BENCHMARK_REPORT.md, benchmark-data.json and the SVG
+// charts of rec into dir.
+func writeReport(dir string, rec runRecord, now time.Time) error {
+	s := splitReportEntries(rec.Results)
+	files, paths := reportCharts(s)
+	files["BENCHMARK_REPORT.md"] = buildMarkdown(rec, s, paths, now)
+	data, err := json.ConfigDefault.MarshalIndent(newHomepageData(s.Main), "", "  ")
+	if err != nil {
+		return err
+	}
+	files["benchmark-data.json"] = string(data) + "\n"
+
+	if err := os.MkdirAll(filepath.Join(dir, "charts"), 0o755); err != nil {
+		return err
+	}
+	for _, name := range sortedKeys(files) {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(files[name]), 0o644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseReportInput reads the results of a run from a saved run, a
+// -format=json array, or captured output with the array on lines of its own.
+func parseReportInput(data []byte) (runRecord, error) {
+	var rec runRecord
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	trimmed := bytes.TrimSpace(data)
+	switch {
+	case bytes.HasPrefix(trimmed, []byte("{")):
+		err := json.Unmarshal(trimmed, &rec)
+		return rec, err
+	case bytes.HasPrefix(trimmed, []byte("[")):
+		err := json.Unmarshal(trimmed, &rec.Results)
+		return rec, err
+	}
+	lines := strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")
+	start, end := -1, -1
+	for i, line := range lines {
+		switch strings.TrimSpace(line) {
+		case "[":
+			if start < 0 {
+				start = i
+			}
+		case "]":
+			if start >= 0 {
+				end = i
+			}
+		}
+	}
+	if start < 0 || end < 0 {
+		return rec, errors.New("no JSON results found")
+	}
+	err := json.Unmarshal([]byte(strings.Join(lines[start:end+1], "\n")), &rec.Results)
+	return rec, err
+}
+
+// runReport implements "benchmark report [INPUT]".
+func runReport(args []string) int {
+	fs := flag.NewFlagSet("report", flag.ContinueOnError)
+	out := fs.String("out", "report", "Directory the report and charts are written to")
+	dir := fs.String("results-dir", "results", "Directory runs are saved to")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: benchmark report [flags] [INPUT]\n\n")
+		fmt.Fprintf(os.Stderr, "INPUT is a saved run (ID, file, \"latest\" or \"baseline\"; default latest), a file of\n")
+		fmt.Fprintf(os.Stderr, "-format=json output, or - for standard input.\n\nFlags:\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() > 1 {
+		fs.Usage()
+		return 2
+	}
+	ref := cmp.Or(fs.Arg(0), "latest")
+
+	var rec runRecord
+	var err error
+	if data, rerr := readReportFile(ref); rerr == nil {
+		rec, err = parseReportInput(data)
+	} else if errors.Is(rerr, os.ErrNotExist) {
+		rec, err = resolveRun(*dir, ref)
+	} else {
+		err = rerr
+	}
+	if err == nil && len(rec.Results) == 0 {
+		err = errors.New("no results to report")
+	}
+	if err == nil {
+		err = writeReport(*out, rec, time.Now())
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s: %v\n", ref, err)
+		return 1
+	}
+	fmt.Printf("Report written to %s (%d results)\n", filepath.Join(*out, "BENCHMARK_REPORT.md"), len(rec.Results))
+	return 0
+}
+
+// readReportFile reads a report input file, or standard input for "-".
+func readReportFile(ref string) ([]byte, error) {
+	if ref == "-" {
+		return io.ReadAll(os.Stdin)
+	}
+	return os.ReadFile(ref)
+}
@@ -0,0 +1,359 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// resourceSample is one reading of a database server's process and data.
+type resourceSample struct {
+	// At is the time since sampling began; CPU the user and system time
+	// the process consumed since then.
+	At      time.Duration
+	RSS     uint64
+	CPU     time.Duration
+	FDs     int
+	Threads int
+	Disk    int64
+}
+
+// resourceUsage summarises the samples taken while one benchmark ran.
+type resourceUsage struct {
+	PeakRSS     uint64
+	MeanRSS     uint64
+	CPU         time.Duration
+	PeakFDs     int
+	MeanFDs     float64
+	PeakThreads int
+	MeanThreads float64
+	PeakDisk    int64
+	MeanDisk    int64
+	// DiskGrowth is how much the data directories grew over the benchmark.
+	DiskGrowth int64
+	Samples    []resourceSample
+}
+
+// resourceTarget is what a resourceSampler watches: a process under proc
+// (normally /proc) and its data paths. Pid 0 samples the data alone.
+type resourceTarget struct {
+	Proc string
+	Pid  int
+	Data []string
+}
+
+// clockTicks is USER_HZ, the unit of the CPU times in /proc/<pid>/stat.
+// It is 100 on every architecture Linux supports.
+const clockTicks = 100
+
+// procStats is a reading of /proc/<pid>.
+type procStats struct {
+	RSS     uint64
+	CPU     time.Duration
+	FDs     int
+	Threads int
+}
+
+// readProc reads the resource usage of process pid from a /proc tree.
+func readProc(proc string, pid int) (procStats, error) {
+	dir := filepath.Join(proc, strconv.Itoa(pid))
+	stat, err := os.ReadFile(filepath.Join(dir, "stat"))
+	if err != nil {
+		return procStats{}, err
+	}
+	// The command name in parentheses may contain spaces; fields are
+	// counted from the state after it.
+	i := strings.LastIndexByte(string(stat), ')')
+	if i < 0 {
+		return procStats{}, fmt.Errorf("%s/stat: unexpected format", dir)
+	}
+	fields := strings.Fields(string(stat[i+1:]))
+	if len(fields) < 18 {
+		return procStats{}, fmt.Errorf("%s/stat: unexpected format", dir)
+	}
+	utime, _ := strconv.ParseUint(fields[11], 10, 64)
+	stime, _ := strconv.ParseUint(fields[12], 10, 64)
+	threads, _ := strconv.Atoi(fields[17])
+
+	var s procStats
+	s.CPU = time.Duration(utime+stime) * time.Second / clockTicks
+	s.Threads = threads
+	if statm, err := os.ReadFile(filepath.Join(dir, "statm")); err == nil {
+		if f := strings.Fields(string(statm)); len(f) > 1 {
+			pages, _ := strconv.ParseUint(f[1], 10, 64)
+			s.RSS = pages * uint64(os.Getpagesize())
+		}
+	}
+	// Other users' descriptors are unreadable without privileges.
+	if fds, err := os.ReadDir(filepath.Join(dir, "fd")); err == nil {
+		s.FDs = len(fds)
+	}
+	return s, nil
+}
+
+// diskUsage returns the total size of the files under paths. Missing
+// paths count as empty.
+func diskUsage(paths []string) int64 {
+	var total int64
+	for _, p := range paths {
+		filepath.WalkDir(p, func(_ string, d fs.DirEntry, err error) error {
+			if err != nil || !d.Type().IsRegular() {
+				return nil
+			}
+			if info, err := d.Info(); err == nil {
+				total += info.Size()
+			}
+			return nil
+		})
+	}
+	return total
+}
+
+// resourceSampler samples a resourceTarget at a fixed interval from
+// start until stop.
+type resourceSampler struct {
+	target   resourceTarget
+	interval time.Duration
+	began    time.Time
+	baseCPU  time.Duration
+	procErr  error
+
+	mu      sync.Mutex
+	samples []resourceSample
+	quit    chan struct{}
+	done    chan struct{}
+}
+
+func newResourceSampler(target resourceTarget, interval time.Duration) *resourceSampler {
+	return &resourceSampler{target: target, interval: interval}
+}
+
+// start takes a first sample and begins sampling in the background.
+func (s *resourceSampler) start() {
+	s.began = time.Now()
+	if s.target.Pid != 0 {
+		if p, err := readProc(s.target.Proc, s.target.Pid); err == nil {
+			s.baseCPU = p.CPU
+		} else {
+			s.procErr = err
+		}
+	}
+	s.quit, s.done = make(chan struct{}), make(chan struct{})
+	s.sample()
+	go func() {
+		defer close(s.done)
+		tick := time.NewTicker(s.interval)
+		defer tick.Stop()
+		for {
+			select {
+			case <-s.quit:
+				return
+			case <-tick.C:
+				s.sample()
+			}
+		}
+	}()
+}
+
+func (s *resourceSampler) sample() {
+	r := resourceSample{At: time.Since(s.began), Disk: diskUsage(s.target.Data)}
+	if s.target.Pid != 0 && s.procErr == nil {
+		if p, err := readProc(s.target.Proc, s.target.Pid); err == nil {
+			r.RSS, r.CPU, r.FDs, r.Threads = p.RSS, p.CPU-s.baseCPU, p.FDs, p.Threads
+		}
+	}
+	s.mu.Lock()
+	s.samples = append(s.samples, r)
+	s.mu.Unlock()
+}
+
+// stop takes a last sample and summarises them all.
+func (s *resourceSampler) stop() *resourceUsage {
+	close(s.quit)
+	<-s.done
+	s.sample()
+	// Marks taken by benchmarks race the ticker.
+	slices.SortFunc(s.samples, func(a, b resourceSample) int { return cmp.Compare(a.At, b.At) })
+	return summariseSamples(s.samples)
+}
+
+// usageOver summarises the samples taken during windows, the timed runs of
+// one benchmark, leaving out what the server did around them, such as
+// preloads and warm-up. Values at the windows' edges are interpolated
+// between the samples either side. Without windows it summarises them all.
+func (s *resourceSampler) usageOver(windows [][2]time.Time) *resourceUsage {
+	if len(windows) == 0 || len(s.samples) == 0 {
+		return summariseSamples(s.samples)
+	}
+	var in []resourceSample
+	var cpu time.Duration
+	var growth int64
+	for _, w := range windows {
+		from, to := w[0].Sub(s.began), w[1].Sub(s.began)
+		first, last := interpolateSample(s.samples, from), interpolateSample(s.samples, to)
+		cpu += last.CPU - first.CPU
+		growth += last.Disk - first.Disk
+		in = append(in, first)
+		for _, r := range s.samples {
+			if r.At > from && r.At < to {
+				in = append(in, r)
+			}
+		}
+		in = append(in, last)
+	}
+	u := summariseSamples(in)
+	u.CPU, u.DiskGrowth = cpu, growth
+	return u
+}
+
+// interpolateSample estimates the sample at t from samples, ordered by At,
+// clamping to the first and last.
+func interpolateSample(samples []resourceSample, t time.Duration) resourceSample {
+	i, _ := slices.BinarySearchFunc(samples, t, func(r resourceSample, t time.Duration) int { return cmp.Compare(r.At, t) })
+	var r resourceSample
+	switch {
+	case i == 0:
+		r = samples[0]
+	case i == len(samples):
+		r = samples[i-1]
+	default:
+		a, b := samples[i-1], samples[i]
+		f := float64(t-a.At) / float64(b.At-a.At)
+		lerp := func(x, y float64) float64 { return x + (y-x)*f }
+		r = resourceSample{
+			RSS:     uint64(lerp(float64(a.RSS), float64(b.RSS))),
+			CPU:     time.Duration(lerp(float64(a.CPU), float64(b.CPU))),
+			FDs:     int(math.Round(lerp(float64(a.FDs), float64(b.FDs)))),
+			Threads: int(math.Round(lerp(float64(a.Threads), float64(b.Threads)))),
+			Disk:    int64(lerp(float64(a.Disk), float64(b.Disk))),
+		}
+	}
+	r.At = t
+	return r
+}
+
+func summariseSamples(samples []resourceSample) *resourceUsage {
+	u := &resourceUsage{Samples: samples}
+	if len(samples) == 0 {
+		return u
+	}
+	var rss, fds, threads, disk float64
+	for _, r := range samples {
+		u.PeakRSS = max(u.PeakRSS, r.RSS)
+		u.PeakFDs = max(u.PeakFDs, r.FDs)
+		u.PeakThreads = max(u.PeakThreads, r.Threads)
+		u.PeakDisk = max(u.PeakDisk, r.Disk)
+		rss += float64(r.RSS)
+		fds += float64(r.FDs)
+		threads += float64(r.Threads)
+		disk += float64(r.Disk)
+	}
+	n := float64(len(samples))
+	u.MeanRSS = uint64(rss / n)
+	u.MeanFDs = fds / n
+	u.MeanThreads = threads / n
+	u.MeanDisk = int64(disk / n)
+	last := samples[len(samples)-1]
+	u.CPU = last.CPU
+	u.DiskGrowth = last.Disk - samples[0].Disk
+	return u
+}
+
+// resourceTargetFor returns the sampling target of a driver's server from
+// the -servers file, and false when there is nothing to sample.
+func resourceTargetFor(cfg *Config, spec *driverSpec) (resourceTarget, bool) {
+	if cfg.Servers == nil {
+		return resourceTarget{}, false
+	}
+	name := spec.Server
+	if name == "" {
+		name = spec.Name
+	}
+	servers, err := cfg.Servers.selectServers([]string{name})
+	if err != nil {
+		return resourceTarget{}, false
+	}
+	s := servers[0]
+	t := resourceTarget{Proc: "/proc", Data: s.Data}
+	// Elsewhere only the data directories can be sampled.
+	if runtime.GOOS == "linux" {
+		t.Pid = cfg.Servers.serverPid(s)
+	}
+	return t, t.Pid != 0 || len(t.Data) > 0
+}
+
+// markSampler is the sampler of the benchmark running, if any. Results
+// sample through it as they are created and as each run ends, so the edges
+// of their timed runs are measured rather than interpolated.
+var markSampler atomic.Pointer[resourceSampler]
+
+// markResources takes a sample when a benchmark is being sampled.
+func markResources() {
+	if s := markSampler.Load(); s != nil {
+		s.sample()
+	}
+}
+
+// sampleResources runs benchmarks while sampling their server, when the
+// -servers file names one, and attaches to each result the usage over its
+// timed runs.
+func sampleResources(cfg *Config, spec *driverSpec, run func() []*BenchmarkResult) []*BenchmarkResult {
+	target, ok := resourceTargetFor(cfg, spec)
+	if !ok {
+		return run()
+	}
+	s := newResourceSampler(target, cfg.SampleInterval)
+	s.start()
+	markSampler.Store(s)
+	results := run()
+	markSampler.Store(nil)
+	s.stop()
+	for _, r := range results {
+		r.Resources = s.usageOver(r.runWindows)
+	}
+	if s.procErr != nil {
+		fmt.Fprintf(os.Stderr, "%s: cannot sample server process: %v\n", spec.Name, s.procErr)
+	}
+	return results
+}
+
+// OpsPerCPUSecond returns the operations completed per second of server
+// CPU time, 0 without resource samples.
+func (r *BenchmarkResult) OpsPerCPUSecond() float64 {
+	if r.Resources == nil || r.Resources.CPU <= 0 {
+		return 0
+	}
+	return float64(r.TotalOps) / r.Resources.CPU.Seconds()
+}
+
+// BytesPerPoint returns how much the server's data grew per operation,
+// meaningful for write benchmarks; 0 when it did not grow.
+func (r *BenchmarkResult) BytesPerPoint() float64 {
+	if r.Resources == nil || r.Resources.DiskGrowth <= 0 || r.TotalOps == 0 {
+		return 0
+	}
+	return float64(r.Resources.DiskGrowth) / float64(r.TotalOps)
+}
+
+// fmtBytes renders a byte count with a binary unit.
+func fmtBytes(n float64) string {
+	for _, u := range []struct {
+		name string
+		size float64
+	}{{"GB", 1 << 30}, {"MB", 1 << 20}, {"KB", 1 << 10}} {
+		if n >= u.size {
+			return fmt.Sprintf("%.1f %s", n/u.size, u.name)
+		}
+	}
+	return fmt.Sprintf("%.0f B", n)
+}
@@ -8,12 +8,19 @@ import (
 )
 
 type BenchmarkResult struct {
-	Name          string
-	DriverName    string
+	Name           string
+	DriverName     string
 	OperationCount int
-	successCount  uint64
-	failureCount  uint64
-	Durations     []time.Duration
+	successCount   uint64
+	failureCount   uint64
+	Durations      []time.Duration
+	// runOps is the number of operations each run completed, runWindows
+	// when it ran and runLatency the latency buckets it filled;
+	// latencyMark holds Latency's counts at the end of the last run.
+	runOps      []uint64
+	runWindows  [][2]time.Time
+	runLatency  [][]histCount
+	latencyMark []uint64
 
 	Min       time.Duration
 	Max       time.Duration
@@ -24,6 +31,52 @@ type BenchmarkResult struct {
 	P99       time.Duration
 	OpsPerSec float64
 	TotalOps  int64
+
+	// OpsPerSecCI and MeanCI are confidenceLevel Student-t intervals of the
+	// mean per-run throughput and run time; zero with fewer than two runs.
+	OpsPerSecCI [2]float64
+	MeanCI      [2]time.Duration
+
+	// Latency holds every individual operation's latency; the fields above
+	// are derived from whole-run wall times.
+	Latency   *latencyHistogram
+	OpLatency latencySummary
+	// OpP50CI and OpP99CI are confidenceLevel bootstrap intervals over runs
+	// of OpLatency's P50 and P99; zero with fewer than two runs.
+	OpP50CI [2]time.Duration
+	OpP99CI [2]time.Duration
+
+	// Mode describes how load was generated; empty for closed-loop runs.
+	Mode         string
+	TargetRate   float64
+	AchievedRate float64
+
+	// Timeline holds per-second throughput and latency for time-based runs.
+	Timeline []timelinePoint
+
+	// Verification is the driver's -verify outcome; VerifyFailed marks
+	// drivers that lost or corrupted data.
+	Verification string
+	VerifyFailed bool
+
+	// Workers is the -concurrency level the result was measured at; 0
+	// outside a sweep.
+	Workers int
+
+	// FaultProfile names the -fault profile the run was made under.
+	FaultProfile string
+
+	// Cardinality is the -cardinality level (keys in the keyspace) and
+	// KeyDist the key distribution of high-cardinality results.
+	// ServerMemory is the server's resident memory after the level ran, 0
+	// when the driver cannot report it.
+	Cardinality  int
+	KeyDist      string
+	ServerMemory uint64
+
+	// Resources is the server's resource usage while the benchmark ran,
+	// nil unless -servers names the driver's server.
+	Resources *resourceUsage
 }
 
 type atomicAccumulator struct {
@@ -37,20 +90,54 @@ func (a *atomicAccumulator) successCount() uint64 { return a.success.Load() }
 func (a *atomicAccumulator) failureCount() uint64 { return a.failure.Load() }
 
 func newBenchResult(name, driver string, opsPerRun int) *BenchmarkResult {
+	markResources()
 	return &BenchmarkResult{
-		Name:          name,
-		DriverName:    driver,
+		Name:           name,
+		DriverName:     driver,
 		OperationCount: opsPerRun,
+		Latency:        newLatencyHistogram(),
 	}
 }
 
+// recordOp records the latency of a single Write/Read/MultiRead call.
+func (r *BenchmarkResult) recordOp(d time.Duration) {
+	r.Latency.Record(d)
+}
+
 func (r *BenchmarkResult) addRun(d time.Duration, success, failure uint64) {
+	end := time.Now()
+	markResources()
 	r.Durations = append(r.Durations, d)
+	r.runOps = append(r.runOps, success+failure)
+	r.runWindows = append(r.runWindows, [2]time.Time{end.Add(-d), end})
+	var lat []histCount
+	lat, r.latencyMark = r.Latency.countsSince(r.latencyMark)
+	r.runLatency = append(r.runLatency, lat)
 	r.successCount += success
 	r.failureCount += failure
 }
 
+// setOpenLoop marks the result as produced by an open-loop run. Latency then
+// holds coordinated-omission-corrected values. For ramps, TargetRate is the
+// mean requested rate.
+func (r *BenchmarkResult) setOpenLoop(sched openLoopSchedule, ops uint64, elapsed time.Duration) {
+	r.Mode = sched.describe()
+	switch s := sched.(type) {
+	case fixedRate:
+		r.TargetRate = s.rate
+	case rampRate:
+		r.TargetRate = (s.from + s.to) / 2
+	}
+	if elapsed > 0 {
+		r.AchievedRate = float64(ops) / elapsed.Seconds()
+	}
+}
+
 func (r *BenchmarkResult) compute() {
+	r.OpLatency = r.Latency.Summary()
+	if cis, ok := latencyCI(r.runLatency, []float64{0.50, 0.99}, confidenceLevel); ok {
+		r.OpP50CI, r.OpP99CI = cis[0], cis[1]
+	}
 	if len(r.Durations) == 0 {
 		return
 	}
@@ -86,6 +173,27 @@ func (r *BenchmarkResult) compute() {
 		r.OpsPerSec = float64(r.OperationCount) / r.Mean.Seconds()
 	}
 	r.TotalOps = int64(r.OperationCount) * int64(n)
+	if r.varyingOps() {
+		r.TotalOps = 0
+		for _, ops := range r.runOps {
+			r.TotalOps += int64(ops)
+		}
+	}
+
+	secs := make([]float64, n)
+	for i, d := range r.Durations {
+		secs[i] = d.Seconds()
+	}
+	if lo, hi, ok := meanCI(secs, confidenceLevel); ok {
+		r.MeanCI = [2]time.Duration{secondsToDuration(max(lo, 0)), secondsToDuration(hi)}
+	}
+	if lo, hi, ok := meanCI(r.RunOpsPerSec(), confidenceLevel); ok {
+		r.OpsPerSecCI = [2]float64{max(lo, 0), hi}
+	}
+}
+
+func secondsToDuration(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
 }
 
 func (r *BenchmarkResult) SuccessRate() float64 {
@@ -96,6 +204,28 @@ func (r *BenchmarkResult) SuccessRate() float64 {
 	return float64(r.successCount) / float64(total) * 100
 }
 
+// varyingOps reports whether each run completed its own number of
+// operations, as time-based, open-loop and scenario runs do. Other runs
+// all perform OperationCount operations.
+func (r *BenchmarkResult) varyingOps() bool { return r.Mode != "" }
+
+// RunOpsPerSec returns the throughput of each run, the samples OpsPerSec
+// averages over.
+func (r *BenchmarkResult) RunOpsPerSec() []float64 {
+	ops := make([]float64, 0, len(r.Durations))
+	for i, d := range r.Durations {
+		if d <= 0 {
+			continue
+		}
+		n := float64(r.OperationCount)
+		if r.varyingOps() {
+			n = float64(r.runOps[i])
+		}
+		ops = append(ops, n/d.Seconds())
+	}
+	return ops
+}
+
 func percentile(durations []time.Duration, p float64) time.Duration {
 	if len(durations) == 0 {
 		return 0
@@ -118,14 +118,11 @@ if ($jsonLines.Count -gt 0) {
     $raw | Out-File "$JsonFile.raw.txt" -Encoding utf8
 }
 
-# 5. Generate report
+# 5. Generate report from the run just saved to results\
 Write-Host ">>> Step 5: Generating report..." -ForegroundColor Yellow
-$venvPython = "$RepoRoot\.venv\Scripts\python.exe"
-if (Test-Path $venvPython) {
-    & $venvPython "$BenchDir\generate_report.py"
-} else {
-    Write-Warning "  Python venv not found - skipping report"
-}
+Push-Location $BenchDir
+& go run . report "--out=$ReportDir" latest
+Pop-Location
 
 # Done
 Write-Host "============================================" -ForegroundColor Cyan
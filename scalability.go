@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+)
+
+// kneeFraction defines a curve's knee: the fewest workers reaching this
+// fraction of the curve's peak throughput. Adding workers beyond it mostly
+// adds latency.
+const kneeFraction = 0.9
+
+// scalabilityCurve is one benchmark/driver pair measured at every
+// -concurrency level, in ascending worker order.
+type scalabilityCurve struct {
+	Name   string
+	Driver string
+	Points []*BenchmarkResult
+	// Knee is the worker count at the knee of the curve.
+	Knee int
+}
+
+// scalabilityCurves groups sweep results into curves, in the order the
+// benchmark/driver pairs first appear.
+func scalabilityCurves(results []*BenchmarkResult) []*scalabilityCurve {
+	var curves []*scalabilityCurve
+	index := make(map[[2]string]*scalabilityCurve)
+	for _, r := range results {
+		if r.Workers == 0 {
+			continue
+		}
+		id := [2]string{r.Name, r.DriverName}
+		c := index[id]
+		if c == nil {
+			c = &scalabilityCurve{Name: r.Name, Driver: r.DriverName}
+			index[id] = c
+			curves = append(curves, c)
+		}
+		c.Points = append(c.Points, r)
+	}
+	for _, c := range curves {
+		var peak float64
+		for _, p := range c.Points {
+			peak = max(peak, p.OpsPerSec)
+		}
+		for _, p := range c.Points {
+			if p.OpsPerSec >= kneeFraction*peak {
+				c.Knee = p.Workers
+				break
+			}
+		}
+	}
+	return curves
+}
+
+// printScalability prints throughput and tail latency against workers for
+// every curve, marking each knee.
+func printScalability(results []*BenchmarkResult) {
+	curves := scalabilityCurves(results)
+	if len(curves) == 0 {
+		return
+	}
+
+	fmt.Println("\n=== SCALABILITY ===")
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "Benchmark\tDriver\tWorkers\tOps/sec\tSpeedup\tOp P50\tOp P99\tOp P99.9\t\n")
+	for _, c := range curves {
+		base := c.Points[0].OpsPerSec
+		for _, p := range c.Points {
+			speedup := "-"
+			if base > 0 {
+				speedup = fmt.Sprintf("%.2fx", p.OpsPerSec/base)
+			}
+			knee := ""
+			if p.Workers == c.Knee {
+				knee = "<- knee"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%d\t%.0f\t%s\t%s\t%s\t%s\t%s\n",
+				c.Name, c.Driver, p.Workers, p.OpsPerSec, speedup,
+				p.OpLatency.P50, p.OpLatency.P99, p.OpLatency.P999, knee)
+		}
+	}
+	w.Flush()
+}
@@ -0,0 +1,268 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Scenario describes a mixed read/write workload loaded from a YAML or JSON
+// file via -scenario. JSON files are accepted because JSON is valid YAML.
+type Scenario struct {
+	Name      string   `yaml:"name"`
+	Databases []string `yaml:"databases"`
+
+	Keys       int    `yaml:"keys"`
+	KeyPattern string `yaml:"key_pattern"`
+	Preload    int    `yaml:"preload"` // points written per key before the run
+
+	ReadRatio float64 `yaml:"read_ratio"` // fraction of operations that are reads
+	BatchSize int     `yaml:"batch_size"` // points per write; 1 uses Write, >1 uses WriteBatch
+
+	Read ScenarioRead `yaml:"read"`
+
+	Concurrency int           `yaml:"concurrency"`
+	Duration    time.Duration `yaml:"duration"`
+	Seed        uint64        `yaml:"seed"`
+}
+
+// ScenarioRead selects the read query shape. Exactly one of LastX and Range is used.
+type ScenarioRead struct {
+	LastX     int           `yaml:"lastx"`
+	Range     time.Duration `yaml:"range"`      // read the window [now-range, now]
+	MultiKeys int           `yaml:"multi_keys"` // keys per read; >1 uses MultiRead
+}
+
+func loadScenario(path string) (*Scenario, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	sc := &Scenario{
+		KeyPattern:  "scenario_sensor_%d",
+		BatchSize:   1,
+		Concurrency: 1,
+		Seed:        1,
+	}
+	if err := yaml.Unmarshal(data, sc); err != nil {
+		return nil, fmt.Errorf("scenario %s: %w", path, err)
+	}
+	if sc.Name == "" {
+		base := filepath.Base(path)
+		sc.Name = strings.TrimSuffix(base, filepath.Ext(base))
+	}
+	if err := sc.Validate(); err != nil {
+		return nil, fmt.Errorf("scenario %s: %w", path, err)
+	}
+	return sc, nil
+}
+
+func (sc *Scenario) Validate() error {
+	if sc.Keys <= 0 {
+		return fmt.Errorf("keys must be positive")
+	}
+	if !strings.Contains(sc.KeyPattern, "%d") {
+		return fmt.Errorf("key_pattern must contain %%d")
+	}
+	if sc.ReadRatio < 0 || sc.ReadRatio > 1 {
+		return fmt.Errorf("read_ratio must be between 0 and 1")
+	}
+	if sc.BatchSize <= 0 {
+		return fmt.Errorf("batch_size must be positive")
+	}
+	if sc.Concurrency <= 0 {
+		return fmt.Errorf("concurrency must be positive")
+	}
+	if sc.Duration <= 0 {
+		return fmt.Errorf("duration must be positive")
+	}
+	if sc.ReadRatio > 0 {
+		if sc.Read.LastX <= 0 && sc.Read.Range <= 0 {
+			return fmt.Errorf("read needs lastx or range")
+		}
+		if sc.Read.LastX > 0 && sc.Read.Range > 0 {
+			return fmt.Errorf("read lastx and range are mutually exclusive")
+		}
+		if sc.Read.MultiKeys > sc.Keys {
+			return fmt.Errorf("read multi_keys exceeds keys")
+		}
+		if sc.Read.MultiKeys > 1 && sc.Read.Range > 0 {
+			return fmt.Errorf("multi-key reads only support lastx")
+		}
+	}
+	return nil
+}
+
+func (sc *Scenario) key(i int) string {
+	return fmt.Sprintf(sc.KeyPattern, i)
+}
+
+// checkDriver reports whether d offers every capability the scenario uses.
+func (sc *Scenario) checkDriver(d Driver) error {
+	if sc.ReadRatio < 1 || sc.Preload > 0 {
+		if _, ok := d.(Writer); !ok {
+			return fmt.Errorf("%s does not support writes", d.Name())
+		}
+	}
+	if sc.ReadRatio > 0 {
+		switch {
+		case sc.Read.Range > 0:
+			if _, ok := d.(RangeReader); !ok {
+				return fmt.Errorf("%s does not support time-range reads", d.Name())
+			}
+		case sc.Read.MultiKeys > 1:
+			if _, ok := d.(MultiReader); !ok {
+				return fmt.Errorf("%s does not support multi-key reads", d.Name())
+			}
+		default:
+			if _, ok := d.(Reader); !ok {
+				return fmt.Errorf("%s does not support reads", d.Name())
+			}
+		}
+	}
+	return nil
+}
+
+// runScenario executes the scenario against d and returns one result for the
+// write side and one for the read side of the workload (omitting empty ones).
+func runScenario(sc *Scenario, d Driver) ([]*BenchmarkResult, error) {
+	if err := sc.checkDriver(d); err != nil {
+		return nil, err
+	}
+	ctx := context.Background()
+
+	if sc.Preload > 0 {
+		if err := sc.preload(ctx, d.(Writer)); err != nil {
+			return nil, fmt.Errorf("%s preload: %w", d.Name(), err)
+		}
+	}
+
+	writes := newBenchResult("Scenario "+sc.Name+" (write)", d.Name(), 0)
+	reads := newBenchResult("Scenario "+sc.Name+" (read)", d.Name(), 0)
+	writeTL, readTL := newTimeline(1), newTimeline(1)
+	var writeAcc, readAcc atomicAccumulator
+
+	var wg sync.WaitGroup
+	start := time.Now()
+	writeTL.begin(start, nil)
+	readTL.begin(start, nil)
+	deadline := start.Add(sc.Duration)
+
+	for i := 0; i < sc.Concurrency; i++ {
+		wg.Add(1)
+		go func(worker uint64) {
+			defer wg.Done()
+			rng := rand.New(rand.NewPCG(sc.Seed, worker))
+			for {
+				opStart := time.Now()
+				if !opStart.Before(deadline) {
+					return
+				}
+				if rng.Float64() < sc.ReadRatio {
+					err := sc.read(ctx, d, rng)
+					lat := time.Since(opStart)
+					reads.recordOp(lat)
+					readTL.record(opStart, lat, err)
+					if err == nil {
+						readAcc.addSuccess(1)
+					} else {
+						readAcc.addFailure(1)
+					}
+				} else {
+					n, err := sc.write(ctx, d.(Writer), rng)
+					lat := time.Since(opStart)
+					writes.recordOp(lat)
+					writeTL.record(opStart, lat, err)
+					if err == nil {
+						writeAcc.addSuccess(n)
+					} else {
+						writeAcc.addFailure(n)
+					}
+				}
+			}
+		}(uint64(i))
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+
+	var results []*BenchmarkResult
+	for _, r := range []struct {
+		result *BenchmarkResult
+		acc    *atomicAccumulator
+		tl     *timeline
+	}{{writes, &writeAcc, writeTL}, {reads, &readAcc, readTL}} {
+		s, f := r.acc.successCount(), r.acc.failureCount()
+		if s+f == 0 {
+			continue
+		}
+		r.result.OperationCount = int(s + f)
+		r.result.Mode = fmt.Sprintf("scenario, %d workers", sc.Concurrency)
+		r.result.addRun(elapsed, s, f)
+		r.result.Timeline = r.tl.points()
+		r.result.compute()
+		results = append(results, r.result)
+	}
+	return results, nil
+}
+
+// write performs one write operation and returns the number of points it carried.
+func (sc *Scenario) write(ctx context.Context, w Writer, rng *rand.Rand) (uint64, error) {
+	if sc.BatchSize == 1 {
+		return 1, w.Write(ctx, sc.key(rng.IntN(sc.Keys)), rng.Float64()*100)
+	}
+	ts := time.Now().Unix()
+	points := make([]KeyedPoint, sc.BatchSize)
+	for i := range points {
+		points[i] = KeyedPoint{Key: sc.key(rng.IntN(sc.Keys)), Value: rng.Float64() * 100, Timestamp: ts + int64(i)}
+	}
+	return uint64(len(points)), w.WriteBatch(ctx, points)
+}
+
+func (sc *Scenario) read(ctx context.Context, d Driver, rng *rand.Rand) error {
+	switch {
+	case sc.Read.Range > 0:
+		end := time.Now().Unix()
+		start := end - int64(sc.Read.Range/time.Second)
+		_, err := d.(RangeReader).ReadRange(ctx, sc.key(rng.IntN(sc.Keys)), start, end)
+		return err
+	case sc.Read.MultiKeys > 1:
+		keys := make([]string, 0, sc.Read.MultiKeys)
+		seen := make(map[int]bool, sc.Read.MultiKeys)
+		for len(keys) < sc.Read.MultiKeys {
+			k := rng.IntN(sc.Keys)
+			if !seen[k] {
+				seen[k] = true
+				keys = append(keys, sc.key(k))
+			}
+		}
+		_, err := d.(MultiReader).MultiRead(ctx, keys, sc.Read.LastX)
+		return err
+	default:
+		_, err := d.(Reader).Read(ctx, sc.key(rng.IntN(sc.Keys)), sc.Read.LastX)
+		return err
+	}
+}
+
+// preload writes sc.Preload points per key, one second apart and ending now.
+func (sc *Scenario) preload(ctx context.Context, w Writer) error {
+	fmt.Printf("Pre-loading %s for scenario %s...\n", w.Name(), sc.Name)
+	rng := rand.New(rand.NewPCG(sc.Seed, 0))
+	base := time.Now().Unix() - int64(sc.Preload)
+	for k := 0; k < sc.Keys; k++ {
+		points := make([]KeyedPoint, sc.Preload)
+		for j := range points {
+			points[j] = KeyedPoint{Key: sc.key(k), Value: rng.Float64() * 100, Timestamp: base + int64(j)}
+		}
+		if err := w.WriteBatch(ctx, points); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,427 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// serverConfig is a servers file: the database servers "benchmark servers"
+// manages. Relative paths are relative to the file, and $VARS in paths,
+// args and env are expanded.
+type serverConfig struct {
+	// State holds each server's pid file and log; default ".servers".
+	State   string         `yaml:"state"`
+	Servers []*serverEntry `yaml:"servers"`
+}
+
+// serverEntry is one server process.
+type serverEntry struct {
+	Name   string   `yaml:"name"`
+	Binary string   `yaml:"binary"`
+	Args   []string `yaml:"args"`
+	// Dir is the working directory; default the file's directory.
+	Dir string            `yaml:"dir"`
+	Env map[string]string `yaml:"env"`
+	// Data lists the files and directories start and wipe delete.
+	Data []string `yaml:"data"`
+	// Health is probed until the server is up: tcp://host:port dials, an
+	// http(s) URL must answer GET with a 2xx status.
+	Health string `yaml:"health"`
+	// Timeout bounds the wait for Health to pass after start and fail
+	// after stop; default 10s.
+	Timeout time.Duration `yaml:"timeout"`
+	// Setup, when set, is POSTed once the server is healthy.
+	Setup *serverSetup `yaml:"setup"`
+}
+
+// serverSetup is a first-run initialisation request, such as InfluxDB's
+// /api/v2/setup.
+type serverSetup struct {
+	URL  string `yaml:"url"`
+	Body string `yaml:"body"`
+}
+
+// defaultServerTimeout is a server's Timeout when the file sets none.
+const defaultServerTimeout = 10 * time.Second
+
+func loadServerConfig(path string) (*serverConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	cfg := &serverConfig{State: ".servers"}
+	if err := yaml.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("servers %s: %w", path, err)
+	}
+	base := filepath.Dir(path)
+	resolve := func(p string) string {
+		p = os.ExpandEnv(p)
+		if p == "" || filepath.IsAbs(p) {
+			return p
+		}
+		return filepath.Join(base, p)
+	}
+	cfg.State = resolve(cfg.State)
+	seen := make(map[string]bool)
+	for _, s := range cfg.Servers {
+		if err := s.validate(); err != nil {
+			return nil, fmt.Errorf("servers %s: %w", path, err)
+		}
+		if seen[s.Name] {
+			return nil, fmt.Errorf("servers %s: duplicate server %q", path, s.Name)
+		}
+		seen[s.Name] = true
+		// Bare command names are looked up on PATH.
+		if s.Binary = os.ExpandEnv(s.Binary); strings.ContainsAny(s.Binary, `/\`) {
+			s.Binary = resolve(s.Binary)
+		}
+		// Relative args are relative to the file too.
+		if s.Dir == "" {
+			s.Dir = base
+		} else {
+			s.Dir = resolve(s.Dir)
+		}
+		for i := range s.Args {
+			s.Args[i] = os.ExpandEnv(s.Args[i])
+		}
+		for k, v := range s.Env {
+			s.Env[k] = os.ExpandEnv(v)
+		}
+		for i := range s.Data {
+			s.Data[i] = resolve(s.Data[i])
+		}
+		if s.Timeout == 0 {
+			s.Timeout = defaultServerTimeout
+		}
+	}
+	return cfg, nil
+}
+
+func (s *serverEntry) validate() error {
+	if s.Name == "" || s.Binary == "" {
+		return fmt.Errorf("every server needs a name and a binary")
+	}
+	u, err := url.Parse(s.Health)
+	if err != nil || u.Host == "" || !slices.Contains([]string{"tcp", "http", "https"}, u.Scheme) {
+		return fmt.Errorf("%s: health must be tcp://host:port or an http(s) URL, got %q", s.Name, s.Health)
+	}
+	if s.Timeout < 0 {
+		return fmt.Errorf("%s: timeout must not be negative", s.Name)
+	}
+	return nil
+}
+
+// selectServers returns the servers named, or all of them.
+func (c *serverConfig) selectServers(names []string) ([]*serverEntry, error) {
+	if len(names) == 0 {
+		return c.Servers, nil
+	}
+	var out []*serverEntry
+	for _, name := range names {
+		i := slices.IndexFunc(c.Servers, func(s *serverEntry) bool { return s.Name == name })
+		if i < 0 {
+			return nil, fmt.Errorf("unknown server %q", name)
+		}
+		out = append(out, c.Servers[i])
+	}
+	return out, nil
+}
+
+func (c *serverConfig) pidFile(s *serverEntry) string { return filepath.Join(c.State, s.Name+".pid") }
+func (c *serverConfig) logFile(s *serverEntry) string { return filepath.Join(c.State, s.Name+".log") }
+
+// probeServer checks a health address once: the vmDriver.Connect-style GET
+// for URLs, a dial for tcp://.
+func probeServer(ctx context.Context, health string) error {
+	u, err := url.Parse(health)
+	if err != nil {
+		return err
+	}
+	if u.Scheme == "tcp" {
+		var d net.Dialer
+		conn, err := d.DialContext(ctx, "tcp", u.Host)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", health, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("health check returned %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// waitHealth polls s's health address until it reports up == want or s's
+// timeout passes. exited, when not nil, aborts the wait as soon as the
+// process ends.
+func waitHealth(s *serverEntry, want bool, exited <-chan error) error {
+	deadline := time.Now().Add(s.Timeout)
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		err := probeServer(ctx, s.Health)
+		cancel()
+		if (err == nil) == want {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			if want {
+				return fmt.Errorf("%s not healthy after %s: %v", s.Name, s.Timeout, err)
+			}
+			return fmt.Errorf("%s still answering on %s after %s", s.Name, s.Health, s.Timeout)
+		}
+		select {
+		case err := <-exited:
+			return fmt.Errorf("%s exited during startup: %v", s.Name, err)
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+}
+
+// wipeData deletes s's data paths.
+func wipeData(s *serverEntry) error {
+	for _, p := range s.Data {
+		if clean := filepath.Clean(p); clean == filepath.Dir(clean) {
+			return fmt.Errorf("%s: refusing to wipe %s", s.Name, p)
+		}
+		if err := os.RemoveAll(p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// startServer wipes s's data unless keepData is set, launches it with its
+// output appended to its log and waits for it to become healthy. The
+// process is left running and its pid recorded for stopServer.
+func (c *serverConfig) startServer(s *serverEntry, keepData bool) error {
+	if s.Binary == "" {
+		return fmt.Errorf("%s: binary is empty; is its variable set?", s.Name)
+	}
+	if probeServer(context.Background(), s.Health) == nil {
+		return fmt.Errorf("%s: something already answers on %s; stop it first", s.Name, s.Health)
+	}
+	if !keepData {
+		if err := wipeData(s); err != nil {
+			return err
+		}
+	}
+	if err := os.MkdirAll(c.State, 0o755); err != nil {
+		return err
+	}
+	logf, err := os.OpenFile(c.logFile(s), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	defer logf.Close()
+
+	cmd := exec.Command(s.Binary, s.Args...)
+	cmd.Dir = s.Dir
+	cmd.Stdout, cmd.Stderr = logf, logf
+	cmd.Env = os.Environ()
+	for k, v := range s.Env {
+		cmd.Env = append(cmd.Env, k+"="+v)
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("%s: %w", s.Name, err)
+	}
+	if err := os.WriteFile(c.pidFile(s), []byte(strconv.Itoa(cmd.Process.Pid)+"\n"), 0o644); err != nil {
+		cmd.Process.Kill()
+		return err
+	}
+	exited := make(chan error, 1)
+	go func() { exited <- cmd.Wait() }()
+
+	if err := waitHealth(s, true, exited); err != nil {
+		cmd.Process.Kill()
+		os.Remove(c.pidFile(s))
+		return fmt.Errorf("%w\n%s", err, logTail(c.logFile(s), 10))
+	}
+	if s.Setup != nil {
+		if err := runSetup(s.Setup); err != nil {
+			fmt.Fprintf(os.Stderr, "  %s setup: %v\n", s.Name, err)
+		}
+	}
+	return nil
+}
+
+// runSetup POSTs a setup request. Servers already set up may reject it,
+// so failures are only reported.
+func runSetup(setup *serverSetup) error {
+	resp, err := http.Post(setup.URL, "application/json", strings.NewReader(setup.Body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("%s returned %d", setup.URL, resp.StatusCode)
+	}
+	return nil
+}
+
+// serverPid returns the pid recorded by startServer, 0 if there is none.
+func (c *serverConfig) serverPid(s *serverEntry) int {
+	data, err := os.ReadFile(c.pidFile(s))
+	if err != nil {
+		return 0
+	}
+	pid, _ := strconv.Atoi(strings.TrimSpace(string(data)))
+	return pid
+}
+
+// stopServer interrupts the process startServer recorded, waits for its
+// health address to stop answering and kills it if it does not.
+func (c *serverConfig) stopServer(s *serverEntry) error {
+	pid := c.serverPid(s)
+	if pid == 0 {
+		return nil
+	}
+	defer os.Remove(c.pidFile(s))
+	// A stale pid may since have been reused by another process.
+	if probeServer(context.Background(), s.Health) != nil {
+		return nil
+	}
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return nil
+	}
+	// Windows cannot deliver interrupts; kill straight away there.
+	if err := p.Signal(os.Interrupt); err != nil {
+		p.Kill()
+	}
+	if err := waitHealth(s, false, nil); err != nil {
+		p.Kill()
+		return waitHealth(s, false, nil)
+	}
+	return nil
+}
+
+// logTail returns the last n lines of a log file.
+func logTail(path string, n int) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	lines := bytes.Split(bytes.TrimRight(data, "\n"), []byte("\n"))
+	lines = lines[max(len(lines)-n, 0):]
+	return "  | " + string(bytes.Join(lines, []byte("\n  | ")))
+}
+
+// runServers implements "benchmark servers ACTION [NAME...]".
+func runServers(args []string) int {
+	fs := flag.NewFlagSet("servers", flag.ContinueOnError)
+	path := fs.String("config", "servers.yaml", "Servers file")
+	keepData := fs.Bool("keep-data", false, "Do not wipe data directories before starting")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: benchmark servers [flags] start|stop|restart|status|wipe [NAME...]\n\n")
+		fmt.Fprintf(os.Stderr, "Manages the database servers of a servers file (see servers.yaml). Actions\n")
+		fmt.Fprintf(os.Stderr, "apply to the named servers, or all of them; stop runs in reverse order.\n\nFlags:\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+	action := fs.Arg(0)
+	cfg, err := loadServerConfig(*path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+	servers, err := cfg.selectServers(fs.Args()[1:])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+
+	var failed []error
+	stop := func() {
+		for _, s := range slices.Backward(servers) {
+			if err := cfg.stopServer(s); err != nil {
+				failed = append(failed, err)
+				continue
+			}
+			fmt.Printf("  %-12s stopped\n", s.Name)
+		}
+	}
+	start := func() {
+		for _, s := range servers {
+			if err := cfg.startServer(s, *keepData); err != nil {
+				failed = append(failed, err)
+				continue
+			}
+			fmt.Printf("  %-12s up      pid %-7d %s\n", s.Name, cfg.serverPid(s), s.Health)
+		}
+	}
+	switch action {
+	case "start":
+		start()
+	case "stop":
+		stop()
+	case "restart":
+		stop()
+		start()
+	case "wipe":
+		for _, s := range servers {
+			if probeServer(context.Background(), s.Health) == nil {
+				failed = append(failed, fmt.Errorf("%s is running; stop it before wiping", s.Name))
+				continue
+			}
+			if err := wipeData(s); err != nil {
+				failed = append(failed, err)
+				continue
+			}
+			fmt.Printf("  %-12s wiped\n", s.Name)
+		}
+	case "status":
+		for _, s := range servers {
+			state := "down"
+			if probeServer(context.Background(), s.Health) == nil {
+				state = "up"
+			}
+			pid := "-"
+			if p := cfg.serverPid(s); p != 0 {
+				pid = strconv.Itoa(p)
+			}
+			fmt.Printf("  %-12s %-7s pid %-7s %s\n", s.Name, state, pid, s.Health)
+			if state == "down" {
+				failed = append(failed, fmt.Errorf("%s is down", s.Name))
+			}
+		}
+	default:
+		fmt.Fprintf(os.Stderr, "Error: unknown action %q\n", action)
+		fs.Usage()
+		return 2
+	}
+	if len(failed) > 0 {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", errors.Join(failed...))
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,517 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"os/signal"
+	"slices"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	json "github.com/bytedance/sonic"
+)
+
+// soakConfig configures a soak test.
+type soakConfig struct {
+	Duration    time.Duration `json:"duration"`
+	Checkpoint  time.Duration `json:"checkpoint"`
+	Warmup      time.Duration `json:"warmup"`
+	Keys        int           `json:"keys"`
+	Concurrency int           `json:"concurrency"`
+	Pipeline    int           `json:"pipeline"`
+	BatchSize   int           `json:"batch_size"`
+	ReadEvery   int           `json:"read_every"`
+	ReadLastX   int           `json:"read_lastx"`
+}
+
+func (c soakConfig) Validate() error {
+	if c.Duration <= 0 || c.Checkpoint <= 0 || c.Warmup < 0 {
+		return fmt.Errorf("duration and checkpoint must be positive and warmup not negative")
+	}
+	if c.Keys <= 0 || c.Concurrency <= 0 || c.Pipeline <= 0 {
+		return fmt.Errorf("keys, concurrency and pipeline must be positive")
+	}
+	if c.BatchSize < 0 || c.ReadEvery < 0 || c.ReadLastX <= 0 {
+		return fmt.Errorf("batch-size and read-every must not be negative and read-lastx must be positive")
+	}
+	return nil
+}
+
+// soakHeader is the first line of a soak log.
+type soakHeader struct {
+	DB      string     `json:"db"`
+	Driver  string     `json:"driver"`
+	Config  soakConfig `json:"config"`
+	Started time.Time  `json:"started"`
+}
+
+// soakCheckpoint is every later line of a soak log: what the load did over
+// one checkpoint interval and the server's state at its end.
+type soakCheckpoint struct {
+	Time time.Time `json:"time"`
+	// Elapsed is the soak time so far, across resumes; Segment counts the
+	// runs that made up the soak, 1 until it is resumed.
+	Elapsed      float64 `json:"elapsed_s"`
+	Segment      int     `json:"segment"`
+	Writes       int64   `json:"writes"`
+	Reads        int64   `json:"reads"`
+	Errors       int64   `json:"errors"`
+	TotalWrites  int64   `json:"total_writes"`
+	PointsPerSec float64 `json:"points_per_sec"`
+	P50          float64 `json:"p50_ms"`
+	P99          float64 `json:"p99_ms"`
+	P999         float64 `json:"p99_9_ms"`
+	Max          float64 `json:"max_ms"`
+	RSS          uint64  `json:"rss_bytes,omitempty"`
+	Disk         int64   `json:"disk_bytes,omitempty"`
+}
+
+// soakLog is the JSONL file a soak test checkpoints to. Every line is
+// synced as it is written, so a crashed soak loses at most the interval
+// in progress.
+type soakLog struct {
+	f *os.File
+}
+
+func createSoakLog(path string, h soakHeader) (*soakLog, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if errors.Is(err, os.ErrExist) {
+		return nil, fmt.Errorf("%s exists; pass -resume to continue that soak or remove it", path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	l := &soakLog{f: f}
+	if err := l.append(h); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return l, nil
+}
+
+// resumeSoakLog reopens a soak log for appending, dropping a line left
+// incomplete by a crash.
+func resumeSoakLog(path string) (*soakLog, soakHeader, []soakCheckpoint, error) {
+	h, cps, valid, err := readSoakLog(path)
+	if err != nil {
+		return nil, h, nil, err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY, 0)
+	if err != nil {
+		return nil, h, nil, err
+	}
+	if err := f.Truncate(valid); err == nil {
+		_, err = f.Seek(valid, io.SeekStart)
+	}
+	if err != nil {
+		f.Close()
+		return nil, h, nil, err
+	}
+	return &soakLog{f: f}, h, cps, nil
+}
+
+// readSoakLog reads a soak log and returns the length of its complete
+// lines.
+func readSoakLog(path string) (h soakHeader, cps []soakCheckpoint, valid int64, err error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return h, nil, 0, err
+	}
+	for line := 0; ; line++ {
+		i := bytes.IndexByte(data[valid:], '\n')
+		if i < 0 {
+			break
+		}
+		text := data[valid : valid+int64(i)]
+		if line == 0 {
+			err = json.Unmarshal(text, &h)
+		} else {
+			var cp soakCheckpoint
+			if err = json.Unmarshal(text, &cp); err == nil {
+				cps = append(cps, cp)
+			}
+		}
+		if err != nil {
+			// Only the last line can be torn by a crash.
+			if valid+int64(i)+1 < int64(len(data)) || line == 0 {
+				return h, nil, 0, fmt.Errorf("%s:%d: %w", path, line+1, err)
+			}
+			break
+		}
+		valid += int64(i) + 1
+	}
+	if valid == 0 {
+		return h, nil, 0, fmt.Errorf("%s: not a soak log", path)
+	}
+	return h, cps, valid, nil
+}
+
+func (l *soakLog) append(v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if _, err := l.f.Write(append(data, '\n')); err != nil {
+		return err
+	}
+	return l.f.Sync()
+}
+
+func (l *soakLog) Close() error { return l.f.Close() }
+
+// soakLoop runs cfg's mixed load against w until the soak's total duration,
+// counting prior checkpoints of a resumed soak, or ctx is done. Every
+// cfg.Checkpoint it appends a checkpoint to log, sampling the server's
+// memory through probe (when not nil) and the size of its data.
+func soakLoop(ctx context.Context, cfg soakConfig, w Writer, probe *memoryProbe, data []string, log *soakLog, prior []soakCheckpoint, out io.Writer) ([]soakCheckpoint, error) {
+	cps := prior
+	segment, elapsed, totalWrites := 1, 0.0, int64(0)
+	if n := len(prior); n > 0 {
+		last := prior[n-1]
+		segment, elapsed, totalWrites = last.Segment+1, last.Elapsed, last.TotalWrites
+	}
+	// A remainder too short to checkpoint counts as done.
+	remaining := cfg.Duration - secondsToDuration(elapsed)
+	if remaining < cfg.Checkpoint/2 {
+		return cps, nil
+	}
+
+	keys := make([]string, cfg.Keys)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("soak_%d", i)
+	}
+	load := writeLoad{Keys: keys, Pipeline: cfg.Pipeline, BatchSize: cfg.BatchSize, ReadEvery: cfg.ReadEvery, ReadLastX: cfg.ReadLastX}
+	var counters loadCounters
+	counters.Latency.Store(newLatencyHistogram())
+	// Intervals are timed from before the deadline is set, so a soak run to
+	// its end always adds up to its duration.
+	last := time.Now()
+	loadCtx, cancel := context.WithTimeout(ctx, remaining)
+	defer cancel()
+	var wg sync.WaitGroup
+	wg.Add(cfg.Concurrency)
+	for range cfg.Concurrency {
+		go func() {
+			defer wg.Done()
+			pipelinedWorker(loadCtx, w, load, &counters)
+		}()
+	}
+
+	printSoakHeader(out)
+	var prevWrites, prevReads, prevErrors int64
+	checkpoint := func(now time.Time) error {
+		hist := counters.Latency.Swap(newLatencyHistogram())
+		writes, reads, errs := counters.Writes.Load(), counters.Reads.Load(), counters.Errors.Load()
+		secs := now.Sub(last).Seconds()
+		elapsed += secs
+		totalWrites += writes - prevWrites
+		lat := hist.Summary()
+		ms := func(d time.Duration) float64 { return float64(d) / float64(time.Millisecond) }
+		cp := soakCheckpoint{
+			Time:         now,
+			Elapsed:      elapsed,
+			Segment:      segment,
+			Writes:       writes - prevWrites,
+			Reads:        reads - prevReads,
+			Errors:       errs - prevErrors,
+			TotalWrites:  totalWrites,
+			PointsPerSec: float64(writes-prevWrites) / secs,
+			P50:          ms(lat.P50),
+			P99:          ms(lat.P99),
+			P999:         ms(lat.P999),
+			Max:          ms(lat.Max),
+			Disk:         diskUsage(data),
+		}
+		if probe != nil {
+			if s, err := probe.sample(ctx); err == nil {
+				cp.RSS = uint64(s.RSSMB * (1 << 20))
+			}
+		}
+		last, prevWrites, prevReads, prevErrors = now, writes, reads, errs
+		cps = append(cps, cp)
+		printSoakCheckpoint(out, cp)
+		return log.append(cp)
+	}
+
+	tick := time.NewTicker(cfg.Checkpoint)
+	defer tick.Stop()
+	var err error
+loop:
+	for err == nil {
+		select {
+		case <-loadCtx.Done():
+			break loop
+		case now := <-tick.C:
+			err = checkpoint(now)
+		}
+	}
+	cancel()
+	wg.Wait()
+	// An interrupted soak drops the interval in progress; resuming redoes
+	// it. The last interval is only kept when long enough to measure.
+	if err == nil && ctx.Err() == nil && time.Since(last) >= cfg.Checkpoint/2 {
+		err = checkpoint(time.Now())
+	}
+	return cps, err
+}
+
+func printSoakHeader(w io.Writer) {
+	fmt.Fprintf(w, "%9s  %12s  %9s  %9s  %9s  %7s  %10s  %10s\n", "Elapsed", "Points/s", "P50", "P99", "P99.9", "Errors", "RSS", "Disk")
+}
+
+func printSoakCheckpoint(w io.Writer, cp soakCheckpoint) {
+	fmt.Fprintf(w, "%9s  %12.0f  %7.2fms  %7.2fms  %7.2fms  %7d  %10s  %10s\n",
+		secondsToDuration(cp.Elapsed).Round(time.Second), cp.PointsPerSec, cp.P50, cp.P99, cp.P999, cp.Errors,
+		fmtBytes(float64(cp.RSS)), fmtBytes(float64(cp.Disk)))
+}
+
+// Soak trend verdicts.
+const (
+	verdictLeak           = "MEMORY LEAK"
+	verdictDecay          = "THROUGHPUT DECAY"
+	verdictLatencyDrift   = "LATENCY DRIFT"
+	verdictCompactionDebt = "COMPACTION DEBT"
+	verdictNoTrend        = "no significant trend"
+	verdictTooFew         = "too few checkpoints"
+)
+
+// soakTrend is the trend of one metric over a soak.
+type soakTrend struct {
+	Metric      string
+	First, Last string
+	// Change is the least-squares slope in percent of the metric's mean
+	// per hour, and P the p-value of it being zero.
+	Change  float64
+	P       float64
+	Verdict string
+}
+
+// flagged reports whether the trend's verdict is a problem.
+func (t soakTrend) flagged() bool {
+	return t.Verdict != verdictNoTrend && t.Verdict != verdictTooFew
+}
+
+// analyzeSoak fits a trend to each metric of the checkpoints past warmup.
+// A metric is flagged when it moves the wrong way by more than threshold
+// percent of its mean per hour at significance level alpha: server memory
+// or latency growing, throughput falling, or the disk used per point
+// written growing as compaction falls behind.
+func analyzeSoak(cps []soakCheckpoint, warmup time.Duration, threshold, alpha float64) []soakTrend {
+	var hours []float64
+	var steady []soakCheckpoint
+	for _, cp := range cps {
+		if cp.Elapsed >= warmup.Seconds() {
+			hours = append(hours, cp.Elapsed/3600)
+			steady = append(steady, cp)
+		}
+	}
+	series := func(f func(soakCheckpoint) float64) []float64 {
+		ys := make([]float64, len(steady))
+		for i, cp := range steady {
+			ys[i] = f(cp)
+		}
+		return ys
+	}
+	var trends []soakTrend
+	add := func(metric string, ys []float64, format func(float64) string, up bool, verdict string) {
+		t := soakTrend{Metric: metric, P: math.NaN(), Verdict: verdictTooFew}
+		if len(ys) > 0 {
+			t.First, t.Last = format(ys[0]), format(ys[len(ys)-1])
+		}
+		slope, p := slopeTest(hours, ys)
+		if mean, _ := meanVar(ys); !math.IsNaN(p) && mean != 0 {
+			t.Change, t.P, t.Verdict = slope/mean*100, p, verdictNoTrend
+			if p < alpha && (up && t.Change > threshold || !up && t.Change < -threshold) {
+				t.Verdict = verdict
+			}
+		}
+		trends = append(trends, t)
+	}
+	rate := func(v float64) string { return fmt.Sprintf("%.0f/s", v) }
+	millis := func(v float64) string { return fmt.Sprintf("%.2fms", v) }
+	add("Throughput", series(func(cp soakCheckpoint) float64 { return cp.PointsPerSec }), rate, false, verdictDecay)
+	add("Op p99", series(func(cp soakCheckpoint) float64 { return cp.P99 }), millis, true, verdictLatencyDrift)
+	if slices.ContainsFunc(steady, func(cp soakCheckpoint) bool { return cp.RSS > 0 }) {
+		add("Server RSS", series(func(cp soakCheckpoint) float64 { return float64(cp.RSS) }), fmtBytes, true, verdictLeak)
+	}
+	if slices.ContainsFunc(steady, func(cp soakCheckpoint) bool { return cp.Disk > 0 }) {
+		perPoint := series(func(cp soakCheckpoint) float64 {
+			if cp.TotalWrites == 0 {
+				return 0
+			}
+			return float64(cp.Disk) / float64(cp.TotalWrites)
+		})
+		add("Disk per point", perPoint, func(v float64) string { return fmt.Sprintf("%.1f B", v) }, true, verdictCompactionDebt)
+	}
+	return trends
+}
+
+func printSoakTrends(w io.Writer, trends []soakTrend) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "Metric\tFirst\tLast\tTrend\tp\tVerdict\n")
+	fmt.Fprintf(tw, "------\t-----\t----\t-----\t-\t-------\n")
+	for _, t := range trends {
+		change, p := "-", "-"
+		if !math.IsNaN(t.P) {
+			change = fmt.Sprintf("%+.1f%%/h", t.Change)
+			p = formatP(t.P)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", t.Metric, t.First, t.Last, change, p, t.Verdict)
+	}
+	tw.Flush()
+}
+
+// runSoak implements "benchmark soak", a long-running mixed load that
+// checkpoints to a log it can resume from and flags leaks and decay.
+func runSoak(args []string) int {
+	fs := flag.NewFlagSet("soak", flag.ContinueOnError)
+	scfg := soakConfig{}
+	cfg := &Config{}
+	registerDriverFlags(fs, cfg)
+	db := fs.String("db", "gtsdb", "Database to soak: any driver that can write")
+	fs.DurationVar(&scfg.Duration, "duration", 4*time.Hour, "Total soak time, across resumes")
+	fs.DurationVar(&scfg.Checkpoint, "checkpoint", time.Minute, "How often to checkpoint")
+	fs.DurationVar(&scfg.Warmup, "warmup", 5*time.Minute, "Initial soak time left out of trend detection")
+	fs.IntVar(&scfg.Keys, "keys", 1000, "Keys written and read")
+	fs.IntVar(&scfg.Concurrency, "concurrency", 8, "Parallel pipelined workers")
+	fs.IntVar(&scfg.Pipeline, "pipeline", 16, "Writes in flight per worker burst")
+	fs.IntVar(&scfg.BatchSize, "batch-size", 0, "Points per batch write (0 = single writes)")
+	fs.IntVar(&scfg.ReadEvery, "read-every", 1, "Read once every this many write bursts on average (0 = write only)")
+	fs.IntVar(&scfg.ReadLastX, "read-lastx", 100, "Points per read")
+	pid := fs.Int("pid", 0, "Server process to read RSS from under /proc")
+	serversPath := fs.String("servers", "", "Servers file (see \"benchmark servers\") to find the server process and data in")
+	logPath := fs.String("out", "soak.jsonl", "Checkpoint log")
+	resume := fs.Bool("resume", false, "Continue the soak in -out after a crash or interrupt")
+	analyze := fs.Bool("analyze", false, "Only report the trends of the soak in -out")
+	threshold := fs.Float64("threshold", 5, "Smallest trend, in percent of the mean per hour, that is flagged")
+	alpha := fs.Float64("alpha", significanceLevel, "Significance level of the trend tests")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: benchmark soak [flags]\n\n")
+		fmt.Fprintf(os.Stderr, "Runs a steady mixed read/write load against one database for hours, appending\n")
+		fmt.Fprintf(os.Stderr, "throughput, latency, server RSS and disk size to -out every -checkpoint. Ends by\n")
+		fmt.Fprintf(os.Stderr, "testing each for a trend: memory leaks, throughput decay, latency drift and\n")
+		fmt.Fprintf(os.Stderr, "compaction debt. Exits 1 when one is flagged.\n\nFlags:\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() > 0 {
+		fs.Usage()
+		return 2
+	}
+	fail := func(code int, err error) int {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return code
+	}
+	set := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+
+	if *analyze {
+		h, cps, _, err := readSoakLog(*logPath)
+		if err != nil {
+			return fail(2, err)
+		}
+		return reportSoak(h, cps, *threshold, *alpha)
+	}
+
+	var log *soakLog
+	var h soakHeader
+	var prior []soakCheckpoint
+	var err error
+	if *resume {
+		log, h, prior, err = resumeSoakLog(*logPath)
+		if err != nil {
+			return fail(2, err)
+		}
+		defer log.Close()
+		if set["db"] && *db != h.DB {
+			return fail(2, fmt.Errorf("%s soaked %s, not %s", *logPath, h.DB, *db))
+		}
+		// The load is the one the soak started with; only its length can change.
+		*db = h.DB
+		if set["duration"] {
+			h.Config.Duration = scfg.Duration
+		}
+		scfg = h.Config
+	}
+	if err := scfg.Validate(); err != nil {
+		return fail(2, err)
+	}
+	cfg.Databases = []string{*db}
+	if err := cfg.validateDrivers(); err != nil {
+		return fail(2, err)
+	}
+
+	spec, d, err := openDriver(cfg, *db)
+	if err != nil {
+		return fail(1, err)
+	}
+	defer d.Close()
+	w, ok := d.(Writer)
+	if !ok {
+		return fail(2, fmt.Errorf("%s cannot write", *db))
+	}
+	target, err := serverTarget(spec, *pid, *serversPath)
+	if err != nil {
+		return fail(2, err)
+	}
+	probe, err := newMemoryProbe(spec, d, *pid, *serversPath, "")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Not tracking server memory: %v\n", err)
+	}
+	if log == nil {
+		h = soakHeader{DB: *db, Driver: d.Name(), Config: scfg, Started: time.Now()}
+		if log, err = createSoakLog(*logPath, h); err != nil {
+			return fail(2, err)
+		}
+		defer log.Close()
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	if len(prior) > 0 {
+		fmt.Fprintf(os.Stderr, "Resuming the %s soak at %s of %s\n", d.Name(),
+			secondsToDuration(prior[len(prior)-1].Elapsed).Round(time.Second), scfg.Duration)
+	} else {
+		fmt.Fprintf(os.Stderr, "Soaking %s for %s: %d workers × %d in flight, %d keys, checkpoints to %s\n",
+			d.Name(), scfg.Duration, scfg.Concurrency, scfg.Pipeline, scfg.Keys, *logPath)
+	}
+	cps, err := soakLoop(ctx, scfg, w, probe, target.Data, log, prior, os.Stdout)
+	if err != nil {
+		return fail(1, fmt.Errorf("checkpoint: %w", err))
+	}
+	if ctx.Err() != nil {
+		fmt.Fprintf(os.Stderr, "\nInterrupted; continue with the same driver flags and: benchmark soak -resume -out %s\n", *logPath)
+		reportSoak(h, cps, *threshold, *alpha)
+		return 1
+	}
+	return reportSoak(h, cps, *threshold, *alpha)
+}
+
+// reportSoak prints the trends of a soak and returns the exit code: 1 when
+// one is flagged.
+func reportSoak(h soakHeader, cps []soakCheckpoint, threshold, alpha float64) int {
+	trends := analyzeSoak(cps, h.Config.Warmup, threshold, alpha)
+	elapsed := 0.0
+	if len(cps) > 0 {
+		elapsed = cps[len(cps)-1].Elapsed
+	}
+	fmt.Printf("\n%s soak, %s of %s after %s warm-up:\n", h.Driver,
+		secondsToDuration(elapsed).Round(time.Second), h.Config.Duration, h.Config.Warmup)
+	printSoakTrends(os.Stdout, trends)
+	code := 0
+	for _, t := range trends {
+		if t.flagged() {
+			code = 1
+		}
+	}
+	return code
+}
@@ -0,0 +1,258 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"slices"
+	"time"
+)
+
+// meanVar returns the mean and unbiased sample variance of xs.
+func meanVar(xs []float64) (mean, variance float64) {
+	if len(xs) == 0 {
+		return 0, 0
+	}
+	for _, x := range xs {
+		mean += x
+	}
+	mean /= float64(len(xs))
+	if len(xs) < 2 {
+		return mean, 0
+	}
+	for _, x := range xs {
+		variance += (x - mean) * (x - mean)
+	}
+	return mean, variance / float64(len(xs)-1)
+}
+
+// welchTTest tests whether a and b have the same mean without assuming
+// equal variances. It returns the t statistic, the Welch–Satterthwaite
+// degrees of freedom and the two-sided p-value. Samples of fewer than two
+// values, or two samples without variance, give p = NaN.
+func welchTTest(a, b []float64) (t, df, p float64) {
+	if len(a) < 2 || len(b) < 2 {
+		return math.NaN(), math.NaN(), math.NaN()
+	}
+	ma, va := meanVar(a)
+	mb, vb := meanVar(b)
+	sa, sb := va/float64(len(a)), vb/float64(len(b))
+	if sa+sb == 0 {
+		return math.NaN(), math.NaN(), math.NaN()
+	}
+	t = (ma - mb) / math.Sqrt(sa+sb)
+	df = (sa + sb) * (sa + sb) / (sa*sa/float64(len(a)-1) + sb*sb/float64(len(b)-1))
+	return t, df, studentTTwoSided(t, df)
+}
+
+// studentTTwoSided returns P(|T| >= |t|) for Student's t with df degrees of
+// freedom.
+func studentTTwoSided(t, df float64) float64 {
+	return regIncBeta(df/2, 0.5, df/(df+t*t))
+}
+
+// regIncBeta is the regularized incomplete beta function I_x(a, b).
+func regIncBeta(a, b, x float64) float64 {
+	switch {
+	case x <= 0:
+		return 0
+	case x >= 1:
+		return 1
+	}
+	la, _ := math.Lgamma(a)
+	lb, _ := math.Lgamma(b)
+	lab, _ := math.Lgamma(a + b)
+	front := math.Exp(lab - la - lb + a*math.Log(x) + b*math.Log(1-x))
+	// The continued fraction converges quickly on this side of the mean.
+	if x < (a+1)/(a+b+2) {
+		return front * betaCF(a, b, x) / a
+	}
+	return 1 - front*betaCF(b, a, 1-x)/b
+}
+
+// betaCF evaluates the continued fraction of the incomplete beta function
+// by the modified Lentz method.
+func betaCF(a, b, x float64) float64 {
+	const (
+		maxIter = 300
+		eps     = 1e-14
+		tiny    = 1e-300
+	)
+	c, d := 1.0, 1-(a+b)*x/(a+1)
+	if math.Abs(d) < tiny {
+		d = tiny
+	}
+	d = 1 / d
+	h := d
+	for m := 1; m <= maxIter; m++ {
+		fm := float64(m)
+		for _, num := range []float64{
+			fm * (b - fm) * x / ((a + 2*fm - 1) * (a + 2*fm)),
+			-(a + fm) * (a + b + fm) * x / ((a + 2*fm) * (a + 2*fm + 1)),
+		} {
+			d = 1 + num*d
+			if math.Abs(d) < tiny {
+				d = tiny
+			}
+			c = 1 + num/c
+			if math.Abs(c) < tiny {
+				c = tiny
+			}
+			d = 1 / d
+			h *= d * c
+		}
+		if math.Abs(d*c-1) < eps {
+			break
+		}
+	}
+	return h
+}
+
+// linearSlope returns the least-squares slope of ys against xs, 0 with
+// fewer than two distinct xs.
+func linearSlope(xs, ys []float64) float64 {
+	mx, vx := meanVar(xs)
+	my, _ := meanVar(ys)
+	if len(xs) < 2 || vx == 0 {
+		return 0
+	}
+	var cov float64
+	for i := range xs {
+		cov += (xs[i] - mx) * (ys[i] - my)
+	}
+	return cov / float64(len(xs)-1) / vx
+}
+
+// slopeTest fits ys against xs by least squares and returns the slope and
+// the two-sided p-value of it being zero. p is NaN with fewer than three
+// points or no spread in xs.
+func slopeTest(xs, ys []float64) (slope, p float64) {
+	slope = linearSlope(xs, ys)
+	mx, vx := meanVar(xs)
+	my, _ := meanVar(ys)
+	n := float64(len(xs))
+	if len(xs) < 3 || vx == 0 {
+		return slope, math.NaN()
+	}
+	var sse float64
+	for i := range xs {
+		r := ys[i] - my - slope*(xs[i]-mx)
+		sse += r * r
+	}
+	se := math.Sqrt(sse / (n - 2) / (vx * (n - 1)))
+	switch {
+	case se == 0 && slope == 0:
+		return slope, 1
+	case se == 0:
+		return slope, 0
+	}
+	return slope, studentTTwoSided(slope/se, n-2)
+}
+
+// confidenceLevel is the level of the confidence intervals attached to
+// results; significanceLevel is the p-value below which two drivers differ.
+const (
+	confidenceLevel   = 0.95
+	significanceLevel = 0.05
+)
+
+// studentTQuantile returns the t with P(|T| <= t) = level for Student's t
+// with df degrees of freedom.
+func studentTQuantile(level, df float64) float64 {
+	lo, hi := 0.0, 1.0
+	for studentTTwoSided(hi, df) > 1-level {
+		hi *= 2
+	}
+	for range 100 {
+		mid := (lo + hi) / 2
+		if studentTTwoSided(mid, df) > 1-level {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+	return (lo + hi) / 2
+}
+
+// meanCI returns the Student-t confidence interval at level of the mean of
+// xs; ok is false with fewer than two values.
+func meanCI(xs []float64, level float64) (lo, hi float64, ok bool) {
+	if len(xs) < 2 {
+		return 0, 0, false
+	}
+	mean, variance := meanVar(xs)
+	n := float64(len(xs))
+	half := studentTQuantile(level, n-1) * math.Sqrt(variance/n)
+	return mean - half, mean + half, true
+}
+
+// ratioTest compares two samples of throughput. rel is the half-width at
+// level of the confidence interval of the ratio of their means, relative to
+// the ratio (delta method), and p the Welch t-test p-value. Both are NaN
+// when they cannot be computed.
+func ratioTest(a, b []float64, level float64) (rel, p float64) {
+	_, df, p := welchTTest(a, b)
+	ma, va := meanVar(a)
+	mb, vb := meanVar(b)
+	if math.IsNaN(p) || ma <= 0 || mb <= 0 {
+		return math.NaN(), p
+	}
+	// The relative standard errors of the two means add in quadrature.
+	rse := math.Sqrt(va/(float64(len(a))*ma*ma) + vb/(float64(len(b))*mb*mb))
+	return studentTQuantile(level, df) * rse, p
+}
+
+// bootstrapRounds is how many resamples latencyCI pools.
+const bootstrapRounds = 1000
+
+// latencyCI returns percentile-bootstrap intervals at level of the
+// quantiles qs of the latencies of runs pooled, each run given as the
+// histogram buckets it filled: runs are resampled with replacement and
+// pooled bootstrapRounds times. ok is false with fewer than two runs.
+func latencyCI(runs [][]histCount, qs []float64, level float64) (cis [][2]time.Duration, ok bool) {
+	if len(runs) < 2 {
+		return nil, false
+	}
+	// A fixed seed keeps reports of the same runs identical.
+	rng := rand.New(rand.NewPCG(1, 2))
+	pooled := make([]uint64, histBucketSize)
+	quantiles := make([][]float64, len(qs))
+	for range bootstrapRounds {
+		clear(pooled)
+		var total uint64
+		for range runs {
+			for _, c := range runs[rng.IntN(len(runs))] {
+				pooled[c.Bucket] += c.N
+				total += c.N
+			}
+		}
+		if total == 0 {
+			continue
+		}
+		for i, q := range qs {
+			quantiles[i] = append(quantiles[i], float64(countsQuantile(pooled, total, q)))
+		}
+	}
+	if len(quantiles[0]) == 0 {
+		return nil, false
+	}
+	cis = make([][2]time.Duration, len(qs))
+	for i, xs := range quantiles {
+		slices.Sort(xs)
+		lo := xs[int(float64(len(xs)-1)*(1-level)/2)]
+		hi := xs[int(math.Ceil(float64(len(xs)-1)*(1+level)/2))]
+		cis[i] = [2]time.Duration{time.Duration(lo), time.Duration(hi)}
+	}
+	return cis, true
+}
+
+// formatP renders a p-value the way the comparison reports it.
+func formatP(p float64) string {
+	switch {
+	case p < 0.001:
+		return "p<0.001"
+	case p < 0.01:
+		return "p<0.01"
+	}
+	return fmt.Sprintf("p=%.2f", p)
+}
@@ -0,0 +1,239 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"slices"
+	"strings"
+	"time"
+
+	json "github.com/bytedance/sonic"
+)
+
+// runRecord is a run saved to the results directory: the metadata needed
+// to tell runs apart and the results in their -format=json form.
+type runRecord struct {
+	ID      string        `json:"id"`
+	Meta    runMeta       `json:"meta"`
+	Results []reportEntry `json:"results"`
+}
+
+type runMeta struct {
+	Timestamp   time.Time `json:"timestamp"`
+	GTSDBCommit string    `json:"gtsdb_commit,omitempty"`
+	// Drivers maps each -db driver to the version of its client module.
+	Drivers    map[string]string `json:"drivers"`
+	Benchmarks []string          `json:"benchmarks"`
+	// Flags holds the flags set on the command line, secrets redacted.
+	Flags map[string]string `json:"flags,omitempty"`
+	Host  hostInfo          `json:"host"`
+}
+
+type hostInfo struct {
+	Hostname  string `json:"hostname"`
+	OS        string `json:"os"`
+	Arch      string `json:"arch"`
+	CPUs      int    `json:"cpus"`
+	GoVersion string `json:"go_version"`
+}
+
+// baselineFile, inside the results directory, holds the baseline run's ID.
+const baselineFile = "baseline"
+
+// runIDLayout names runs after their start time, so IDs sort chronologically.
+const runIDLayout = "20060102-150405"
+
+// setFlags returns the flags set on fs, with token values redacted.
+func setFlags(fs *flag.FlagSet) map[string]string {
+	flags := make(map[string]string)
+	fs.Visit(func(f *flag.Flag) {
+		if strings.Contains(f.Name, "token") {
+			flags[f.Name] = "<redacted>"
+			return
+		}
+		flags[f.Name] = f.Value.String()
+	})
+	return flags
+}
+
+// gitCommit returns the HEAD commit of the repository in dir, or "" when
+// there is none.
+func gitCommit(dir string) string {
+	out, err := exec.Command("git", "-C", dir, "rev-parse", "HEAD").Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// driverVersions maps each driver to the version of its client module as
+// built into this binary; in-tree protocols report "in-tree".
+func driverVersions(databases []string) map[string]string {
+	modules := make(map[string]string)
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, dep := range info.Deps {
+			if dep.Replace != nil {
+				dep = dep.Replace
+			}
+			modules[dep.Path] = dep.Version
+		}
+	}
+	versions := make(map[string]string, len(databases))
+	for _, db := range databases {
+		spec, ok := lookupDriver(db)
+		switch {
+		case !ok:
+			continue
+		case spec.Module == "":
+			versions[db] = "in-tree"
+		case modules[spec.Module] != "":
+			versions[db] = spec.Module + "@" + modules[spec.Module]
+		default:
+			versions[db] = spec.Module
+		}
+	}
+	return versions
+}
+
+// newRunRecord describes a run of cfg that started at start.
+func newRunRecord(cfg *Config, results []*BenchmarkResult, start time.Time) runRecord {
+	commit := cfg.GTSDBCommit
+	if commit == "" {
+		commit = gitCommit("..")
+	}
+	hostname, _ := os.Hostname()
+	return runRecord{
+		ID: start.Format(runIDLayout),
+		Meta: runMeta{
+			Timestamp:   start,
+			GTSDBCommit: commit,
+			Drivers:     driverVersions(cfg.Databases),
+			Benchmarks:  cfg.Benchmarks,
+			Flags:       cfg.Flags,
+			Host: hostInfo{
+				Hostname:  hostname,
+				OS:        runtime.GOOS,
+				Arch:      runtime.GOARCH,
+				CPUs:      runtime.NumCPU(),
+				GoVersion: runtime.Version(),
+			},
+		},
+		Results: reportEntries(results),
+	}
+}
+
+// saveRun writes rec to dir as <ID>.json, suffixing the ID if a run
+// started in the same second, and returns the file's path.
+func saveRun(dir string, rec runRecord) (string, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	base := rec.ID
+	for i := 2; ; i++ {
+		path := filepath.Join(dir, rec.ID+".json")
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+		if errors.Is(err, os.ErrExist) {
+			rec.ID = fmt.Sprintf("%s-%d", base, i)
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		enc := json.ConfigDefault.NewEncoder(f)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(rec)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		return path, err
+	}
+}
+
+// loadRun reads a saved run.
+func loadRun(path string) (runRecord, error) {
+	var rec runRecord
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return rec, err
+	}
+	if err := json.Unmarshal(data, &rec); err != nil {
+		return rec, fmt.Errorf("%s: %w", path, err)
+	}
+	return rec, nil
+}
+
+// listRuns returns the IDs of the runs saved in dir, oldest first.
+func listRuns(dir string) ([]string, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]string, len(paths))
+	for i, p := range paths {
+		ids[i] = strings.TrimSuffix(filepath.Base(p), ".json")
+	}
+	slices.Sort(ids)
+	return ids, nil
+}
+
+// resolveRun loads the run ref names: a path to a run file, a run ID,
+// "latest" or "baseline".
+func resolveRun(dir, ref string) (runRecord, error) {
+	if strings.HasSuffix(ref, ".json") {
+		return loadRun(ref)
+	}
+	switch ref {
+	case "latest":
+		ids, err := listRuns(dir)
+		if err != nil {
+			return runRecord{}, err
+		}
+		if len(ids) == 0 {
+			return runRecord{}, fmt.Errorf("no runs saved in %s", dir)
+		}
+		ref = ids[len(ids)-1]
+	case "baseline":
+		id, err := readBaseline(dir)
+		if err != nil {
+			return runRecord{}, err
+		}
+		if id == "" {
+			return runRecord{}, fmt.Errorf("no baseline set in %s; set one with \"benchmark baseline <run>\"", dir)
+		}
+		ref = id
+	}
+	return loadRun(filepath.Join(dir, ref+".json"))
+}
+
+// previousRun returns the ID of the run saved in dir before id.
+func previousRun(dir, id string) (string, error) {
+	ids, err := listRuns(dir)
+	if err != nil {
+		return "", err
+	}
+	i, _ := slices.BinarySearch(ids, id)
+	if i == 0 {
+		return "", fmt.Errorf("no run saved in %s before %s", dir, id)
+	}
+	return ids[i-1], nil
+}
+
+// readBaseline returns the ID of dir's baseline run, "" when none is set.
+func readBaseline(dir string) (string, error) {
+	data, err := os.ReadFile(filepath.Join(dir, baselineFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return "", nil
+	}
+	return strings.TrimSpace(string(data)), err
+}
+
+// writeBaseline makes id dir's baseline run.
+func writeBaseline(dir, id string) error {
+	return os.WriteFile(filepath.Join(dir, baselineFile), []byte(id+"\n"), 0o644)
+}
@@ -0,0 +1,401 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"math"
+	"strings"
+)
+
+// Chart colours, shared with the markdown report's dark theme.
+const (
+	svgBackground = "#1a1a2e"
+	svgPlotArea   = "#16213e"
+	svgGrid       = "#2a2a4a"
+	svgText       = "#e0e0e0"
+)
+
+// driverColors gives each driver the colour it has in every chart; other
+// drivers take colours from fallbackColors in order of appearance.
+var driverColors = map[string]string{
+	"GTSDB":    "#4CAF50",
+	"InfluxDB": "#2196F3",
+	"VM":       "#FF9800",
+	"NSQ":      "#9C27B0",
+}
+
+var fallbackColors = []string{"#E91E63", "#00BCD4", "#CDDC39", "#795548", "#607D8B", "#FFEB3B"}
+
+// chartSeries is one driver's values in a chart. Marks highlights points,
+// such as scalability knees, in line charts.
+type chartSeries struct {
+	Name   string
+	Values []float64
+	Marks  []bool
+}
+
+// seriesColors assigns a colour to each series.
+func seriesColors(series []chartSeries) []string {
+	colors := make([]string, len(series))
+	next := 0
+	for i, s := range series {
+		if c, ok := driverColors[s.Name]; ok {
+			colors[i] = c
+			continue
+		}
+		colors[i] = fallbackColors[next%len(fallbackColors)]
+		next++
+	}
+	return colors
+}
+
+// svgCanvas accumulates the elements of one chart.
+type svgCanvas struct {
+	b    strings.Builder
+	w, h float64
+}
+
+func newSVGCanvas(w, h float64, title string) *svgCanvas {
+	c := &svgCanvas{w: w, h: h}
+	fmt.Fprintf(&c.b, `<svg xmlns="http://www.w3.org/2000/svg" width="%g" height="%g" viewBox="0 0 %g %g" font-family="sans-serif">`+"\n", w, h, w, h)
+	c.rect(0, 0, w, h, svgBackground)
+	c.text(w/2, 28, title, "middle", 16, "bold")
+	return c
+}
+
+func (c *svgCanvas) rect(x, y, w, h float64, fill string) {
+	fmt.Fprintf(&c.b, `<rect x="%.1f" y="%.1f" width="%.1f" height="%.1f" fill="%s"/>`+"\n", x, y, w, h, fill)
+}
+
+func (c *svgCanvas) line(x1, y1, x2, y2 float64, stroke string, width float64) {
+	fmt.Fprintf(&c.b, `<line x1="%.1f" y1="%.1f" x2="%.1f" y2="%.1f" stroke="%s" stroke-width="%g"/>`+"\n", x1, y1, x2, y2, stroke, width)
+}
+
+func (c *svgCanvas) text(x, y float64, s, anchor string, size float64, weight string) {
+	fmt.Fprintf(&c.b, `<text x="%.1f" y="%.1f" fill="%s" font-size="%g" font-weight="%s" text-anchor="%s">%s</text>`+"\n",
+		x, y, svgText, size, weight, anchor, html.EscapeString(s))
+}
+
+// rotatedText draws s rotated by deg degrees about its anchor point.
+func (c *svgCanvas) rotatedText(x, y float64, s, anchor string, size, deg float64) {
+	fmt.Fprintf(&c.b, `<text x="%.1f" y="%.1f" fill="%s" font-size="%g" text-anchor="%s" transform="rotate(%g %.1f %.1f)">%s</text>`+"\n",
+		x, y, svgText, size, anchor, deg, x, y, html.EscapeString(s))
+}
+
+func (c *svgCanvas) polyline(points [][2]float64, stroke, fill string, opacity float64) {
+	fmt.Fprintf(&c.b, `<polyline points="%s" stroke="%s" stroke-width="2" fill="%s" fill-opacity="%g"/>`+"\n",
+		pointList(points), stroke, fill, opacity)
+}
+
+func (c *svgCanvas) circle(x, y, r float64, fill string) {
+	fmt.Fprintf(&c.b, `<circle cx="%.1f" cy="%.1f" r="%g" fill="%s"/>`+"\n", x, y, r, fill)
+}
+
+// star draws a five-pointed star, the marker of highlighted points.
+func (c *svgCanvas) star(x, y, r float64, fill string) {
+	var pts strings.Builder
+	for i := range 10 {
+		rr := r
+		if i%2 == 1 {
+			rr = r * 0.45
+		}
+		a := math.Pi/2 + float64(i)*math.Pi/5
+		fmt.Fprintf(&pts, "%.1f,%.1f ", x+rr*math.Cos(a), y-rr*math.Sin(a))
+	}
+	fmt.Fprintf(&c.b, `<polygon points="%s" fill="%s" stroke="white" stroke-width="1"/>`+"\n", strings.TrimSpace(pts.String()), fill)
+}
+
+// legend draws one swatch per series down the right-hand side.
+func (c *svgCanvas) legend(x, y float64, series []chartSeries, colors []string) {
+	for i, s := range series {
+		yy := y + float64(i)*18
+		c.rect(x, yy-10, 12, 12, colors[i])
+		c.text(x+18, yy, s.Name, "start", 11, "normal")
+	}
+}
+
+func (c *svgCanvas) String() string {
+	return c.b.String() + "</svg>\n"
+}
+
+// valueAxis maps values onto a vertical or horizontal pixel range, on a
+// linear or log10 scale.
+type valueAxis struct {
+	lo, hi   float64
+	log      bool
+	from, to float64
+}
+
+// newValueAxis fits an axis to values, ignoring NaNs and, on log axes,
+// values that are not positive.
+func newValueAxis(values []float64, log bool, from, to float64) valueAxis {
+	lo, hi := math.Inf(1), math.Inf(-1)
+	for _, v := range values {
+		if math.IsNaN(v) || math.IsInf(v, 0) || (log && v <= 0) {
+			continue
+		}
+		lo, hi = math.Min(lo, v), math.Max(hi, v)
+	}
+	a := valueAxis{log: log, from: from, to: to}
+	switch {
+	case math.IsInf(lo, 1):
+		a.lo, a.hi = 0, 1
+		if log {
+			a.lo, a.hi = 1, 10
+		}
+	case log:
+		a.lo = math.Pow(10, math.Floor(math.Log10(lo)))
+		a.hi = math.Pow(10, math.Ceil(math.Log10(hi)))
+		if a.hi <= a.lo {
+			a.hi = a.lo * 10
+		}
+	default:
+		a.lo, a.hi = 0, niceCeil(hi)
+	}
+	return a
+}
+
+// niceCeil rounds v up to 1, 2 or 5 times a power of ten.
+func niceCeil(v float64) float64 {
+	if v <= 0 {
+		return 1
+	}
+	p := math.Pow(10, math.Floor(math.Log10(v)))
+	for _, m := range []float64{1, 2, 5, 10} {
+		if v <= m*p {
+			return m * p
+		}
+	}
+	return 10 * p
+}
+
+func (a valueAxis) pos(v float64) float64 {
+	f := (v - a.lo) / (a.hi - a.lo)
+	if a.log {
+		f = math.Log10(v/a.lo) / math.Log10(a.hi/a.lo)
+	}
+	return a.from + f*(a.to-a.from)
+}
+
+// valid reports whether v can be drawn on the axis.
+func (a valueAxis) valid(v float64) bool {
+	return !math.IsNaN(v) && !math.IsInf(v, 0) && (!a.log || v > 0)
+}
+
+// ticks returns the values gridlines are drawn at: decades on log axes and
+// five steps on linear ones.
+func (a valueAxis) ticks() []float64 {
+	var ticks []float64
+	if a.log {
+		for v := a.lo; v <= a.hi*1.0001; v *= 10 {
+			ticks = append(ticks, v)
+		}
+		return ticks
+	}
+	for i := 0; i <= 5; i++ {
+		ticks = append(ticks, a.lo+(a.hi-a.lo)*float64(i)/5)
+	}
+	return ticks
+}
+
+// formatTick renders an axis value compactly: 1.5k, 20M.
+func formatTick(v float64) string {
+	switch av := math.Abs(v); {
+	case av >= 1e9:
+		return fmt.Sprintf("%gG", v/1e9)
+	case av >= 1e6:
+		return fmt.Sprintf("%gM", v/1e6)
+	case av >= 1e3:
+		return fmt.Sprintf("%gk", v/1e3)
+	}
+	return fmt.Sprintf("%g", v)
+}
+
+// plotFrame draws the plot area and horizontal gridlines of y, labelled.
+func (c *svgCanvas) plotFrame(left, top, right, bottom float64, y valueAxis, yLabel string) {
+	c.rect(left, top, right-left, bottom-top, svgPlotArea)
+	for _, t := range y.ticks() {
+		py := y.pos(t)
+		c.line(left, py, right, py, svgGrid, 1)
+		c.text(left-6, py+4, formatTick(t), "end", 10, "normal")
+	}
+	c.rotatedText(18, (top+bottom)/2, yLabel, "middle", 12, -90)
+}
+
+// barChartSVG draws grouped bars: one group per category, one bar per
+// series. Missing values (NaN or zero) leave a gap.
+func barChartSVG(title, yLabel string, categories []string, series []chartSeries, logY bool) string {
+	const w, h, left, top, right, bottom = 960.0, 460.0, 80.0, 50.0, 820.0, 360.0
+	c := newSVGCanvas(w, h, title)
+	var all []float64
+	for _, s := range series {
+		all = append(all, s.Values...)
+	}
+	y := newValueAxis(all, logY, bottom, top)
+	c.plotFrame(left, top, right, bottom, y, yLabel)
+	colors := seriesColors(series)
+
+	groupW := (right - left) / float64(max(len(categories), 1))
+	barW := groupW * 0.8 / float64(max(len(series), 1))
+	for ci, cat := range categories {
+		gx := left + float64(ci)*groupW + groupW*0.1
+		for si, s := range series {
+			if ci >= len(s.Values) || !y.valid(s.Values[ci]) || s.Values[ci] == 0 {
+				continue
+			}
+			v := s.Values[ci]
+			py := math.Min(y.pos(v), bottom)
+			x := gx + float64(si)*barW
+			c.rect(x, py, barW-2, bottom-py, colors[si])
+			c.rotatedText(x+barW/2, py-4, formatTick(math.Round(v)), "start", 9, -60)
+		}
+		c.rotatedText(left+float64(ci)*groupW+groupW/2, bottom+14, cat, "end", 11, -30)
+	}
+	c.legend(right+20, top+10, series, colors)
+	return c.String()
+}
+
+// lineChart describes a line chart with one line per series over shared x
+// values; XTicks labels them.
+type lineChart struct {
+	Title, XLabel, YLabel string
+	X                     []float64
+	XTicks                []string
+	LogX, LogY            bool
+	Series                []chartSeries
+}
+
+// lineChartSVG draws c. Points a log axis cannot show break their line.
+func lineChartSVG(lc lineChart) string {
+	const w, h, left, top, right, bottom = 960.0, 420.0, 80.0, 50.0, 820.0, 350.0
+	c := newSVGCanvas(w, h, lc.Title)
+	var all []float64
+	for _, s := range lc.Series {
+		all = append(all, s.Values...)
+	}
+	y := newValueAxis(all, lc.LogY, bottom, top)
+	c.plotFrame(left, top, right, bottom, y, lc.YLabel)
+
+	// The x axis always spans the given points, padded by a margin.
+	const pad = 30.0
+	xpos := func(i int) float64 {
+		if len(lc.X) < 2 {
+			return (left + right) / 2
+		}
+		lo, hi, v := lc.X[0], lc.X[len(lc.X)-1], lc.X[i]
+		if lc.LogX {
+			lo, hi, v = math.Log2(lo), math.Log2(hi), math.Log2(v)
+		}
+		if hi == lo {
+			return (left + right) / 2
+		}
+		return left + pad + (v-lo)/(hi-lo)*(right-left-2*pad)
+	}
+	for i := range lc.X {
+		px := xpos(i)
+		c.line(px, bottom, px, bottom+4, svgText, 1)
+		if i < len(lc.XTicks) {
+			c.text(px, bottom+18, lc.XTicks[i], "middle", 10, "normal")
+		}
+	}
+	c.text((left+right)/2, bottom+40, lc.XLabel, "middle", 12, "bold")
+
+	colors := seriesColors(lc.Series)
+	point := func(s chartSeries, i int) ([2]float64, bool) {
+		if i >= len(lc.X) || !y.valid(s.Values[i]) {
+			return [2]float64{}, false
+		}
+		return [2]float64{xpos(i), y.pos(s.Values[i])}, true
+	}
+	// Lines first, so every marker is drawn on top of them.
+	for si, s := range lc.Series {
+		var run [][2]float64
+		for i := 0; i <= len(s.Values); i++ {
+			p, ok := [2]float64{}, false
+			if i < len(s.Values) {
+				p, ok = point(s, i)
+			}
+			if ok {
+				run = append(run, p)
+				continue
+			}
+			if len(run) > 1 {
+				c.polyline(run, colors[si], "none", 0)
+			}
+			run = nil
+		}
+	}
+	for si, s := range lc.Series {
+		for i := range s.Values {
+			p, ok := point(s, i)
+			switch {
+			case !ok:
+			case i < len(s.Marks) && s.Marks[i]:
+				c.star(p[0], p[1], 9, colors[si])
+			default:
+				c.circle(p[0], p[1], 3.5, colors[si])
+			}
+		}
+	}
+	c.legend(right+20, top+10, lc.Series, colors)
+	return c.String()
+}
+
+// radarChartSVG draws one polygon per series over axes, with values on a
+// 0-100 scale.
+func radarChartSVG(title string, axes []string, series []chartSeries) string {
+	const w, h, cx, cy, r = 760.0, 620.0, 330.0, 330.0, 220.0
+	c := newSVGCanvas(w, h, title)
+	n := len(axes)
+	point := func(i int, v float64) (float64, float64) {
+		a := math.Pi/2 - 2*math.Pi*float64(i)/float64(n)
+		return cx + r*v/100*math.Cos(a), cy - r*v/100*math.Sin(a)
+	}
+	for _, ring := range []float64{25, 50, 75, 100} {
+		var pts [][2]float64
+		for i := 0; i <= n; i++ {
+			x, y := point(i%n, ring)
+			pts = append(pts, [2]float64{x, y})
+		}
+		fmt.Fprintf(&c.b, `<polyline points="%s" stroke="%s" stroke-width="1" fill="none"/>`+"\n", pointList(pts), svgGrid)
+	}
+	for i, name := range axes {
+		x, y := point(i, 100)
+		c.line(cx, cy, x, y, svgGrid, 1)
+		lx, ly := point(i, 112)
+		anchor := "middle"
+		switch {
+		case lx < cx-10:
+			anchor = "end"
+		case lx > cx+10:
+			anchor = "start"
+		}
+		c.text(lx, ly+4, strings.ToUpper(name), anchor, 10, "bold")
+	}
+	colors := seriesColors(series)
+	for si, s := range series {
+		var pts [][2]float64
+		for i := 0; i <= n; i++ {
+			v := 0.0
+			if i%n < len(s.Values) && !math.IsNaN(s.Values[i%n]) {
+				v = s.Values[i%n]
+			}
+			x, y := point(i%n, v)
+			pts = append(pts, [2]float64{x, y})
+		}
+		c.polyline(pts, colors[si], colors[si], 0.1)
+		for _, p := range pts[:n] {
+			c.circle(p[0], p[1], 3, colors[si])
+		}
+	}
+	c.legend(w-150, 70, series, colors)
+	return c.String()
+}
+
+func pointList(pts [][2]float64) string {
+	s := make([]string, len(pts))
+	for i, p := range pts {
+		s[i] = fmt.Sprintf("%.1f,%.1f", p[0], p[1])
+	}
+	return strings.Join(s, " ")
+}
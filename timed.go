@@ -0,0 +1,218 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// timelinePoint summarises one second of a run.
+type timelinePoint struct {
+	Run        int
+	Second     int
+	Ops        uint64
+	Errors     uint64
+	TargetRate float64
+	P50        time.Duration
+	P99        time.Duration
+	Max        time.Duration
+}
+
+type timelineBucket struct {
+	ops    uint64
+	errors uint64
+	hist   *latencyHistogram
+}
+
+// timeline buckets operations by the second (relative to the run start) in
+// which they were issued. A nil *timeline discards everything recorded.
+type timeline struct {
+	mu      sync.Mutex
+	run     int
+	start   time.Time
+	rateAt  func(time.Duration) float64
+	buckets []*timelineBucket
+}
+
+func newTimeline(run int) *timeline {
+	return &timeline{run: run}
+}
+
+func (t *timeline) begin(start time.Time, rateAt func(time.Duration) float64) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	t.start = start
+	t.rateAt = rateAt
+	t.mu.Unlock()
+}
+
+func (t *timeline) record(issued time.Time, lat time.Duration, err error) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	sec := int(issued.Sub(t.start) / time.Second)
+	if sec < 0 {
+		sec = 0
+	}
+	for len(t.buckets) <= sec {
+		t.buckets = append(t.buckets, &timelineBucket{hist: newLatencyHistogram()})
+	}
+	b := t.buckets[sec]
+	b.ops++
+	if err != nil {
+		b.errors++
+	}
+	b.hist.Record(lat)
+}
+
+func (t *timeline) points() []timelinePoint {
+	if t == nil {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	points := make([]timelinePoint, len(t.buckets))
+	for i, b := range t.buckets {
+		s := b.hist.Summary()
+		p := timelinePoint{
+			Run:    t.run,
+			Second: i,
+			Ops:    b.ops,
+			Errors: b.errors,
+			P50:    s.P50,
+			P99:    s.P99,
+			Max:    s.Max,
+		}
+		if t.rateAt != nil {
+			p.TargetRate = t.rateAt(time.Duration(i)*time.Second + time.Second/2)
+		}
+		points[i] = p
+	}
+	return points
+}
+
+// runClosedLoopFor calls op back-to-back from workers goroutines (at least
+// one) until d has elapsed. seq numbers the calls across all workers.
+func runClosedLoopFor(ctx context.Context, d time.Duration, workers int, hist *latencyHistogram, tl *timeline, op func(ctx context.Context, seq int) error) (success, failure uint64, elapsed time.Duration) {
+	start := time.Now()
+	tl.begin(start, nil)
+	deadline := start.Add(d)
+	var seq atomic.Int64
+	var acc atomicAccumulator
+	splitWork(workers, max(workers, 1), func(_, _ int) {
+		var s, f uint64
+		for {
+			opStart := time.Now()
+			if !opStart.Before(deadline) {
+				break
+			}
+			err := op(ctx, int(seq.Add(1)-1))
+			lat := time.Since(opStart)
+			hist.Record(lat)
+			tl.record(opStart, lat, err)
+			if err == nil {
+				s++
+			} else {
+				f++
+			}
+		}
+		acc.addSuccess(s)
+		acc.addFailure(f)
+	})
+	return acc.successCount(), acc.failureCount(), time.Since(start)
+}
+
+// runTimedWrite performs single-point writes from workers goroutines for a fixed duration per run.
+func runTimedWrite(w Writer, key string, warmup, runs int, d time.Duration, workers int) *BenchmarkResult {
+	result := newBenchResult("Write (seq)", w.Name(), 0)
+	ctx := context.Background()
+
+	for i := 0; i < warmup; i++ {
+		w.Write(ctx, key, float64(i))
+	}
+
+	var totalOps uint64
+	for run := 0; run < runs; run++ {
+		tl := newTimeline(run + 1)
+		s, f, elapsed := runClosedLoopFor(ctx, d, workers, result.Latency, tl, func(ctx context.Context, seq int) error {
+			return w.Write(ctx, key, float64(seq))
+		})
+		result.addRun(elapsed, s, f)
+		result.Timeline = append(result.Timeline, tl.points()...)
+		totalOps += s + f
+	}
+
+	result.Mode = "duration " + d.String()
+	result.OperationCount = int(totalOps) / runs
+	result.compute()
+	return result
+}
+
+// runTimedRead performs single-key reads from workers goroutines for a fixed duration per run.
+func runTimedRead(r Reader, key string, lastX, runs int, d time.Duration, workers int) *BenchmarkResult {
+	result := newBenchResult("Read (single)", r.Name(), 0)
+	ctx := context.Background()
+
+	r.Read(ctx, key, lastX)
+
+	var totalOps uint64
+	for run := 0; run < runs; run++ {
+		tl := newTimeline(run + 1)
+		s, f, elapsed := runClosedLoopFor(ctx, d, workers, result.Latency, tl, func(ctx context.Context, _ int) error {
+			_, err := r.Read(ctx, key, lastX)
+			return err
+		})
+		result.addRun(elapsed, s, f)
+		result.Timeline = append(result.Timeline, tl.points()...)
+		totalOps += s + f
+	}
+
+	result.Mode = "duration " + d.String()
+	result.OperationCount = int(totalOps) / runs
+	result.compute()
+	return result
+}
+
+// parseRamp parses a ramp spec of the form "10s:1000->100000ops", meaning the
+// target rate rises linearly from 1000 to 100000 ops/sec over 10 seconds.
+func parseRamp(s string) (*rampRate, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil, nil
+	}
+	durStr, rates, ok := strings.Cut(s, ":")
+	if !ok {
+		return nil, fmt.Errorf("invalid ramp %q: expected DURATION:FROM->TO", s)
+	}
+	d, err := time.ParseDuration(durStr)
+	if err != nil || d <= 0 {
+		return nil, fmt.Errorf("invalid ramp duration %q", durStr)
+	}
+	for _, suffix := range []string{"ops/s", "ops", "/s"} {
+		rates = strings.TrimSuffix(rates, suffix)
+	}
+	fromStr, toStr, ok := strings.Cut(rates, "->")
+	if !ok {
+		return nil, fmt.Errorf("invalid ramp %q: expected FROM->TO", s)
+	}
+	from, err := strconv.ParseFloat(strings.TrimSpace(fromStr), 64)
+	if err != nil || from < 0 {
+		return nil, fmt.Errorf("invalid ramp start rate %q", fromStr)
+	}
+	to, err := strconv.ParseFloat(strings.TrimSpace(toStr), 64)
+	if err != nil || to < 0 {
+		return nil, fmt.Errorf("invalid ramp end rate %q", toStr)
+	}
+	if from == 0 && to == 0 {
+		return nil, fmt.Errorf("invalid ramp %q: rate is always zero", s)
+	}
+	return &rampRate{from: from, to: to, duration: d}, nil
+}
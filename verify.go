@@ -0,0 +1,304 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// verifySettle is how long -verify waits between writing the dataset and
+// reading it back, for databases that make writes visible asynchronously.
+const verifySettle = 2 * time.Second
+
+// verifyMaxPoints caps -verify-points. The dataset has one point per second
+// and ends a minute in the past, and the HTTP databases' point reads only
+// look back an hour; this keeps the dataset inside that window with room
+// for slow writes.
+const verifyMaxPoints = 3000
+
+// keyVerification compares what one read path returned for a key with what
+// was written.
+type keyVerification struct {
+	Key        string
+	Path       string // "read" or "multi-read"
+	Expected   int
+	Returned   int
+	Missing    int // written points never returned
+	Duplicated int // points returned more than once
+	Reordered  int // points returned with a timestamp older than the one before
+	Mismatched int // points with a wrong value or a timestamp that was never written
+	Err        error
+}
+
+func (k keyVerification) ok() bool {
+	return k.Err == nil && k.Missing == 0 && k.Duplicated == 0 && k.Reordered == 0 && k.Mismatched == 0
+}
+
+// verifyReport is the outcome of -verify for one driver.
+type verifyReport struct {
+	Driver   string
+	Skipped  string // reason the driver could not be verified
+	WriteErr error
+	Keys     []keyVerification
+}
+
+// failed reports whether the driver lost or corrupted data. Skipped drivers
+// have not failed.
+func (r *verifyReport) failed() bool {
+	if r.WriteErr != nil {
+		return true
+	}
+	for _, k := range r.Keys {
+		if !k.ok() {
+			return true
+		}
+	}
+	return false
+}
+
+// summary describes the report in a few words for tables and comparisons.
+func (r *verifyReport) summary() string {
+	switch {
+	case r.Skipped != "":
+		return "not verified: " + r.Skipped
+	case r.WriteErr != nil:
+		return "failed: write error"
+	}
+	var missing, dup, reord, mism, errs int
+	for _, k := range r.Keys {
+		missing += k.Missing
+		dup += k.Duplicated
+		reord += k.Reordered
+		mism += k.Mismatched
+		if k.Err != nil {
+			errs++
+		}
+	}
+	var parts []string
+	for _, c := range []struct {
+		n    int
+		what string
+	}{{errs, "read errors"}, {missing, "missing"}, {dup, "duplicated"}, {reord, "reordered"}, {mism, "mismatched"}} {
+		if c.n > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", c.n, c.what))
+		}
+	}
+	if len(parts) == 0 {
+		return "ok"
+	}
+	return "failed: " + strings.Join(parts, ", ")
+}
+
+// verifyDataset builds numPoints points for each of numKeys keys, one second
+// apart starting at base. Keys embed base so that repeated runs never read
+// each other's data. Values have three decimals so every driver's text
+// encoding round-trips them exactly.
+func verifyDataset(seed uint64, numKeys, numPoints int, base int64) []KeyedPoint {
+	rng := rand.New(rand.NewPCG(seed, uint64(base)))
+	points := make([]KeyedPoint, 0, numKeys*numPoints)
+	for k := 0; k < numKeys; k++ {
+		key := fmt.Sprintf("verify_%d_%d", base, k)
+		for j := 0; j < numPoints; j++ {
+			points = append(points, KeyedPoint{
+				Key:       key,
+				Value:     float64(rng.IntN(100_000_000)) / 1000,
+				Timestamp: base + int64(j),
+			})
+		}
+	}
+	return points
+}
+
+// compareKey checks got against the points written for one key (ascending by
+// timestamp).
+func compareKey(key, path string, want, got []KeyedPoint) keyVerification {
+	v := keyVerification{Key: key, Path: path, Expected: len(want), Returned: len(got)}
+	values := make(map[int64]float64, len(want))
+	for _, p := range want {
+		values[p.Timestamp] = p.Value
+	}
+	seen := make(map[int64]bool, len(got))
+	for i, p := range got {
+		if seen[p.Timestamp] {
+			v.Duplicated++
+			continue
+		}
+		seen[p.Timestamp] = true
+		if i > 0 && p.Timestamp < got[i-1].Timestamp {
+			v.Reordered++
+		}
+		expected, ok := values[p.Timestamp]
+		if !ok || math.Abs(p.Value-expected) > 1e-9*math.Max(1, math.Abs(expected)) {
+			v.Mismatched++
+		}
+	}
+	for ts := range values {
+		if !seen[ts] {
+			v.Missing++
+		}
+	}
+	return v
+}
+
+// runVerify writes a seeded dataset through d and reads it back through
+// every point-returning read path d offers.
+func runVerify(d Driver, maxBatch int, seed uint64, numKeys, numPoints int, settle time.Duration) *verifyReport {
+	report := &verifyReport{Driver: d.Name()}
+	w, ok := d.(Writer)
+	if !ok {
+		report.Skipped = "no writes"
+		return report
+	}
+	pr, single := d.(PointReader)
+	mpr, multi := d.(MultiPointReader)
+	if !single && !multi {
+		report.Skipped = "reads do not return values"
+		return report
+	}
+
+	ctx := context.Background()
+	// End the dataset a minute in the past: some databases hide the most
+	// recent samples from queries.
+	base := time.Now().Unix() - int64(numPoints) - 60
+	dataset := verifyDataset(seed, numKeys, numPoints, base)
+	batch := maxBatch
+	if batch <= 0 {
+		batch = len(dataset)
+	}
+	for start := 0; start < len(dataset); start += batch {
+		if err := w.WriteBatch(ctx, dataset[start:min(start+batch, len(dataset))]); err != nil {
+			report.WriteErr = err
+			return report
+		}
+	}
+	time.Sleep(settle)
+
+	keys := make([]string, numKeys)
+	want := make(map[string][]KeyedPoint, numKeys)
+	for _, p := range dataset {
+		want[p.Key] = append(want[p.Key], p)
+	}
+	for k := range keys {
+		keys[k] = dataset[k*numPoints].Key
+	}
+
+	if single {
+		for _, key := range keys {
+			got, err := pr.ReadPoints(ctx, key, numPoints)
+			v := compareKey(key, "read", want[key], got)
+			v.Err = err
+			report.Keys = append(report.Keys, v)
+		}
+	}
+	if multi {
+		got, err := mpr.MultiReadPoints(ctx, keys, numPoints)
+		for _, key := range keys {
+			v := compareKey(key, "multi-read", want[key], got[key])
+			v.Err = err
+			report.Keys = append(report.Keys, v)
+		}
+	}
+	return report
+}
+
+// runVerification runs -verify against every selected database.
+func runVerification(cfg *Config) []*verifyReport {
+	var reports []*verifyReport
+	for _, name := range cfg.Databases {
+		spec, d, err := openDriver(cfg, name)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", name, err)
+			continue
+		}
+		fmt.Printf("Verifying %s...\n", d.Name())
+		reports = append(reports, runVerify(d, spec.MaxBatch, 1, cfg.Sensors, cfg.VerifyPoints, verifySettle))
+		d.Close()
+	}
+	return reports
+}
+
+// applyVerification tags each result with its driver's verification outcome.
+func applyVerification(results []*BenchmarkResult, reports []*verifyReport) {
+	byDriver := make(map[string]*verifyReport, len(reports))
+	for _, r := range reports {
+		byDriver[r.Driver] = r
+	}
+	for _, r := range results {
+		if v, ok := byDriver[r.DriverName]; ok {
+			r.Verification = v.summary()
+			r.VerifyFailed = v.failed()
+		}
+	}
+}
+
+// printVerification prints one row per driver and read path, followed by
+// the keys that failed.
+func printVerification(reports []*verifyReport) {
+	fmt.Println("\n=== VERIFICATION ===")
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "Driver\tPath\tKeys\tExpected\tReturned\tMissing\tDuplicated\tReordered\tMismatched\tStatus\n")
+	for _, r := range reports {
+		if r.Skipped != "" || r.WriteErr != nil {
+			fmt.Fprintf(w, "%s\t-\t-\t-\t-\t-\t-\t-\t-\t%s\n", r.Driver, r.summary())
+			continue
+		}
+		type pathTotal struct {
+			keys int
+			keyVerification
+		}
+		var paths []string
+		totals := make(map[string]*pathTotal)
+		for _, k := range r.Keys {
+			t, ok := totals[k.Path]
+			if !ok {
+				t = &pathTotal{}
+				totals[k.Path] = t
+				paths = append(paths, k.Path)
+			}
+			t.keys++
+			t.Expected += k.Expected
+			t.Returned += k.Returned
+			t.Missing += k.Missing
+			t.Duplicated += k.Duplicated
+			t.Reordered += k.Reordered
+			t.Mismatched += k.Mismatched
+			if k.Err != nil && t.Err == nil {
+				t.Err = k.Err
+			}
+		}
+		for _, path := range paths {
+			t := totals[path]
+			status := "ok"
+			if t.Err != nil {
+				status = "error: " + t.Err.Error()
+			} else if !t.ok() {
+				status = "FAILED"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%d\t%d\t%d\t%d\t%d\t%s\n",
+				r.Driver, path, t.keys, t.Expected, t.Returned, t.Missing, t.Duplicated, t.Reordered, t.Mismatched, status)
+		}
+	}
+	w.Flush()
+
+	for _, r := range reports {
+		if r.WriteErr != nil {
+			fmt.Printf("%s: writing the verification dataset failed: %v\n", r.Driver, r.WriteErr)
+		}
+		var failed []string
+		for _, k := range r.Keys {
+			if !k.ok() {
+				failed = append(failed, k.Path+" "+k.Key)
+			}
+		}
+		if len(failed) > 0 {
+			sort.Strings(failed)
+			fmt.Printf("%s: failing keys: %s\n", r.Driver, strings.Join(failed, ", "))
+		}
+	}
+}
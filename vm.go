@@ -4,9 +4,14 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"flag"
 	"fmt"
 	"io"
+	"math"
 	"net/http"
+	"net/url"
+	"os"
+	"strconv"
 	"strings"
 	"time"
 
@@ -31,6 +36,29 @@ func newVMDriver(url string) *vmDriver {
 	}
 }
 
+func init() {
+	registerDriver(&driverSpec{
+		Name: "vm",
+		Flags: func(fs *flag.FlagSet, cfg *Config) {
+			fs.StringVar(&cfg.VMURL, "vm-url", "http://localhost:8428", "VictoriaMetrics URL")
+		},
+		New:       func(cfg *Config) Driver { return newVMDriver(cfg.VMURL) },
+		Endpoints: func(cfg *Config) []endpoint { return []endpoint{urlEndpoint(&cfg.VMURL)} },
+		Runners: map[string]benchRunner{
+			"Multi-Key Write": func(cfg *Config, d Driver, _ driverCaps) *BenchmarkResult {
+				return runMultiWriteVM(d.(*vmDriver), cfg.Count/cfg.Sensors, cfg.Sensors, cfg.Runs, cfg.Workers)
+			},
+			"Multi-Key Read": func(cfg *Config, d Driver, _ driverCaps) *BenchmarkResult {
+				v := d.(*vmDriver)
+				if err := preloadMultiKey(cfg, v); err != nil {
+					fmt.Fprintf(os.Stderr, "%s preload: %v\n", v.Name(), err)
+				}
+				return runReadManyVM(v, cfg.Sensors, multiKeyPoints, cfg.Runs, cfg.Workers)
+			},
+		},
+	})
+}
+
 func (d *vmDriver) Name() string { return "VM" }
 
 func (d *vmDriver) Connect(ctx context.Context) error {
@@ -51,10 +79,14 @@ func (d *vmDriver) Close() error {
 	return nil
 }
 
+func (d *vmDriver) ServerMemory(ctx context.Context) (uint64, error) {
+	return scrapeResidentMemory(ctx, d.client, d.url+"/metrics")
+}
+
 func (d *vmDriver) Write(ctx context.Context, key string, value float64) error {
 	return d.importJSON(ctx, fmt.Sprintf(
 		`{"metric":{"__name__":"benchmark_value","key":"%s"},"values":[%f],"timestamps":[%d]}`+"\n",
-		key, value, time.Now().Unix()))
+		key, value, time.Now().UnixMilli()))
 }
 
 func (d *vmDriver) WriteBatch(ctx context.Context, points []KeyedPoint) error {
@@ -78,7 +110,8 @@ func (d *vmDriver) WriteBatch(ctx context.Context, points []KeyedPoint) error {
 			if i > 0 {
 				buf.WriteByte(',')
 			}
-			fmt.Fprintf(&buf, "%d", p.Timestamp)
+			// /api/v1/import takes milliseconds.
+			fmt.Fprintf(&buf, "%d", p.Timestamp*1000)
 		}
 		buf.WriteString("]}\n")
 	}
@@ -96,9 +129,10 @@ func (d *vmDriver) importJSON(ctx context.Context, body string) error {
 		return err
 	}
 	defer resp.Body.Close()
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
 	io.Copy(io.Discard, resp.Body)
 	if resp.StatusCode >= 300 {
-		return fmt.Errorf("vm import returned %d", resp.StatusCode)
+		return fmt.Errorf("vm import returned %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
 	}
 	return nil
 }
@@ -120,7 +154,26 @@ func (d *vmDriver) readManyCtx(ctx context.Context, client *http.Client, keys []
 		start = 1700000000
 	}
 	query := fmt.Sprintf(`benchmark_value{key=~"%s"}`, strings.Join(keys, "|"))
-	req, err := http.NewRequestWithContext(ctx, "GET", d.url+"/api/v1/query_range", nil)
+	return promQueryRangeCount(ctx, client, d.url, query, start, end, 1)
+}
+
+func (d *vmDriver) ReadRange(ctx context.Context, key string, start, end int64) (int, error) {
+	return promQueryRangeCount(ctx, d.client, d.url, fmt.Sprintf(`benchmark_value{key="%s"}`, key), start, end, 1)
+}
+
+func (d *vmDriver) ReadAggregate(ctx context.Context, key string, start, end, bucket int64, agg string) (int, error) {
+	return promQueryRangeCount(ctx, d.client, d.url, promAggregateQuery(key, bucket, agg), start, end, bucket)
+}
+
+// promAggregateQuery reduces key's samples over each bucket-second step.
+func promAggregateQuery(key string, bucket int64, agg string) string {
+	return fmt.Sprintf(`%s_over_time(benchmark_value{key="%s"}[%ds])`, agg, key, bucket)
+}
+
+// promQueryRangeCount runs a PromQL range query with a step of step seconds
+// against baseURL and returns the number of points returned.
+func promQueryRangeCount(ctx context.Context, client *http.Client, baseURL, query string, start, end, step int64) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", baseURL+"/api/v1/query_range", nil)
 	if err != nil {
 		return 0, err
 	}
@@ -129,7 +182,7 @@ func (d *vmDriver) readManyCtx(ctx context.Context, client *http.Client, keys []
 	q.Set("query", query)
 	q.Set("start", fmt.Sprintf("%d", start))
 	q.Set("end", fmt.Sprintf("%d", end))
-	q.Set("step", "1")
+	q.Set("step", strconv.FormatInt(step, 10))
 	req.URL.RawQuery = q.Encode()
 
 	resp, err := client.Do(req)
@@ -142,16 +195,25 @@ func (d *vmDriver) readManyCtx(ctx context.Context, client *http.Client, keys []
 
 	var promResp struct {
 		Status string `json:"status"`
+		Error  string `json:"error"`
 		Data   struct {
 			ResultType string          `json:"resultType"`
 			Result     json.RawMessage `json:"result"`
 		} `json:"data"`
 	}
-	if err := sonic.Unmarshal(body, &promResp); err != nil {
-		return 0, err
+	jsonErr := sonic.Unmarshal(body, &promResp)
+	if resp.StatusCode >= 300 {
+		msg := promResp.Error
+		if jsonErr != nil || msg == "" {
+			msg = strings.TrimSpace(string(body[:min(len(body), 512)]))
+		}
+		return 0, fmt.Errorf("query_range returned %d: %s", resp.StatusCode, msg)
+	}
+	if jsonErr != nil {
+		return 0, jsonErr
 	}
 	if promResp.Status != "success" {
-		return 0, nil
+		return 0, fmt.Errorf("query_range failed: %s", promResp.Error)
 	}
 
 	type metricResult struct {
@@ -171,36 +233,109 @@ func (d *vmDriver) readManyCtx(ctx context.Context, client *http.Client, keys []
 	return totalPoints, nil
 }
 
-func (d *vmDriver) multiWrite(numPointsPerSensor, numSensors int) (success, failure uint64, elapsed time.Duration) {
-	start := time.Now()
-	now := time.Now().Unix()
+func (d *vmDriver) ReadPoints(ctx context.Context, key string, lastX int) ([]KeyedPoint, error) {
+	return promQueryRawPoints(ctx, d.client, d.url, key, time.Hour, lastX)
+}
 
-	var buf bytes.Buffer
-	for i := 0; i < numSensors; i++ {
-		key := fmt.Sprintf("benchmark_sensor_%d", i)
-		buf.WriteString(`{"metric":{"__name__":"benchmark_value","key":"`)
-		buf.WriteString(key)
-		buf.WriteString(`"},"values":[`)
-		for j := 0; j < numPointsPerSensor; j++ {
-			if j > 0 {
-				buf.WriteByte(',')
+// promQueryRawPoints returns the raw samples of key's benchmark_value series
+// from the last window, keeping at most the newest lastX. It uses a range
+// selector in an instant query, which returns stored samples rather than
+// the interpolated steps of query_range.
+func promQueryRawPoints(ctx context.Context, client *http.Client, baseURL, key string, window time.Duration, lastX int) ([]KeyedPoint, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", baseURL+"/api/v1/query", nil)
+	if err != nil {
+		return nil, err
+	}
+	q := req.URL.Query()
+	q.Set("query", fmt.Sprintf(`benchmark_value{key="%s"}[%ds]`, key, int64(window/time.Second)))
+	q.Set("time", fmt.Sprintf("%d", time.Now().Unix()))
+	req.URL.RawQuery = q.Encode()
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+
+	var promResp struct {
+		Status string `json:"status"`
+		Error  string `json:"error"`
+		Data   struct {
+			Result []struct {
+				Values [][2]interface{} `json:"values"`
+			} `json:"result"`
+		} `json:"data"`
+	}
+	if err := sonic.Unmarshal(body, &promResp); err != nil {
+		return nil, err
+	}
+	if promResp.Status != "success" {
+		return nil, fmt.Errorf("query failed: %s", promResp.Error)
+	}
+
+	var points []KeyedPoint
+	for _, r := range promResp.Data.Result {
+		for _, v := range r.Values {
+			ts, ok := v[0].(float64)
+			str, ok2 := v[1].(string)
+			if !ok || !ok2 {
+				return nil, fmt.Errorf("unexpected sample %v", v)
 			}
-			fmt.Fprintf(&buf, "%f", float64(j)*1.5)
-		}
-		buf.WriteString(`],"timestamps":[`)
-		for j := 0; j < numPointsPerSensor; j++ {
-			if j > 0 {
-				buf.WriteByte(',')
+			val, err := strconv.ParseFloat(str, 64)
+			if err != nil {
+				return nil, err
 			}
-			fmt.Fprintf(&buf, "%d", now+int64(j))
+			points = append(points, KeyedPoint{Key: key, Value: val, Timestamp: int64(math.Floor(ts))})
 		}
-		buf.WriteString("]}\n")
-		success += uint64(numPointsPerSensor)
 	}
+	if len(points) > lastX {
+		points = points[len(points)-lastX:]
+	}
+	return points, nil
+}
+
+// multiWrite imports numPointsPerSensor points for each sensor. With
+// workers > 1 the sensors are split into that many concurrent imports;
+// otherwise everything goes in one request.
+func (d *vmDriver) multiWrite(numPointsPerSensor, numSensors, workers int) (success, failure uint64, elapsed time.Duration) {
+	start := time.Now()
+	now := time.Now().UnixMilli()
 
-	d.importJSON(context.Background(), buf.String())
+	var acc atomicAccumulator
+	splitWork(workers, numSensors, func(lo, hi int) {
+		var buf bytes.Buffer
+		for i := lo; i < hi; i++ {
+			key := fmt.Sprintf("benchmark_sensor_%d", i)
+			buf.WriteString(`{"metric":{"__name__":"benchmark_value","key":"`)
+			buf.WriteString(key)
+			buf.WriteString(`"},"values":[`)
+			for j := 0; j < numPointsPerSensor; j++ {
+				if j > 0 {
+					buf.WriteByte(',')
+				}
+				fmt.Fprintf(&buf, "%f", float64(j)*1.5)
+			}
+			buf.WriteString(`],"timestamps":[`)
+			for j := 0; j < numPointsPerSensor; j++ {
+				if j > 0 {
+					buf.WriteByte(',')
+				}
+				fmt.Fprintf(&buf, "%d", now+int64(j)*1000)
+			}
+			buf.WriteString("]}\n")
+		}
+
+		// Each payload is one import request, so it succeeds or fails as a unit.
+		n := uint64(numPointsPerSensor * (hi - lo))
+		if err := d.importJSON(context.Background(), buf.String()); err != nil {
+			acc.addFailure(n)
+		} else {
+			acc.addSuccess(n)
+		}
+	})
 	elapsed = time.Since(start)
-	return
+	return acc.successCount(), acc.failureCount(), elapsed
 }
 
 func (d *vmDriver) writePipelined(ctx context.Context, key string, values []float64) (int, error) {
@@ -208,7 +343,7 @@ func (d *vmDriver) writePipelined(ctx context.Context, key string, values []floa
 	buf.WriteString(`{"metric":{"__name__":"benchmark_value","key":"`)
 	buf.WriteString(key)
 	buf.WriteString(`"},"values":[`)
-	now := time.Now().Unix()
+	now := time.Now().UnixMilli()
 	for i, v := range values {
 		if i > 0 {
 			buf.WriteByte(',')
@@ -220,7 +355,7 @@ func (d *vmDriver) writePipelined(ctx context.Context, key string, values []floa
 		if i > 0 {
 			buf.WriteByte(',')
 		}
-		fmt.Fprintf(&buf, "%d", now+int64(i))
+		fmt.Fprintf(&buf, "%d", now+int64(i)*1000)
 	}
 	buf.WriteString("]}\n")
 	if err := d.importJSON(ctx, buf.String()); err != nil {
@@ -228,3 +363,100 @@ func (d *vmDriver) writePipelined(ctx context.Context, key string, values []floa
 	}
 	return len(values), nil
 }
+
+// call sends an admin request to path and returns the response body,
+// failing on non-2xx statuses.
+func (d *vmDriver) call(ctx context.Context, method, path string, query url.Values, body io.Reader) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, method, d.url+path+"?"+query.Encode(), body)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 300 {
+		if len(data) > 512 {
+			data = data[:512]
+		}
+		return nil, fmt.Errorf("vm %s returned %d: %s", path, resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+	return data, nil
+}
+
+// vmSeries selects the benchmark series of key.
+func vmSeries(key string) url.Values {
+	return url.Values{"match[]": {fmt.Sprintf(`benchmark_value{key="%s"}`, key)}}
+}
+
+// ListKeys lists the values of the key label.
+func (d *vmDriver) ListKeys(ctx context.Context) (int, error) {
+	body, err := d.call(ctx, "GET", "/api/v1/label/key/values", url.Values{"match[]": {"benchmark_value"}}, nil)
+	if err != nil {
+		return 0, err
+	}
+	var values struct {
+		Status string   `json:"status"`
+		Data   []string `json:"data"`
+	}
+	if err := sonic.Unmarshal(body, &values); err != nil {
+		return 0, err
+	}
+	if values.Status != "success" {
+		return 0, fmt.Errorf("vm label values: status %s", values.Status)
+	}
+	return len(values.Data), nil
+}
+
+// DeleteKey deletes key's series through delete_series.
+func (d *vmDriver) DeleteKey(ctx context.Context, key string) error {
+	_, err := d.call(ctx, "POST", "/api/v1/admin/tsdb/delete_series", vmSeries(key), nil)
+	return err
+}
+
+// Flush persists recently imported samples and makes them searchable.
+func (d *vmDriver) Flush(ctx context.Context) error {
+	_, err := d.call(ctx, "GET", "/internal/force_flush", nil, nil)
+	return err
+}
+
+// Export streams key's raw samples through /api/v1/export and counts them.
+func (d *vmDriver) Export(ctx context.Context, key string) (int, error) {
+	body, err := d.call(ctx, "GET", "/api/v1/export", vmSeries(key), nil)
+	if err != nil {
+		return 0, err
+	}
+	count := 0
+	for _, line := range bytes.Split(body, []byte("\n")) {
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		var series struct {
+			Timestamps []int64 `json:"timestamps"`
+		}
+		if err := sonic.Unmarshal(line, &series); err != nil {
+			return count, fmt.Errorf("vm export: %w", err)
+		}
+		count += len(series.Timestamps)
+	}
+	return count, nil
+}
+
+// BulkLoad ingests points for key as CSV through /api/v1/import/csv.
+func (d *vmDriver) BulkLoad(ctx context.Context, key string, points []KeyedPoint) error {
+	var buf bytes.Buffer
+	for _, p := range points {
+		fmt.Fprintf(&buf, "%d,%f\n", p.Timestamp, p.Value)
+	}
+	q := url.Values{
+		"format":      {"1:time:unix_s,2:metric:benchmark_value"},
+		"extra_label": {"key=" + key},
+	}
+	_, err := d.call(ctx, "POST", "/api/v1/import/csv", q, &buf)
+	return err
+}